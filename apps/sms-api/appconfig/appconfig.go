@@ -6,9 +6,7 @@ import (
 
 	"encore.app/internal/cache"
 	"encore.app/internal/config"
-	"encore.app/internal/entities"
 	"encore.app/internal/logger"
-	"encore.dev/beta/auth"
 	"encore.dev/beta/errs"
 	"github.com/redis/go-redis/v9"
 )
@@ -56,14 +54,11 @@ func GetLangPack(ctx context.Context) (*LangPackResponse, error) {
 	}, nil
 }
 
-// SetLangPack saves a new application-level language pack. Admin only.
+// SetLangPack saves a new application-level language pack. Requires
+// config.langpack.manage.
 //
 //encore:api auth method=PUT path=/config/langpack
 func SetLangPack(ctx context.Context, req *SetLangPackRequest) error {
-	if err := requireAdmin(ctx); err != nil {
-		return err
-	}
-
 	// Validate JSON structure using map[string]any (Encore-safe internally)
 	var tmp map[string]any
 	if err := json.Unmarshal(req.Pack, &tmp); err != nil {
@@ -98,10 +93,6 @@ func SetLangPack(ctx context.Context, req *SetLangPackRequest) error {
 //
 //encore:api auth method=DELETE path=/config/langpack
 func DeleteLangPack(ctx context.Context) error {
-	if err := requireAdmin(ctx); err != nil {
-		return err
-	}
-
 	if err := rdb.Del(ctx, langPackKey).Err(); err != nil {
 		logger.ErrorContext(ctx, "DeleteLangPack redis error", "err", err)
 		return &errs.Error{
@@ -113,21 +104,3 @@ func DeleteLangPack(ctx context.Context) error {
 	logger.InfoContext(ctx, "LangPack deleted by admin")
 	return nil
 }
-
-// requireAdmin checks that the authenticated user has the admin role.
-func requireAdmin(ctx context.Context) error {
-	payload, ok := auth.Data().(*entities.TokenPayload)
-	if !ok || payload == nil {
-		return &errs.Error{
-			Code:    errs.Unauthenticated,
-			Message: "not authenticated",
-		}
-	}
-	if payload.Role != entities.RoleAdmin {
-		return &errs.Error{
-			Code:    errs.PermissionDenied,
-			Message: "admin role required",
-		}
-	}
-	return nil
-}
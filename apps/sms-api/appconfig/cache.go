@@ -0,0 +1,38 @@
+package appconfig
+
+import (
+	"context"
+
+	"encore.app/audit"
+	"encore.app/authn"
+)
+
+// FlushMoodleCacheRequest is the body for POST /admin/cache/flush. At least
+// one of the IDs is required.
+type FlushMoodleCacheRequest struct {
+	CourseID   *int64 `json:"courseId,omitempty"`
+	CategoryID *int64 `json:"categoryId,omitempty"`
+}
+
+type FlushMoodleCacheResponse struct {
+	// Flushed is the number of cached responses dropped.
+	Flushed int `json:"flushed"`
+}
+
+// FlushMoodleCache drops the cached Moodle data of a course or category, so
+// that changes made in Moodle show at once. Requires cache.manage.
+//
+//encore:api auth method=POST path=/admin/cache/flush
+func FlushMoodleCache(ctx context.Context, req *FlushMoodleCacheRequest) (*FlushMoodleCacheResponse, error) {
+	audit.SetDetails(ctx, map[string]any{"courseId": req.CourseID, "categoryId": req.CategoryID})
+	flushed, err := authn.GetContainer().GetCacheController().Flush(ctx, req.CourseID, req.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	audit.SetDetails(ctx, map[string]any{
+		"courseId":   req.CourseID,
+		"categoryId": req.CategoryID,
+		"flushed":    flushed,
+	})
+	return &FlushMoodleCacheResponse{Flushed: flushed}, nil
+}
@@ -0,0 +1,63 @@
+package appconfig
+
+import (
+	"context"
+
+	"encore.app/audit"
+	"encore.app/authn"
+	"encore.app/internal/authz"
+	"encore.app/internal/logger"
+)
+
+// PermissionsResponse is the shape returned by GET /admin/permissions.
+type PermissionsResponse struct {
+	// Permissions lists every permission that can be granted.
+	Permissions []authz.PermissionInfo `json:"permissions"`
+	Roles       []string               `json:"roles"`
+	// Policy maps each role to the permissions it holds.
+	Policy authz.Policy `json:"policy"`
+}
+
+// SetPermissionsRequest is the body for PUT /admin/permissions.
+type SetPermissionsRequest struct {
+	Policy authz.Policy `json:"policy"`
+}
+
+// GetPermissions returns the permissions each role holds.
+//
+//encore:api auth method=GET path=/admin/permissions
+func GetPermissions(ctx context.Context) (*PermissionsResponse, error) {
+	policy, err := authn.GetContainer().GetAuthzController().GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &PermissionsResponse{
+		Permissions: authz.Permissions(),
+		Roles:       authz.Roles,
+		Policy:      policy,
+	}, nil
+}
+
+// SetPermissions replaces the permissions of every role. Roles left out of
+// the policy lose all permissions.
+//
+//encore:api auth method=PUT path=/admin/permissions
+func SetPermissions(ctx context.Context, req *SetPermissionsRequest) error {
+	audit.SetDetails(ctx, map[string]any{"policy": req.Policy})
+	err := authn.GetContainer().GetAuthzController().SetPolicy(ctx, req.Policy)
+	if err != nil {
+		logger.ErrorContext(ctx, "SetPermissions error", "err", err)
+	}
+	return err
+}
+
+// ResetPermissions reverts every role to its default permissions.
+//
+//encore:api auth method=DELETE path=/admin/permissions
+func ResetPermissions(ctx context.Context) error {
+	err := authn.GetContainer().GetAuthzController().ResetPolicy(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "ResetPermissions error", "err", err)
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// detailsKey is the context key under which the AuditMiddleware stores the
+// per-request details holder.
+type detailsKey struct{}
+
+// detailsHolder lets a handler hand structured details back to the
+// AuditMiddleware, which only sees the request before and the response after.
+type detailsHolder struct {
+	mu        sync.Mutex
+	details   any
+	eventType EventType
+}
+
+// WithDetailsHolder returns a child context that can carry audit details set
+// by the handler via SetDetails. Called by the AuditMiddleware before next().
+func WithDetailsHolder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, detailsKey{}, &detailsHolder{})
+}
+
+// SetDetails attaches details to the audit entry that the middleware writes
+// for the current request. It is a no-op when the route is not audited.
+func SetDetails(ctx context.Context, details any) {
+	h, ok := ctx.Value(detailsKey{}).(*detailsHolder)
+	if !ok || h == nil {
+		return
+	}
+	h.mu.Lock()
+	h.details = details
+	h.mu.Unlock()
+}
+
+// DetailsFromContext returns the details set by the handler, or nil.
+func DetailsFromContext(ctx context.Context) any {
+	h, ok := ctx.Value(detailsKey{}).(*detailsHolder)
+	if !ok || h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.details
+}
+
+// SetEventType records the entry under eventType instead of the route's
+// whitelisted event, e.g. EventAuthDenied when a login attempt is rejected.
+// It is a no-op when the route is not audited.
+func SetEventType(ctx context.Context, eventType EventType) {
+	h, ok := ctx.Value(detailsKey{}).(*detailsHolder)
+	if !ok || h == nil {
+		return
+	}
+	h.mu.Lock()
+	h.eventType = eventType
+	h.mu.Unlock()
+}
+
+// EventTypeFromContext returns the event type set by the handler, or fallback.
+func EventTypeFromContext(ctx context.Context, fallback EventType) EventType {
+	h, ok := ctx.Value(detailsKey{}).(*detailsHolder)
+	if !ok || h == nil {
+		return fallback
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.eventType == "" {
+		return fallback
+	}
+	return h.eventType
+}
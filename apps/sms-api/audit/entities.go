@@ -24,10 +24,38 @@ const (
 	EventTokenRefresh EventType = "auth.token_refresh"
 	// A request was rejected because the token was missing, invalid, or expired.
 	EventAuthDenied EventType = "auth.denied"
+	// User ends their current session.
+	EventLogout EventType = "auth.logout"
+	// User ends all of their sessions on every device.
+	EventLogoutAll EventType = "auth.logout_all"
+	// Admin revokes a session of another user.
+	EventSessionRevoke EventType = "auth.session_revoke"
+	// A token refresh picked up a new role for the user from Moodle.
+	EventRoleChange EventType = "auth.role_change"
+	// A request was rejected because the caller's role lacks a permission.
+	EventPermissionDenied EventType = "auth.permission_denied"
+	// Admin starts viewing the application as another user.
+	EventImpersonate EventType = "auth.impersonate"
+	// Any request made with an impersonation token.
+	EventImpersonatedRequest EventType = "auth.impersonated_request"
+
+	// ── API keys ──────────────────────────────────────────────────────────────
+	// Admin creates an API key for a service account.
+	EventAPIKeyCreate EventType = "apikey.create"
+	// Admin replaces the secret of an API key.
+	EventAPIKeyRotate EventType = "apikey.rotate"
+	// Admin revokes an API key for good.
+	EventAPIKeyRevoke EventType = "apikey.revoke"
+	// Any request made with an API key.
+	EventAPIKeyRequest EventType = "apikey.request"
 
 	// ── Grades ────────────────────────────────────────────────────────────────
 	// Teacher submits updated grade values for one or more students.
 	EventUpdateGrades EventType = "grade.update"
+	// Manager or admin freezes a course's grades, or one exam type within it.
+	EventLockGrades EventType = "grade.lock"
+	// Admin lifts a grade lock, with a recorded reason.
+	EventUnlockGrades EventType = "grade.unlock"
 
 	// ── Grade export ──────────────────────────────────────────────────────────
 	// A grade sheet is generated and downloaded by a teacher / manager.
@@ -45,6 +73,22 @@ const (
 	// Admin removes the active language pack (reverts to defaults).
 	EventDeleteLangPack EventType = "config.langpack_delete"
 
+	// ── Grade coefficients ────────────────────────────────────────────────────
+	// Admin sets the exam-type coefficients of a course category.
+	EventSetCoefficients EventType = "config.coefficients_set"
+	// Admin removes a category's coefficients (reverts to defaults).
+	EventDeleteCoefficients EventType = "config.coefficients_delete"
+
+	// ── Permissions ───────────────────────────────────────────────────────────
+	// Admin changes which roles hold which permissions.
+	EventSetPermissions EventType = "config.permissions_set"
+	// Admin reverts role permissions to the defaults.
+	EventResetPermissions EventType = "config.permissions_reset"
+
+	// ── Moodle cache ──────────────────────────────────────────────────────────
+	// Admin drops the cached Moodle data of a course or category.
+	EventCacheFlush EventType = "config.cache_flush"
+
 	// ── Audit log management ──────────────────────────────────────────────────
 	// Admin manually purges old audit log entries via the REST endpoint.
 	EventAuditPurge EventType = "audit.purge"
@@ -63,19 +107,23 @@ const (
 // Audit Entry
 // =====================
 
-// Entry is a single immutable audit log record.
+// Entry is a single immutable audit log record. ImpersonatorID is the admin
+// acting as ActorID, and APIKeyID the API key the actor authenticated with;
+// both are 0 for ordinary requests.
 type Entry struct {
-	ID        string          `json:"id"         db:"id"`
-	Timestamp time.Time       `json:"timestamp"  db:"timestamp"`
-	EventType EventType       `json:"event_type" db:"event_type"`
-	ActorID   int64           `json:"actor_id"   db:"actor_id"`
-	ActorRole string          `json:"actor_role" db:"actor_role"`
-	Outcome   Outcome         `json:"outcome"    db:"outcome"`
-	Service   string          `json:"service"    db:"service"`
-	Endpoint  string          `json:"endpoint"   db:"endpoint"`
-	IPAddress string          `json:"ip_address" db:"ip_address"`
-	Details   json.RawMessage `json:"details"    db:"details"`
-	ErrorMsg  string          `json:"error_msg"  db:"error_msg"`
+	ID             string          `json:"id"              db:"id"`
+	Timestamp      time.Time       `json:"timestamp"       db:"timestamp"`
+	EventType      EventType       `json:"event_type"      db:"event_type"`
+	ActorID        int64           `json:"actor_id"        db:"actor_id"`
+	ActorRole      string          `json:"actor_role"      db:"actor_role"`
+	ImpersonatorID int64           `json:"impersonator_id" db:"impersonator_id"`
+	APIKeyID       int64           `json:"api_key_id"      db:"api_key_id"`
+	Outcome        Outcome         `json:"outcome"         db:"outcome"`
+	Service        string          `json:"service"         db:"service"`
+	Endpoint       string          `json:"endpoint"        db:"endpoint"`
+	IPAddress      string          `json:"ip_address"      db:"ip_address"`
+	Details        json.RawMessage `json:"details"         db:"details"`
+	ErrorMsg       string          `json:"error_msg"       db:"error_msg"`
 }
 
 // =====================
@@ -138,12 +186,30 @@ func (r *ListRequest) Validate() error {
 		case EventLogin,
 			EventTokenRefresh,
 			EventAuthDenied,
+			EventLogout,
+			EventLogoutAll,
+			EventSessionRevoke,
+			EventRoleChange,
+			EventPermissionDenied,
+			EventImpersonate,
+			EventImpersonatedRequest,
+			EventAPIKeyCreate,
+			EventAPIKeyRotate,
+			EventAPIKeyRevoke,
+			EventAPIKeyRequest,
 			EventUpdateGrades,
+			EventLockGrades,
+			EventUnlockGrades,
 			EventExportGrades,
 			EventUploadTemplate,
 			EventDeleteTemplate,
 			EventSetLangPack,
 			EventDeleteLangPack,
+			EventSetCoefficients,
+			EventDeleteCoefficients,
+			EventSetPermissions,
+			EventResetPermissions,
+			EventCacheFlush,
 			EventAuditPurge:
 			// valid
 		default:
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"time"
 
+	"encore.app/internal/entities"
 	"encore.app/internal/helper"
 	"encore.app/internal/logger"
+	"encore.dev/beta/auth"
 )
 
 const (
@@ -66,6 +68,7 @@ func (al *Logger) Shutdown(ctx context.Context) {
 // ── Public logging API ────────────────────────────────────────────────────────
 
 // Log queues an audit entry asynchronously. It never blocks the caller.
+// Entries about the caller record the impersonator or API key it acts through.
 // If the channel is full (buffer of 4096) the entry is dropped and a warning
 // is emitted — audit logging must never degrade the user-facing request path.
 func (al *Logger) Log(
@@ -97,6 +100,13 @@ func (al *Logger) Log(
 		Details:   raw,
 		ErrorMsg:  errMsg,
 	}
+	// An impersonating admin is recorded next to the user they act as, and
+	// API keys next to the service role.
+	if payload, ok := auth.Data().(*entities.TokenPayload); ok && payload != nil &&
+		payload.UserID == actorID {
+		entry.ImpersonatorID = payload.Impersonator
+		entry.APIKeyID = payload.APIKeyID
+	}
 
 	select {
 	case al.queue <- entry:
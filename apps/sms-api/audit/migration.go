@@ -18,7 +18,8 @@ import (
 //go:embed migrations/*.sql
 var migrationFiles embed.FS
 
-// RunMigrations applies all pending UP migrations for the audit log table.
+// RunMigrations applies all pending UP migrations for the SMS-owned tables
+// (audit log, grade history, …).
 // It is safe to call on every service startup — golang-migrate is idempotent.
 func RunMigrations(db *dbxlib.DB) error {
 	// Extract the underlying *sql.DB from dbx.
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS sms_grade_history (
+    id           BIGINT        NOT NULL AUTO_INCREMENT,
+    course_id    BIGINT        NOT NULL,
+    -- Course-module id, as sent in core_grades_update_grades "activityid".
+    activity_id  BIGINT        NOT NULL,
+    item_number  INT           NOT NULL DEFAULT 0,
+    student_id   BIGINT        NOT NULL,
+    -- NULL when the student had no grade before the change.
+    old_grade    DECIMAL(10,5) DEFAULT NULL,
+    new_grade    DECIMAL(10,5) NOT NULL,
+    actor_id     BIGINT        NOT NULL DEFAULT 0,
+    actor_role   VARCHAR(32)   NOT NULL DEFAULT '',
+    changed_at   DATETIME(3)   NOT NULL,
+
+    PRIMARY KEY (id),
+
+    INDEX idx_course_time          (course_id, changed_at),
+    INDEX idx_course_student_time  (course_id, student_id, changed_at),
+    INDEX idx_course_activity_time (course_id, activity_id, changed_at),
+    INDEX idx_actor_time           (actor_id, changed_at)
+
+) ENGINE=InnoDB
+  DEFAULT CHARSET=utf8mb4
+  COLLATE=utf8mb4_unicode_ci;
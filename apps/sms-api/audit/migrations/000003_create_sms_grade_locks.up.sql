@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS sms_grade_locks (
+    id            BIGINT        NOT NULL AUTO_INCREMENT,
+    course_id     BIGINT        NOT NULL,
+    -- Empty string locks the whole course; otherwise one of '15P', '1T', 'Thi'.
+    exam_type     VARCHAR(8)    NOT NULL DEFAULT '',
+    reason        VARCHAR(500)  NOT NULL DEFAULT '',
+    locked_by     BIGINT        NOT NULL,
+    locked_at     DATETIME(3)   NOT NULL,
+    -- Unlock columns stay NULL while the lock is active.
+    unlocked_by   BIGINT        DEFAULT NULL,
+    unlocked_at   DATETIME(3)   DEFAULT NULL,
+    unlock_reason VARCHAR(500)  DEFAULT NULL,
+
+    PRIMARY KEY (id),
+
+    INDEX idx_course_active (course_id, unlocked_at),
+    INDEX idx_course_type   (course_id, exam_type)
+
+) ENGINE=InnoDB
+  DEFAULT CHARSET=utf8mb4
+  COLLATE=utf8mb4_unicode_ci;
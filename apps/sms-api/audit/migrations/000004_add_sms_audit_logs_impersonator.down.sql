@@ -0,0 +1,3 @@
+ALTER TABLE sms_audit_logs
+    DROP INDEX idx_impersonator_time,
+    DROP COLUMN impersonator_id;
@@ -0,0 +1,4 @@
+-- Admin acting as actor_id through impersonation; 0 for ordinary requests.
+ALTER TABLE sms_audit_logs
+    ADD COLUMN impersonator_id BIGINT NOT NULL DEFAULT 0 AFTER actor_role,
+    ADD INDEX idx_impersonator_time (impersonator_id, timestamp);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS sms_api_keys (
+    id           BIGINT        NOT NULL AUTO_INCREMENT,
+    name         VARCHAR(100)  NOT NULL,
+    -- Public part of the key, used to look it up. The key itself is only
+    -- stored as its hex SHA-256.
+    prefix       VARCHAR(16)   NOT NULL,
+    key_hash     CHAR(64)      NOT NULL,
+    -- JSON array of permission names.
+    scopes       JSON          NOT NULL,
+    created_by   BIGINT        NOT NULL,
+    created_at   DATETIME(3)   NOT NULL,
+    rotated_at   DATETIME(3)   DEFAULT NULL,
+    last_used_at DATETIME(3)   DEFAULT NULL,
+    -- NULL keys never expire.
+    expires_at   DATETIME(3)   DEFAULT NULL,
+    -- Revoke columns stay NULL while the key is active.
+    revoked_by   BIGINT        DEFAULT NULL,
+    revoked_at   DATETIME(3)   DEFAULT NULL,
+
+    PRIMARY KEY (id),
+
+    UNIQUE INDEX idx_prefix (prefix),
+    INDEX idx_created_at    (created_at)
+
+) ENGINE=InnoDB
+  DEFAULT CHARSET=utf8mb4
+  COLLATE=utf8mb4_unicode_ci;
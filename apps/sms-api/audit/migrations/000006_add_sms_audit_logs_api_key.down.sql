@@ -0,0 +1,3 @@
+ALTER TABLE sms_audit_logs
+    DROP INDEX idx_api_key_time,
+    DROP COLUMN api_key_id;
@@ -0,0 +1,4 @@
+-- API key the request authenticated with; 0 for users.
+ALTER TABLE sms_audit_logs
+    ADD COLUMN api_key_id BIGINT NOT NULL DEFAULT 0 AFTER impersonator_id,
+    ADD INDEX idx_api_key_time (api_key_id, timestamp);
@@ -0,0 +1,3 @@
+ALTER TABLE sms_grade_history
+    DROP INDEX uq_event_id,
+    DROP COLUMN event_id;
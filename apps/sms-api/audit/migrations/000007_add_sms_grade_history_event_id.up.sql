@@ -0,0 +1,5 @@
+-- Moodle event a change was recorded from; NULL for changes made through the
+-- SMS. Unique so that a redelivered event is recorded once.
+ALTER TABLE sms_grade_history
+    ADD COLUMN event_id VARCHAR(64) DEFAULT NULL AFTER actor_role,
+    ADD UNIQUE INDEX uq_event_id (event_id);
@@ -0,0 +1,3 @@
+ALTER TABLE sms_grade_locks
+    DROP INDEX uq_active_key,
+    DROP COLUMN active_key;
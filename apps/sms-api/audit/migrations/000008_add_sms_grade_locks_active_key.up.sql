@@ -0,0 +1,6 @@
+-- Set while a lock is active, so that a course has at most one active lock
+-- per exam type even when two lock requests race.
+ALTER TABLE sms_grade_locks
+    ADD COLUMN active_key VARCHAR(32)
+        GENERATED ALWAYS AS (IF(unlocked_at IS NULL, CONCAT(course_id, ':', exam_type), NULL)) STORED,
+    ADD UNIQUE INDEX uq_active_key (active_key);
@@ -40,17 +40,19 @@ func (r *mysqlRepository) Save(ctx context.Context, entry *Entry) error {
 
 	_, err := r.db.WithContext(ctx).
 		Insert(table, dbx.Params{
-			"id":         entry.ID,
-			"timestamp":  entry.Timestamp.UTC().Format("2006-01-02 15:04:05.000"),
-			"event_type": string(entry.EventType),
-			"actor_id":   entry.ActorID,
-			"actor_role": entry.ActorRole,
-			"outcome":    string(entry.Outcome),
-			"service":    entry.Service,
-			"endpoint":   entry.Endpoint,
-			"ip_address": ipVal,
-			"details":    detailsVal,
-			"error_msg":  errMsgVal,
+			"id":              entry.ID,
+			"timestamp":       entry.Timestamp.UTC().Format("2006-01-02 15:04:05.000"),
+			"event_type":      string(entry.EventType),
+			"actor_id":        entry.ActorID,
+			"actor_role":      entry.ActorRole,
+			"impersonator_id": entry.ImpersonatorID,
+			"api_key_id":      entry.APIKeyID,
+			"outcome":         string(entry.Outcome),
+			"service":         entry.Service,
+			"endpoint":        entry.Endpoint,
+			"ip_address":      ipVal,
+			"details":         detailsVal,
+			"error_msg":       errMsgVal,
 		}).Execute()
 	if err != nil {
 		return fmt.Errorf("audit: insert: %w", err)
@@ -113,7 +115,8 @@ func (r *mysqlRepository) List(ctx context.Context, req *ListRequest) (*ListResp
 	// details_text is a generated column — exclude it from SELECT to avoid
 	// confusion; callers read the JSON `details` field instead.
 	selectSQL := fmt.Sprintf(
-		"SELECT id, timestamp, event_type, actor_id, actor_role, outcome,"+
+		"SELECT id, timestamp, event_type, actor_id, actor_role, impersonator_id,"+
+			" api_key_id, outcome,"+
 			" service, endpoint,"+
 			" COALESCE(ip_address, '') AS ip_address,"+
 			" COALESCE(CAST(details AS CHAR), '') AS details,"+
@@ -288,31 +291,35 @@ func sanitiseSearch(s string) string {
 // ── scan type ─────────────────────────────────────────────────────────────────
 
 type dbxEntry struct {
-	ID        string    `db:"id"`
-	Timestamp time.Time `db:"timestamp"`
-	EventType string    `db:"event_type"`
-	ActorID   int64     `db:"actor_id"`
-	ActorRole string    `db:"actor_role"`
-	Outcome   string    `db:"outcome"`
-	Service   string    `db:"service"`
-	Endpoint  string    `db:"endpoint"`
-	IPAddress string    `db:"ip_address"`
-	Details   string    `db:"details"`
-	ErrorMsg  string    `db:"error_msg"`
+	ID             string    `db:"id"`
+	Timestamp      time.Time `db:"timestamp"`
+	EventType      string    `db:"event_type"`
+	ActorID        int64     `db:"actor_id"`
+	ActorRole      string    `db:"actor_role"`
+	ImpersonatorID int64     `db:"impersonator_id"`
+	APIKeyID       int64     `db:"api_key_id"`
+	Outcome        string    `db:"outcome"`
+	Service        string    `db:"service"`
+	Endpoint       string    `db:"endpoint"`
+	IPAddress      string    `db:"ip_address"`
+	Details        string    `db:"details"`
+	ErrorMsg       string    `db:"error_msg"`
 }
 
 func (e *dbxEntry) toEntry() Entry {
 	entry := Entry{
-		ID:        e.ID,
-		Timestamp: e.Timestamp,
-		EventType: EventType(e.EventType),
-		ActorID:   e.ActorID,
-		ActorRole: e.ActorRole,
-		Outcome:   Outcome(e.Outcome),
-		Service:   e.Service,
-		Endpoint:  e.Endpoint,
-		IPAddress: e.IPAddress,
-		ErrorMsg:  e.ErrorMsg,
+		ID:             e.ID,
+		Timestamp:      e.Timestamp,
+		EventType:      EventType(e.EventType),
+		ActorID:        e.ActorID,
+		ActorRole:      e.ActorRole,
+		ImpersonatorID: e.ImpersonatorID,
+		APIKeyID:       e.APIKeyID,
+		Outcome:        Outcome(e.Outcome),
+		Service:        e.Service,
+		Endpoint:       e.Endpoint,
+		IPAddress:      e.IPAddress,
+		ErrorMsg:       e.ErrorMsg,
 	}
 	if e.Details != "" {
 		entry.Details = json.RawMessage(e.Details)
@@ -61,16 +61,13 @@ func (s *Service) Shutdown(ctx context.Context) {
 
 // ListAuditLogs returns a paginated, filterable list of audit log entries.
 // Supports structured filters and full-text search simultaneously.
-// Admin only.
+// Requires audit.read.
 //
 //encore:api auth method=GET path=/audit/logs
 func (s *Service) ListAuditLogs(
 	ctx context.Context,
 	req *audit.ListRequest,
 ) (*audit.ListResponse, error) {
-	if err := requireAdmin(ctx); err != nil {
-		return nil, err
-	}
 	resp, err := s.repo.List(ctx, req)
 	if err != nil {
 		logger.ErrorContext(ctx, "audit: list error", "err", err)
@@ -80,13 +77,10 @@ func (s *Service) ListAuditLogs(
 }
 
 // GetAuditStats returns aggregate counts for the admin dashboard.
-// Admin only.
+// Requires audit.read.
 //
 //encore:api auth method=GET path=/audit/stats
 func (s *Service) GetAuditStats(ctx context.Context) (*audit.StatsResponse, error) {
-	if err := requireAdmin(ctx); err != nil {
-		return nil, err
-	}
 	stats, err := s.repo.Stats(ctx)
 	if err != nil {
 		logger.ErrorContext(ctx, "audit: stats error", "err", err)
@@ -108,16 +102,13 @@ type PurgeResponse struct {
 }
 
 // PurgeAuditLogs deletes audit entries older than DaysOld days.
-// Admin only.
+// Requires audit.purge.
 //
 //encore:api auth method=DELETE path=/audit/logs
 func (s *Service) PurgeAuditLogs(
 	ctx context.Context,
 	req *PurgeRequest,
 ) (*PurgeResponse, error) {
-	if err := requireAdmin(ctx); err != nil {
-		return nil, err
-	}
 	if req.DaysOld < 7 {
 		return nil, &errs.Error{
 			Code:    errs.InvalidArgument,
@@ -149,17 +140,6 @@ func (s *Service) PurgeAuditLogs(
 
 // ── helpers ───────────────────────────────────────────────────────────────────
 
-func requireAdmin(ctx context.Context) error {
-	payload, ok := auth.Data().(*entities.TokenPayload)
-	if !ok || payload == nil {
-		return &errs.Error{Code: errs.Unauthenticated, Message: "not authenticated"}
-	}
-	if payload.Role != entities.RoleAdmin {
-		return &errs.Error{Code: errs.PermissionDenied, Message: "admin role required"}
-	}
-	return nil
-}
-
 func actorID(ctx context.Context) int64 {
 	if p, ok := auth.Data().(*entities.TokenPayload); ok && p != nil {
 		return p.UserID
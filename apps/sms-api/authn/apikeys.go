@@ -0,0 +1,108 @@
+package authn
+
+import (
+	"context"
+	"time"
+
+	"encore.app/audit"
+	"encore.app/internal/apikeys"
+	"encore.app/internal/entities"
+	"encore.app/internal/usecases"
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+)
+
+type ListAPIKeysResponse struct {
+	Data []apikeys.Key `json:"data"`
+}
+
+// ListAPIKeys returns every API key, revoked ones included. Secrets are never
+// returned. Requires apikeys.manage.
+//
+//encore:api auth method=GET path=/admin/api-keys
+func ListAPIKeys(ctx context.Context) (*ListAPIKeysResponse, error) {
+	keys, err := container.GetAPIKeyController().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ListAPIKeysResponse{Data: keys}, nil
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	// Scopes are the permissions of the key, e.g. "courses.access_all".
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is optional; keys without it never expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeySecretResponse is the only response that contains a key's secret.
+type APIKeySecretResponse struct {
+	Key *apikeys.Key `json:"key"`
+	// Secret is sent as the X-API-Key header. It cannot be shown again.
+	Secret string `json:"secret"`
+}
+
+// CreateAPIKey creates an API key for a service account. Requires
+// apikeys.manage.
+//
+//encore:api auth method=POST path=/admin/api-keys
+func CreateAPIKey(ctx context.Context, req *CreateAPIKeyRequest) (*APIKeySecretResponse, error) {
+	actor, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || actor == nil {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+
+	key, secret, err := container.GetAPIKeyController().Create(ctx, actor.UserID, &usecases.CreateKeyParams{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	audit.SetDetails(ctx, apiKeyDetails(key))
+	return &APIKeySecretResponse{Key: key, Secret: secret}, nil
+}
+
+// RotateAPIKey replaces the secret of an API key. The old secret stops
+// working at once. Requires apikeys.manage.
+//
+//encore:api auth method=POST path=/admin/api-keys/:id/rotate
+func RotateAPIKey(ctx context.Context, id int64) (*APIKeySecretResponse, error) {
+	audit.SetDetails(ctx, map[string]any{"keyId": id})
+	key, secret, err := container.GetAPIKeyController().Rotate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	audit.SetDetails(ctx, apiKeyDetails(key))
+	return &APIKeySecretResponse{Key: key, Secret: secret}, nil
+}
+
+// RevokeAPIKey disables an API key for good. Requires apikeys.manage.
+//
+//encore:api auth method=DELETE path=/admin/api-keys/:id
+func RevokeAPIKey(ctx context.Context, id int64) error {
+	actor, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || actor == nil {
+		return &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+
+	audit.SetDetails(ctx, map[string]any{"keyId": id})
+	key, err := container.GetAPIKeyController().Revoke(ctx, actor.UserID, id)
+	if err != nil {
+		return err
+	}
+	audit.SetDetails(ctx, apiKeyDetails(key))
+	return nil
+}
+
+// apiKeyDetails describes a key in the audit log, without its secret.
+func apiKeyDetails(key *apikeys.Key) map[string]any {
+	return map[string]any{
+		"keyId":  key.ID,
+		"name":   key.Name,
+		"prefix": key.Prefix,
+		"scopes": key.Scopes,
+	}
+}
@@ -5,18 +5,41 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
+	"encore.app/audit"
 	"encore.app/internal/entities"
 	"encore.app/internal/logger"
+	"encore.app/internal/oauth2"
+	"encore.app/internal/sessions"
 	"encore.dev/beta/auth"
 	"encore.dev/beta/errs"
 )
 
+// AuthParams carries the credentials of a request: the JWT of a user, or the
+// API key of a service account.
+type AuthParams struct {
+	Authorization string `header:"Authorization"`
+	APIKey        string `header:"X-API-Key"`
+}
+
 // AuthHandler can be named whatever you prefer (but must be exported).
 //
+// Requests with an API key act as the key's service account, under the
+// synthetic service role and with the key's scopes as permissions.
+//
 //encore:authhandler
-func AuthHandler(ctx context.Context, token string) (auth.UID, *entities.TokenPayload, error) {
-	token = strings.TrimPrefix(token, "Bearer ")
+func AuthHandler(ctx context.Context, p *AuthParams) (auth.UID, *entities.TokenPayload, error) {
+	if p.APIKey != "" {
+		payload, err := container.GetAPIKeyController().Authenticate(ctx, p.APIKey)
+		if err != nil {
+			logger.ErrorContext(ctx, "authHandler API key error", "error", err)
+			return "", nil, &errs.Error{Code: errs.Unauthenticated, Message: "Invalid API key"}
+		}
+		return auth.UID("apikey:" + strconv.FormatInt(payload.APIKeyID, 10)), payload, nil
+	}
+
+	token := strings.TrimPrefix(p.Authorization, "Bearer ")
 
 	if token == "" {
 		return "", nil, &errs.Error{
@@ -50,6 +73,14 @@ type OAuth2CallbackRequest struct {
 	Code  string `json:"code"  query:"code"`
 }
 
+// OAuth2Login starts the Moodle login flow by redirecting to Moodle's
+// authorization page with a fresh state and PKCE challenge.
+//
+//encore:api public method=GET path=/oauth2/login
+func OAuth2Login(ctx context.Context) (*entities.HttpCallbackResponse, error) {
+	return container.GetController().HandleLogin(ctx)
+}
+
 // Oauth2 callback endpoint
 //
 //encore:api public method=GET path=/oauth2/callback
@@ -57,7 +88,11 @@ func OAuth2Callback(
 	ctx context.Context,
 	req *OAuth2CallbackRequest,
 ) (*entities.HttpCallbackResponse, error) {
-	return container.GetController().HandleCallback(ctx, req)
+	resp, err := container.GetController().HandleCallback(ctx, req)
+	if errs.Code(err) == errs.Unauthenticated {
+		audit.SetEventType(ctx, audit.EventAuthDenied)
+	}
+	return resp, err
 }
 
 // GetUserInfo endpoint
@@ -74,13 +109,145 @@ func Me(ctx context.Context) (*entities.UserInfo, error) {
 	return container.GetController().HandleGetUserInfo(ctx, userID)
 }
 
+// JWKS publishes the public keys that verify our JWTs, selected by their
+// kid header.
+//
+//encore:api public method=GET path=/.well-known/jwks.json
+func JWKS(ctx context.Context) (*oauth2.JWKS, error) {
+	return container.GetController().HandleJWKS(), nil
+}
+
+type ExchangeRequest struct {
+	Code string `json:"code"`
+}
+
+// Exchange redeems the one-time code of the OAuth2 callback redirect for the
+// token pair. Codes expire after a few seconds and work only once.
+//
+//encore:api public method=POST path=/authn/exchange
+func Exchange(ctx context.Context, req *ExchangeRequest) (*entities.CallbackResponse, error) {
+	return container.GetController().HandleExchange(ctx, req.Code)
+}
+
 type RefreshTokenRequest struct {
 	Token string `json:"token"`
 }
 
-// RefreshToken endpoint
+// RefreshToken endpoint. The new tokens carry the user's current role; a
+// change is audited as a role change instead of a plain refresh.
 //
 //encore:api public method=POST path=/authn/refresh
 func RefreshToken(ctx context.Context, req *RefreshTokenRequest) (*entities.CallbackResponse, error) {
-	return container.GetController().HandleRefreshToken(ctx, req.Token)
+	result, err := container.GetController().HandleRefreshToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.RoleChanged() {
+		audit.SetEventType(ctx, audit.EventRoleChange)
+		audit.SetDetails(ctx, map[string]any{
+			"userId":    result.UserID,
+			"sessionId": result.SessionID,
+			"from":      result.PreviousRole,
+			"to":        result.Role,
+		})
+	}
+	return result.Tokens, nil
+}
+
+// Logout revokes the session of the calling token.
+//
+//encore:api auth method=POST path=/authn/logout
+func Logout(ctx context.Context) error {
+	payload, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || payload == nil {
+		return &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+
+	audit.SetDetails(ctx, map[string]any{"sessionId": payload.SessionID})
+	return container.GetController().HandleLogout(ctx, payload.SessionID)
+}
+
+type LogoutAllResponse struct {
+	// Revoked is the number of sessions that were ended.
+	Revoked int `json:"revoked"`
+}
+
+// LogoutAll revokes every session of the caller, on all devices.
+//
+//encore:api auth method=POST path=/authn/logout-all
+func LogoutAll(ctx context.Context) (*LogoutAllResponse, error) {
+	payload, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || payload == nil {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+
+	n, err := container.GetController().HandleLogoutAll(ctx, payload.UserID)
+	if err != nil {
+		return nil, err
+	}
+	audit.SetDetails(ctx, map[string]any{"revoked": n})
+	return &LogoutAllResponse{Revoked: n}, nil
+}
+
+type ListSessionsResponse struct {
+	Data []sessions.Session `json:"data"`
+}
+
+// ListUserSessions returns the active sessions of a user. Requires
+// sessions.manage.
+//
+//encore:api auth method=GET path=/admin/users/:id/sessions
+func ListUserSessions(ctx context.Context, id int64) (*ListSessionsResponse, error) {
+	list, err := container.GetController().HandleListSessions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &ListSessionsResponse{Data: list}, nil
+}
+
+// RevokeSession ends any session by ID. Requires sessions.manage.
+//
+//encore:api auth method=DELETE path=/admin/sessions/:id
+func RevokeSession(ctx context.Context, id string) error {
+	session, err := container.GetController().HandleRevokeSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	audit.SetDetails(ctx, map[string]any{"sessionId": id, "userId": session.UserID})
+	return nil
+}
+
+type ImpersonateResponse struct {
+	// AccessToken acts as the user until ExpiresAt. There is no refresh token.
+	AccessToken string             `json:"accessToken"`
+	ExpiresAt   time.Time          `json:"expiresAt"`
+	User        *entities.UserInfo `json:"user"`
+}
+
+// Impersonate issues a short-lived, read-only token to view the application
+// as another user. Every request made with it is audited with both user IDs.
+// Requires users.impersonate.
+//
+//encore:api auth method=POST path=/admin/impersonate/:userId
+func Impersonate(ctx context.Context, userId int64) (*ImpersonateResponse, error) {
+	actor, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || actor == nil {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+
+	result, err := container.GetController().HandleImpersonate(ctx, actor, userId)
+	if err != nil {
+		return nil, err
+	}
+	audit.SetDetails(ctx, map[string]any{
+		"userId":    userId,
+		"role":      result.User.Role,
+		"sessionId": result.SessionID,
+	})
+	return &ImpersonateResponse{
+		AccessToken: result.AccessToken,
+		ExpiresAt:   result.ExpiresAt,
+		User:        result.User,
+	}, nil
 }
@@ -3,84 +3,224 @@ package authn
 import (
 	"sync"
 
+	"encore.app/internal/apikeys"
+	"encore.app/internal/authz"
 	"encore.app/internal/cache"
 	"encore.app/internal/categories"
 	"encore.app/internal/config"
 	"encore.app/internal/controllers"
+	"encore.app/internal/courses"
+	"encore.app/internal/db"
+	"encore.app/internal/exportjobs"
+	"encore.app/internal/gradecalc"
+	"encore.app/internal/gradehistory"
+	"encore.app/internal/gradelocks"
+	"encore.app/internal/logger"
 	"encore.app/internal/mdlapi"
+	"encore.app/internal/mdlcache"
+	"encore.app/internal/mdlevents"
 	"encore.app/internal/oauth2"
 	"encore.app/internal/pool"
+	"encore.app/internal/sessions"
 	"encore.app/internal/usecases"
+	"encore.app/internal/users"
+	"encore.app/middleware"
+	"github.com/pocketbase/dbx"
 )
 
 var container *Container
 
 func init() {
 	container = NewContainer()
+
+	// Wire the authorizer into the global permission middleware.
+	middleware.SetAuthorizerProvider(func() *authz.Authorizer { return container.GetAuthorizer() })
 }
 
 type Container struct {
-	config             *config.Config
-	oauth2Provider     oauth2.OAuth2Provider
-	userInfoProvider   oauth2.UserInfoProvider
-	controller         *AuthnController
-	courseController   *controllers.CourseController
-	categoryController *categories.CategoryController
-	userController     *controllers.UserController
-	exportController   *controllers.ExportController
+	config              *config.Config
+	oauth2Provider      oauth2.OAuth2Provider
+	userInfoProvider    oauth2.UserInfoProvider
+	controller          *AuthnController
+	courseController    *controllers.CourseController
+	categoryController  *categories.CategoryController
+	userController      *controllers.UserController
+	exportController    *controllers.ExportController
+	gradeLockController *controllers.GradeLockController
+	gradeCalcController *controllers.GradeCalcController
+	exportJobController *controllers.ExportJobController
+	reportController    *controllers.CategoryReportController
+	authzController     *controllers.AuthzController
+	authorizer          *authz.Authorizer
+	apiKeyController    *controllers.APIKeyController
+	cacheController     *controllers.CacheController
+	eventController     *controllers.MoodleEventController
 
 	mu sync.RWMutex
 }
 
+// newTeacherProvider reads courses and categories from the source set by
+// MOODLE_COURSE_SOURCE. The database is Moodle's own.
+func newTeacherProvider(cfg *config.Config, mdlApi mdlapi.MoodleApi, database *dbx.DB) mdlapi.LocalTeacherProvider {
+	webService := mdlapi.NewLocalTeacherProvider(mdlApi)
+	dbProvider := categories.NewDBTeacherProvider(
+		categories.NewRepository(database),
+		courses.NewRepository(database),
+		users.NewRepo(database),
+	)
+
+	switch cfg.MoodleApiConfig.CourseSource {
+	case config.CourseSourceDB:
+		return dbProvider
+	case config.CourseSourceFallback:
+		return mdlapi.NewFallbackTeacherProvider(webService, dbProvider)
+	default:
+		return webService
+	}
+}
+
 func NewContainer() *Container {
 	cfg := config.GetConfig()
 
 	rdb := cache.New(&cfg.CacheConfig)
 	tokenRepo := oauth2.NewOauth2Repository(rdb)
+	coefRepo := gradecalc.NewRedisRepository(rdb)
+	exportJobRepo := exportjobs.NewRedisRepository(rdb)
+	sessionRepo := sessions.NewRedisRepository(rdb)
+	authzRepo := authz.NewRedisRepository(rdb)
+	mdlEventRepo := mdlevents.NewRedisRepository(rdb)
 
 	mdlApi := mdlapi.New(&cfg.MoodleApiConfig)
 
-	courseGradesProvider   := mdlapi.NewLocalCourseGradesProvider(mdlApi)
-	userGradeItemsProvider := mdlapi.NewMdlApiUserGradeItemsProvider(mdlApi)
-	teacherProvider        := mdlapi.NewLocalTeacherProvider(mdlApi)
+	// SMS-owned tables are migrated by the auditlog service on startup.
+	// Open doesn't connect, so an error here is a bad config; refuse to start.
+	database, err := db.Open(&cfg.DatabaseConfig)
+	if err != nil {
+		logger.Error("Failed to open database", "err", err)
+		panic(err)
+	}
+	gradeHistoryRepo := gradehistory.NewMySQLRepository(database)
+	gradeLockRepo    := gradelocks.NewMySQLRepository(database)
+	apiKeyRepo       := apikeys.NewMySQLRepository(database)
+
+	// Course, category and grade reads are served from Redis while fresh;
+	// grade writes flush the course they change.
+	mdlCache := mdlcache.New(rdb)
+
+	courseGradesProvider   := mdlcache.NewCourseGradesProvider(mdlapi.NewLocalCourseGradesProvider(mdlApi), mdlCache)
+	userGradeItemsProvider := mdlcache.NewUserGradeItemsProvider(mdlapi.NewMdlApiUserGradeItemsProvider(mdlApi), mdlCache)
+	teacherProvider        := mdlcache.NewTeacherProvider(newTeacherProvider(cfg, mdlApi, database), mdlCache)
 	localUserInfoProvider  := mdlapi.NewLocalUserInfoProvider(mdlApi)
 	exportProvider         := mdlapi.NewMdlApiExportProvider(mdlApi)
 
+	// Without signing keys no token can be issued; refuse to start.
+	jwtKeys, err := oauth2.LoadKeySet(&cfg.AuthnConfig)
+	if err != nil {
+		logger.Error("Failed to load JWT keys", "err", err)
+		panic(err)
+	}
+
 	oauth2Provider   := oauth2.NewMoodleOauth2Provider(cfg)
 	userInfoProvider := oauth2.NewHTTPUserInfoProvider(localUserInfoProvider)
-	tokenProvider    := oauth2.NewAppTokenProvider(&cfg.AuthnConfig)
+	tokenProvider    := oauth2.NewAppTokenProvider(&cfg.AuthnConfig, jwtKeys)
 
 	useCase := usecases.NewAuthnUseCase(
 		oauth2Provider,
 		userInfoProvider,
 		tokenProvider,
 		tokenRepo,
+		sessionRepo,
 		&cfg.AuthnConfig,
 	)
 
+	authorizer := authz.NewAuthorizer(authzRepo)
+
 	p := pool.New(nil)
 	p.Start()
 
-	courseUseCase       := usecases.NewCourseUseCase(courseGradesProvider, userGradeItemsProvider, teacherProvider)
-	studentGradeUseCase := usecases.NewStudentGradeUseCase(userGradeItemsProvider)
-	teacherUseCase      := usecases.NewTeacherUseCase(teacherProvider)
-	exportUseCase       := usecases.NewExportUseCase(exportProvider)
+	// Export jobs get their own small pool so that long exports never hold
+	// the workers used by request-scoped fan-outs.
+	exportPoolConfig := pool.DefaultConfig()
+	exportPoolConfig.MaxWorkers = 2
+	exportPoolConfig.QueueSize = 20
+	exportPool := pool.New(exportPoolConfig)
+	exportPool.Start()
+
+	courseUseCase := usecases.NewCourseUseCase(
+		courseGradesProvider,
+		userGradeItemsProvider,
+		teacherProvider,
+		gradeHistoryRepo,
+		gradeLockRepo,
+		coefRepo,
+		authorizer,
+		authzRepo,
+		p,
+	)
+	studentGradeUseCase := usecases.NewStudentGradeUseCase(
+		userGradeItemsProvider,
+		courseGradesProvider,
+		coefRepo,
+		p,
+		&cfg.SchoolConfig,
+	)
+
+	teacherUseCase   := usecases.NewTeacherUseCase(teacherProvider)
+	exportUseCase    := usecases.NewExportUseCase(exportProvider, courseGradesProvider, coefRepo)
+	gradeLockUseCase := usecases.NewGradeLockUseCase(gradeLockRepo)
+	gradeCalcUseCase := usecases.NewGradeCalcUseCase(coefRepo)
+	authzUseCase     := usecases.NewAuthzUseCase(authorizer)
+	apiKeyUseCase    := usecases.NewAPIKeyUseCase(apiKeyRepo)
+	cacheUseCase     := usecases.NewCacheUseCase(mdlCache)
+	eventUseCase     := usecases.NewMoodleEventUseCase(&cfg.WebhookConfig, mdlEventRepo, mdlCache, gradeHistoryRepo)
+	exportJobUseCase := usecases.NewExportJobUseCase(
+		exportJobRepo,
+		exportUseCase,
+		teacherProvider,
+		authorizer,
+		exportPool,
+	)
 
-	controller         := NewAuthnController(useCase)
-	courseController   := controllers.NewCourseController(courseUseCase)
-	categoryController := categories.NewCategoryController(teacherUseCase)
-	userController     := controllers.NewUserController(studentGradeUseCase)
-	exportController   := controllers.NewExportController(exportUseCase)
+	reportUseCase := usecases.NewCategoryReportUseCase(
+		teacherProvider,
+		courseGradesProvider,
+		coefRepo,
+		authorizer,
+		p,
+	)
+
+	controller          := NewAuthnController(useCase)
+	courseController    := controllers.NewCourseController(courseUseCase)
+	categoryController  := categories.NewCategoryController(teacherUseCase)
+	userController      := controllers.NewUserController(studentGradeUseCase)
+	exportController    := controllers.NewExportController(exportUseCase)
+	gradeLockController := controllers.NewGradeLockController(gradeLockUseCase)
+	gradeCalcController := controllers.NewGradeCalcController(gradeCalcUseCase)
+	exportJobController := controllers.NewExportJobController(exportJobUseCase)
+	reportController    := controllers.NewCategoryReportController(reportUseCase)
+	authzController     := controllers.NewAuthzController(authzUseCase)
+	apiKeyController    := controllers.NewAPIKeyController(apiKeyUseCase)
+	cacheController     := controllers.NewCacheController(cacheUseCase)
+	eventController     := controllers.NewMoodleEventController(eventUseCase)
 
 	return &Container{
-		config:             cfg,
-		oauth2Provider:     oauth2Provider,
-		userInfoProvider:   userInfoProvider,
-		controller:         controller,
-		courseController:   courseController,
-		categoryController: categoryController,
-		userController:     userController,
-		exportController:   exportController,
+		config:              cfg,
+		oauth2Provider:      oauth2Provider,
+		userInfoProvider:    userInfoProvider,
+		controller:          controller,
+		courseController:    courseController,
+		categoryController:  categoryController,
+		userController:      userController,
+		exportController:    exportController,
+		gradeLockController: gradeLockController,
+		gradeCalcController: gradeCalcController,
+		exportJobController: exportJobController,
+		reportController:    reportController,
+		authzController:     authzController,
+		authorizer:          authorizer,
+		apiKeyController:    apiKeyController,
+		cacheController:     cacheController,
+		eventController:     eventController,
 	}
 }
 
@@ -114,6 +254,60 @@ func (c *Container) GetExportController() *controllers.ExportController {
 	return c.exportController
 }
 
+func (c *Container) GetGradeLockController() *controllers.GradeLockController {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gradeLockController
+}
+
+func (c *Container) GetGradeCalcController() *controllers.GradeCalcController {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gradeCalcController
+}
+
+func (c *Container) GetExportJobController() *controllers.ExportJobController {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.exportJobController
+}
+
+func (c *Container) GetCategoryReportController() *controllers.CategoryReportController {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reportController
+}
+
+func (c *Container) GetAuthzController() *controllers.AuthzController {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.authzController
+}
+
+func (c *Container) GetAuthorizer() *authz.Authorizer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.authorizer
+}
+
+func (c *Container) GetAPIKeyController() *controllers.APIKeyController {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.apiKeyController
+}
+
+func (c *Container) GetCacheController() *controllers.CacheController {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cacheController
+}
+
+func (c *Container) GetMoodleEventController() *controllers.MoodleEventController {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.eventController
+}
+
 func GetContainer() *Container {
 	return container
 }
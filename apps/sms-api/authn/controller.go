@@ -3,10 +3,15 @@ package authn
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
 
 	"encore.app/internal/config"
 	"encore.app/internal/entities"
+	"encore.app/internal/oauth2"
+	"encore.app/internal/sessions"
 	"encore.app/internal/usecases"
+	"encore.dev"
 	"encore.dev/beta/errs"
 )
 
@@ -22,22 +27,37 @@ func (c *AuthnController) HandleCallback(
 	ctx context.Context,
 	req *OAuth2CallbackRequest,
 ) (*entities.HttpCallbackResponse, error) {
-	resp, err := c.useCase.HandleCallback(ctx, req.State, req.Code)
+	resp, err := c.useCase.HandleCallback(ctx, req.State, req.Code, currentClient())
+	if errs.Code(err) == errs.Unauthenticated {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errs.WrapCode(err, errs.Internal, errs.Internal.String())
+	}
+
+	code, err := c.useCase.NewExchangeCode(ctx, resp)
 	if err != nil {
 		return nil, errs.WrapCode(err, errs.Internal, errs.Internal.String())
 	}
 
 	cfg := config.GetConfig()
 	location := fmt.Sprintf(
-		"%s/%s?accessToken=%s&refreshToken=%s",
+		"%s/%s?code=%s",
 		cfg.ClientOriginUrl,
 		cfg.ClientOauth2Callback,
-		resp.AccessToken,
-		resp.RefreshToken,
+		url.QueryEscape(code),
 	)
 	return &entities.HttpCallbackResponse{Status: 308, Location: location}, nil
 }
 
+func (c *AuthnController) HandleLogin(ctx context.Context) (*entities.HttpCallbackResponse, error) {
+	location, err := c.useCase.LoginURL(ctx)
+	if err != nil {
+		return nil, errs.WrapCode(err, errs.Internal, errs.Internal.String())
+	}
+	return &entities.HttpCallbackResponse{Status: 302, Location: location}, nil
+}
+
 func (c *AuthnController) HandleGetUserInfo(
 	ctx context.Context,
 	userId int64,
@@ -55,6 +75,61 @@ func (c *AuthnController) VerifyAccessToken(
 func (c *AuthnController) HandleRefreshToken(
 	ctx context.Context,
 	token string,
-) (*entities.CallbackResponse, error) {
+) (*usecases.RefreshResult, error) {
 	return c.useCase.RefreshToken(ctx, token)
 }
+
+func (c *AuthnController) HandleJWKS() *oauth2.JWKS {
+	return c.useCase.JWKS()
+}
+
+func (c *AuthnController) HandleExchange(
+	ctx context.Context,
+	code string,
+) (*entities.CallbackResponse, error) {
+	return c.useCase.ExchangeCode(ctx, code)
+}
+
+func (c *AuthnController) HandleLogout(ctx context.Context, sessionID string) error {
+	return c.useCase.Logout(ctx, sessionID)
+}
+
+func (c *AuthnController) HandleLogoutAll(ctx context.Context, userID int64) (int, error) {
+	return c.useCase.LogoutAll(ctx, userID)
+}
+
+func (c *AuthnController) HandleListSessions(
+	ctx context.Context,
+	userID int64,
+) ([]sessions.Session, error) {
+	return c.useCase.ListSessions(ctx, userID)
+}
+
+func (c *AuthnController) HandleRevokeSession(
+	ctx context.Context,
+	sessionID string,
+) (*sessions.Session, error) {
+	return c.useCase.RevokeSession(ctx, sessionID)
+}
+
+func (c *AuthnController) HandleImpersonate(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	userID int64,
+) (*usecases.ImpersonationResult, error) {
+	return c.useCase.Impersonate(ctx, actor, userID, currentClient())
+}
+
+// currentClient describes the device of the current request. Behind the
+// ingress the client address is only known from the forwarding headers.
+func currentClient() sessions.Client {
+	headers := encore.CurrentRequest().Headers
+	ip := headers.Get("X-Real-Ip")
+	if forwarded := headers.Get("X-Forwarded-For"); forwarded != "" {
+		ip, _, _ = strings.Cut(forwarded, ",")
+	}
+	return sessions.Client{
+		UserAgent: headers.Get("User-Agent"),
+		IP:        strings.TrimSpace(ip),
+	}
+}
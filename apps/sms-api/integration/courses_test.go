@@ -0,0 +1,285 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"encore.app/appconfig"
+	"encore.app/internal/entities"
+	"encore.app/internal/gradehistory"
+	"encore.app/internal/mdlapi"
+	"encore.app/internal/mdltest"
+	"encore.app/usrcategories"
+	"encore.app/usrcourses"
+	"encore.dev/beta/errs"
+)
+
+func TestCategoriesByRole(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+
+	cats, err := usrcategories.GetCategories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cats.Data) != 2 {
+		t.Fatalf("admin sees %d categories, want 2", len(cats.Data))
+	}
+	courses, err := usrcategories.GetCategoryCourses(ctx, mdltest.ScienceCategoryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(courses.Data) != 2 {
+		t.Fatalf("admin sees %d science courses, want 2", len(courses.Data))
+	}
+
+	as(mdltest.Teacher2ID, entities.RoleTeacher)
+	cats, err = usrcategories.GetCategories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cats.Data) != 1 || cats.Data[0].Id != mdltest.ScienceCategoryID {
+		t.Fatalf("teacher 2 sees %+v, want only science", cats.Data)
+	}
+	courses, err = usrcategories.GetCategoryCourses(ctx, mdltest.ScienceCategoryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(courses.Data) != 1 || courses.Data[0].ID != mdltest.ChemistryCourseID {
+		t.Fatalf("teacher 2 sees %+v, want only chemistry", courses.Data)
+	}
+}
+
+func TestCourseAccess(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+
+	as(mdltest.TeacherID, entities.RoleTeacher)
+	details, err := usrcourses.GetCourseDetails(ctx, mdltest.MathCourseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(details.Students) != 3 || len(details.Modules) != 3 {
+		t.Fatalf("got %d students and %d modules, want 3 and 3", len(details.Students), len(details.Modules))
+	}
+
+	as(mdltest.Teacher2ID, entities.RoleTeacher)
+	_, err = usrcourses.GetCourseDetails(ctx, mdltest.MathCourseID)
+	wantCode(t, err, errs.PermissionDenied)
+
+	as(mdltest.AdminID, entities.RoleAdmin)
+	_, err = usrcourses.GetCourseDetails(ctx, 9999)
+	wantCode(t, err, errs.NotFound)
+}
+
+func TestUpdateGradesFlushesCache(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	as(mdltest.TeacherID, entities.RoleTeacher)
+
+	for i := 0; i < 2; i++ {
+		if _, err := usrcourses.GetCourseDetails(ctx, mdltest.MathCourseID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := moodle.Calls(mdlapi.GET_CUSTOM_COURSE_DETAILS); n != 1 {
+		t.Fatalf("course details fetched %d times for two reads, want 1", n)
+	}
+
+	_, err := usrcourses.UpdateCourseGrades(ctx, &mdlapi.UpdateGradesRequest{
+		Source:     mdlapi.GradeSourceAssign,
+		CourseID:   mdltest.MathCourseID,
+		Component:  mdlapi.GradeComponentAssign,
+		ActivityID: mdltest.MathFinalCmid,
+		Grades:     []mdlapi.UpdateGrade{{StudentID: mdltest.Student3ID, Grade: 7}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := mdltest.GradeKey{StudentID: mdltest.Student3ID, Cmid: mdltest.MathFinalCmid}
+	if grade, ok := moodle.Grade(mdltest.MathCourseID, key); !ok || grade != 7 {
+		t.Fatalf("Moodle holds %v, %v, want 7", grade, ok)
+	}
+
+	details, err := usrcourses.GetCourseDetails(ctx, mdltest.MathCourseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grade := gradeOf(details, mdltest.Student3ID, mdltest.MathFinalCmid); grade == nil || *grade != 7 {
+		t.Fatalf("read after the update shows %v, want 7", grade)
+	}
+}
+
+func TestUpdateGradesValidation(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	as(mdltest.TeacherID, entities.RoleTeacher)
+
+	update := func(activityID int, grades ...mdlapi.UpdateGrade) error {
+		_, err := usrcourses.UpdateCourseGrades(ctx, &mdlapi.UpdateGradesRequest{
+			Source:     mdlapi.GradeSourceAssign,
+			CourseID:   mdltest.MathCourseID,
+			Component:  mdlapi.GradeComponentAssign,
+			ActivityID: activityID,
+			Grades:     grades,
+		})
+		return err
+	}
+	wantCode(t, update(9999, mdlapi.UpdateGrade{StudentID: mdltest.Student1ID, Grade: 5}), errs.InvalidArgument)
+	wantCode(t, update(mdltest.MathFinalCmid,
+		mdlapi.UpdateGrade{StudentID: mdltest.Student1ID, Grade: 5},
+		mdlapi.UpdateGrade{StudentID: mdltest.Student2ID, Grade: 11},
+	), errs.InvalidArgument)
+	if n := moodle.Calls(mdlapi.UPDATE_GRADES); n != 0 {
+		t.Fatalf("update called %d times for invalid requests, want 0", n)
+	}
+}
+
+func TestGradeHistoryFilters(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	// The history outlives each test; only look at what this one writes.
+	from := time.Now().UTC()
+
+	_, err := usrcourses.UpdateCourseGrades(ctx, &mdlapi.UpdateGradesRequest{
+		Source:     mdlapi.GradeSourceAssign,
+		CourseID:   mdltest.MathCourseID,
+		Component:  mdlapi.GradeComponentAssign,
+		ActivityID: mdltest.MathMidtermCmid,
+		Grades: []mdlapi.UpdateGrade{
+			{StudentID: mdltest.Student1ID, Grade: 1},
+			{StudentID: mdltest.Student2ID, Grade: 2},
+			{StudentID: mdltest.Student3ID, Grade: 3},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		students []int64
+		want     int64
+	}{
+		{nil, 3},
+		{[]int64{mdltest.Student2ID}, 1},
+		{[]int64{mdltest.Student1ID, mdltest.Student3ID}, 2},
+	} {
+		resp, err := usrcourses.GetGradeHistory(ctx, mdltest.MathCourseID, &gradehistory.ListRequest{
+			StudentID:  tc.students,
+			ActivityID: []int64{mdltest.MathMidtermCmid},
+			From:       from,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Total != tc.want {
+			t.Errorf("students %v: %d entries, want %d", tc.students, resp.Total, tc.want)
+		}
+	}
+}
+
+func TestFlushMoodleCache(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+
+	if _, err := usrcourses.GetCourseDetails(ctx, mdltest.PhysicsCourseID); err != nil {
+		t.Fatal(err)
+	}
+	courseID := int64(mdltest.PhysicsCourseID)
+	resp, err := appconfig.FlushMoodleCache(ctx, &appconfig.FlushMoodleCacheRequest{CourseID: &courseID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Flushed == 0 {
+		t.Fatal("nothing flushed after reading the course")
+	}
+	if _, err := usrcourses.GetCourseDetails(ctx, mdltest.PhysicsCourseID); err != nil {
+		t.Fatal(err)
+	}
+	if n := moodle.Calls(mdlapi.GET_CUSTOM_COURSE_DETAILS); n != 2 {
+		t.Fatalf("course details fetched %d times, want 2", n)
+	}
+
+	as(mdltest.TeacherID, entities.RoleTeacher)
+	_, err = appconfig.FlushMoodleCache(ctx, &appconfig.FlushMoodleCacheRequest{CourseID: &courseID})
+	wantCode(t, err, errs.PermissionDenied)
+}
+
+func TestGradeLocksNeedCourseAccess(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+
+	lock, err := usrcourses.LockCourseGrades(ctx, mdltest.MathCourseID, &usrcourses.LockCourseGradesRequest{ExamType: "Thi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Locks outlive the test, unlike the Moodle fixtures.
+	t.Cleanup(func() {
+		as(mdltest.AdminID, entities.RoleAdmin)
+		req := &usrcourses.UnlockCourseGradesRequest{Reason: "integration test"}
+		if _, err := usrcourses.UnlockCourseGrades(ctx, mdltest.MathCourseID, lock.ID, req); err != nil {
+			t.Error(err)
+		}
+	})
+
+	as(mdltest.TeacherID, entities.RoleTeacher)
+	locks, err := usrcourses.GetCourseGradeLocks(ctx, mdltest.MathCourseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locks.Data) != 1 || locks.Data[0].ID != lock.ID {
+		t.Fatalf("teacher sees locks %+v, want only %d", locks.Data, lock.ID)
+	}
+
+	as(mdltest.Teacher2ID, entities.RoleTeacher)
+	_, err = usrcourses.GetCourseGradeLocks(ctx, mdltest.MathCourseID)
+	wantCode(t, err, errs.PermissionDenied)
+}
+
+func TestConcurrentGradeLocks(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+
+	const requests = 5
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		ids    []int64
+		failed []error
+	)
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := usrcourses.LockCourseGrades(ctx, mdltest.PhysicsCourseID, &usrcourses.LockCourseGradesRequest{ExamType: "1T"})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed = append(failed, err)
+				return
+			}
+			ids = append(ids, lock.ID)
+		}()
+	}
+	wg.Wait()
+	// Locks outlive the test, unlike the Moodle fixtures.
+	t.Cleanup(func() {
+		req := &usrcourses.UnlockCourseGradesRequest{Reason: "integration test"}
+		for _, id := range ids {
+			if _, err := usrcourses.UnlockCourseGrades(ctx, mdltest.PhysicsCourseID, id, req); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	if len(ids) != 1 {
+		t.Fatalf("%d of %d concurrent locks were created, want 1", len(ids), requests)
+	}
+	for _, err := range failed {
+		wantCode(t, err, errs.AlreadyExists)
+	}
+}
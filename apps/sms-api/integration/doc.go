@@ -0,0 +1,7 @@
+// Package integration drives the endpoints end to end against mdltest, an
+// in-process stand-in for Moodle serving seeded fixtures. The tests need
+// the MySQL and Redis of `encore run` and take their other settings from
+// .env in this directory, so ENV must be unset or "dev". Run them with
+//
+//	encore test -tags integration ./integration/...
+package integration
@@ -0,0 +1,56 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"encore.app/internal/authz"
+	"encore.app/internal/entities"
+	"encore.app/internal/mdltest"
+	"encore.app/usrexport"
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+	"encore.dev/et"
+)
+
+func TestExportJobNeedsCourseAccess(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	req := &usrexport.CreateExportJobRequest{CourseID: mdltest.MathCourseID}
+
+	for _, caller := range []struct {
+		id   int
+		role entities.UserRole
+	}{
+		{mdltest.Student1ID, entities.RoleStudent},
+		// Teacher 2 teaches chemistry only.
+		{mdltest.Teacher2ID, entities.RoleTeacher},
+	} {
+		as(caller.id, caller.role)
+		_, err := usrexport.CreateExportJob(ctx, req)
+		wantCode(t, err, errs.PermissionDenied)
+	}
+
+	as(mdltest.TeacherID, entities.RoleTeacher)
+	if _, err := usrexport.CreateExportJob(ctx, req); err != nil {
+		t.Fatalf("the math teacher could not export math: %v", err)
+	}
+}
+
+func TestTemplatePreviewNeedsCourseAccess(t *testing.T) {
+	setup(t)
+	// A key that may manage templates, but not read any course.
+	et.OverrideAuthInfo(auth.UID("apikey:1"), &entities.TokenPayload{
+		Role:     entities.RoleService,
+		APIKeyID: 1,
+		Scopes:   []string{string(authz.PermExportTemplateManage)},
+	})
+
+	_, err := usrexport.PreviewExportTemplate(context.Background(), &usrexport.PreviewExportTemplateRequest{
+		CourseID:   mdltest.MathCourseID,
+		TemplateID: "1",
+	})
+	wantCode(t, err, errs.PermissionDenied)
+}
@@ -0,0 +1,95 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+
+	"encore.app/appconfig"
+	"encore.app/internal/config"
+	"encore.app/internal/entities"
+	"encore.app/internal/mdlapi"
+	"encore.app/internal/mdltest"
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+	"encore.dev/et"
+)
+
+var moodle *mdltest.Server
+
+func TestMain(m *testing.M) {
+	cfg := config.GetConfig()
+	if cfg.MoodleApiConfig.ApiToken != mdltest.Token || cfg.Oauth2Config.ClientId != mdltest.ClientID {
+		fmt.Fprintln(os.Stderr, "integration: Moodle settings do not match mdltest; unset MOODLE_* and CLIENT_* to use .env")
+		os.Exit(1)
+	}
+	u, err := url.Parse(cfg.MoodleApiConfig.Url)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "integration: bad MOODLE_URL:", err)
+		os.Exit(1)
+	}
+	moodle, err = mdltest.Listen(u.Host, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "integration:", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	moodle.Close()
+	os.Exit(code)
+}
+
+// setup restores the seeded Moodle and drops everything cached from it, so
+// that each test starts from the fixtures. The test then calls as the admin.
+func setup(t *testing.T) {
+	t.Helper()
+	moodle.Reset(nil)
+
+	as(mdltest.AdminID, entities.RoleAdmin)
+	for _, id := range []int64{mdltest.MathCourseID, mdltest.PhysicsCourseID, mdltest.ChemistryCourseID} {
+		if _, err := appconfig.FlushMoodleCache(context.Background(), &appconfig.FlushMoodleCacheRequest{CourseID: &id}); err != nil {
+			t.Fatalf("flush course %d: %v", id, err)
+		}
+	}
+	for _, id := range []int64{mdltest.MathCategoryID, mdltest.ScienceCategoryID} {
+		if _, err := appconfig.FlushMoodleCache(context.Background(), &appconfig.FlushMoodleCacheRequest{CategoryID: &id}); err != nil {
+			t.Fatalf("flush category %d: %v", id, err)
+		}
+	}
+}
+
+// as makes the following calls of the test come from a Moodle user.
+func as(userID int, role entities.UserRole) {
+	et.OverrideAuthInfo(auth.UID(strconv.Itoa(userID)), &entities.TokenPayload{
+		UserID:    int64(userID),
+		Role:      role,
+		SessionID: "integration",
+	})
+}
+
+func wantCode(t *testing.T, err error, want errs.ErrCode) {
+	t.Helper()
+	if got := errs.Code(err); got != want {
+		t.Fatalf("error code = %s (%v), want %s", got, err, want)
+	}
+}
+
+// gradeOf returns a student's grade for an activity in the course details,
+// or nil if they have none.
+func gradeOf(details *mdlapi.GetCourseGradesResponse, studentID, cmid int) *float64 {
+	student := details.FindStudent(studentID)
+	if student == nil {
+		return nil
+	}
+	for _, g := range student.Grades {
+		if g.ActivityID == cmid {
+			return &g.Grade
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"encore.app/internal/entities"
+	"encore.app/internal/mdlapi"
+	"encore.app/internal/mdltest"
+	"encore.app/usrcourses"
+	"encore.app/usrexport"
+	"encore.dev/beta/errs"
+)
+
+func TestReadsAreRetried(t *testing.T) {
+	setup(t)
+	moodle.InjectFault(mdlapi.GET_CUSTOM_COURSE_DETAILS, mdltest.Fault{Status: http.StatusBadGateway, Times: 2})
+
+	if _, err := usrcourses.GetCourseDetails(context.Background(), mdltest.MathCourseID); err != nil {
+		t.Fatalf("read failed despite retries: %v", err)
+	}
+	if n := moodle.Calls(mdlapi.GET_CUSTOM_COURSE_DETAILS); n != 3 {
+		t.Fatalf("course details called %d times, want 3", n)
+	}
+}
+
+func TestWritesAreNotRetried(t *testing.T) {
+	setup(t)
+	as(mdltest.TeacherID, entities.RoleTeacher)
+	moodle.InjectFault(mdlapi.UPDATE_GRADES, mdltest.Fault{Status: http.StatusServiceUnavailable, Times: 1})
+
+	_, err := usrcourses.UpdateCourseGrades(context.Background(), &mdlapi.UpdateGradesRequest{
+		Source:     mdlapi.GradeSourceQuiz,
+		CourseID:   mdltest.MathCourseID,
+		Component:  mdlapi.GradeComponentQuiz,
+		ActivityID: mdltest.MathQuizCmid,
+		Grades:     []mdlapi.UpdateGrade{{StudentID: mdltest.Student3ID, Grade: 4}},
+	})
+	if err == nil {
+		t.Fatal("update succeeded although Moodle failed")
+	}
+	if n := moodle.Calls(mdlapi.UPDATE_GRADES); n != 1 {
+		t.Fatalf("update called %d times, want 1", n)
+	}
+	key := mdltest.GradeKey{StudentID: mdltest.Student3ID, Cmid: mdltest.MathQuizCmid}
+	if _, ok := moodle.Grade(mdltest.MathCourseID, key); ok {
+		t.Fatal("grade written although the update failed")
+	}
+}
+
+func TestExportsAreNotRetried(t *testing.T) {
+	setup(t)
+	moodle.InjectFault(mdlapi.EXPORT_COURSE_GRADES, mdltest.Fault{Status: http.StatusBadGateway, Times: 1})
+
+	_, err := usrexport.ExportCourseGrades(context.Background(), mdltest.MathCourseID, &usrexport.ExportCourseRequest{})
+	if err == nil {
+		t.Fatal("export succeeded although Moodle failed")
+	}
+	if n := moodle.Calls(mdlapi.EXPORT_COURSE_GRADES); n != 1 {
+		t.Fatalf("export called %d times, want 1", n)
+	}
+}
+
+func TestMoodleExceptions(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		errorCode string
+		want      errs.ErrCode
+	}{
+		{"nopermissions", errs.PermissionDenied},
+		{"invalidparameter", errs.InvalidArgument},
+		{"sitemaintenance", errs.Unavailable},
+		{"dmlreadexception", errs.Internal},
+	} {
+		moodle.InjectFault(mdlapi.GET_CUSTOM_COURSE_DETAILS, mdltest.Fault{
+			Exception: &mdltest.Exception{Exception: "moodle_exception", ErrorCode: tc.errorCode, Message: tc.errorCode},
+			Times:     1,
+		})
+		_, err := usrcourses.GetCourseDetails(ctx, mdltest.MathCourseID)
+		wantCode(t, err, tc.want)
+	}
+	// An exception is an answer: it is not retried.
+	if n := moodle.Calls(mdlapi.GET_CUSTOM_COURSE_DETAILS); n != 4 {
+		t.Fatalf("course details called %d times for 4 exceptions, want 4", n)
+	}
+}
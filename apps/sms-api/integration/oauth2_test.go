@@ -0,0 +1,66 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"encore.app/authn"
+	"encore.app/internal/entities"
+	"encore.app/internal/mdltest"
+	"encore.dev/beta/errs"
+)
+
+func TestOAuth2Login(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	moodle.LoginAs(mdltest.TeacherID)
+
+	login, err := authn.OAuth2Login(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The browser's part: Moodle logs the user in and sends them back to
+	// the callback.
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := browser.Get(login.Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback := &authn.OAuth2CallbackRequest{State: back.Query().Get("state"), Code: back.Query().Get("code")}
+
+	done, err := authn.OAuth2Callback(ctx, callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := url.Parse(done.Location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := authn.Exchange(ctx, &authn.ExchangeRequest{Code: client.Query().Get("code")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, payload, err := authn.AuthHandler(ctx, &authn.AuthParams{Authorization: "Bearer " + tokens.AccessToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.UserID != mdltest.TeacherID || payload.Role != entities.RoleTeacher {
+		t.Fatalf("logged in as %d (%s), want the teacher", payload.UserID, payload.Role)
+	}
+
+	_, err = authn.OAuth2Callback(ctx, callback)
+	wantCode(t, err, errs.Unauthenticated)
+}
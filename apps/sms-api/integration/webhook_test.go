@@ -0,0 +1,150 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"encore.app/authn"
+	"encore.app/internal/config"
+	"encore.app/internal/gradehistory"
+	"encore.app/internal/mdlapi"
+	"encore.app/internal/mdlevents"
+	"encore.app/internal/mdltest"
+	"encore.app/mdlwebhook"
+	"encore.app/usrcourses"
+	"encore.dev/et"
+)
+
+// post sends body to the webhook, signed with secret, and returns the
+// response.
+func post(t *testing.T, secret string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	ts := time.Now().Unix()
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/moodle", bytes.NewReader(body))
+	req.Header.Set("X-Moodle-Timestamp", strconv.FormatInt(ts, 10))
+	req.Header.Set("X-Moodle-Signature", mdlevents.Sign(secret, ts, body))
+	rec := httptest.NewRecorder()
+	mdlwebhook.Receive(rec, req)
+	return rec
+}
+
+func TestWebhookPublishesOnce(t *testing.T) {
+	setup(t)
+	secret := config.GetConfig().WebhookConfig.Secret
+	event := &mdlevents.Event{
+		ID:          fmt.Sprintf("integration-%d", time.Now().UnixNano()),
+		EventName:   mdlevents.CourseUpdated,
+		CourseID:    mdltest.MathCourseID,
+		CategoryID:  mdltest.MathCategoryID,
+		TimeCreated: time.Now().Unix(),
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := post(t, "wrong-secret", body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrongly signed event: status %d, want 401", rec.Code)
+	}
+
+	for i, want := range []bool{true, false} {
+		rec := post(t, secret, body)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("delivery %d: status %d, want 202: %s", i+1, rec.Code, rec.Body)
+		}
+		var resp mdlwebhook.ReceiveResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Published != want {
+			t.Fatalf("delivery %d: published = %v, want %v", i+1, resp.Published, want)
+		}
+	}
+
+	published := 0
+	for _, msg := range et.Topic(mdlwebhook.MoodleEvents).PublishedMessages() {
+		if msg.ID == event.ID {
+			published++
+		}
+	}
+	if published != 1 {
+		t.Fatalf("event published %d times, want 1", published)
+	}
+}
+
+func TestMoodleEventFlushesCache(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+
+	read := func() {
+		t.Helper()
+		if _, err := usrcourses.GetCourseDetails(ctx, mdltest.MathCourseID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read()
+	read()
+	if n := moodle.Calls(mdlapi.GET_CUSTOM_COURSE_DETAILS); n != 1 {
+		t.Fatalf("course details called %d times before the event, want 1", n)
+	}
+
+	event := &mdlevents.Event{ID: "flush", EventName: mdlevents.UserGraded, CourseID: mdltest.MathCourseID}
+	if err := authn.GetContainer().GetMoodleEventController().Invalidate(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	read()
+	if n := moodle.Calls(mdlapi.GET_CUSTOM_COURSE_DETAILS); n != 2 {
+		t.Fatalf("course details called %d times after the event, want 2", n)
+	}
+}
+
+func TestMoodleGradeRecordedOnce(t *testing.T) {
+	setup(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	grade := 7.5
+	event := &mdlevents.Event{
+		// The history outlives each test; make the event new to it.
+		ID:            fmt.Sprintf("record-once-%d", now.UnixNano()),
+		EventName:     mdlevents.UserGraded,
+		CourseID:      mdltest.MathCourseID,
+		UserID:        mdltest.TeacherID,
+		RelatedUserID: mdltest.Student1ID,
+		Origin:        "web",
+		TimeCreated:   now.Unix(),
+		Grade:         &mdlevents.Grade{ActivityID: mdltest.MathFinalCmid, FinalGrade: &grade},
+	}
+	for range 2 {
+		if err := authn.GetContainer().GetMoodleEventController().RecordGrade(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := usrcourses.GetGradeHistory(ctx, mdltest.MathCourseID, &gradehistory.ListRequest{
+		StudentID:  []int64{mdltest.Student1ID},
+		ActivityID: []int64{mdltest.MathFinalCmid},
+		From:       now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, e := range resp.Data {
+		if e.EventID == event.ID {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Fatalf("event recorded %d times, want 1", n)
+	}
+}
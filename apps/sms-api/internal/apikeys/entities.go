@@ -0,0 +1,43 @@
+package apikeys
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a key does not exist or has been revoked.
+var ErrNotFound = errors.New("api key not found")
+
+// touchInterval throttles last-used writes to one per key and minute.
+const touchInterval = time.Minute
+
+// Key is the stored part of an API key of a service account. Only the hash
+// of the secret is kept; the secret is shown once, when it is created or
+// rotated.
+type Key struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Prefix identifies the key in lists and logs. It is not secret.
+	Prefix string `json:"prefix"`
+	Hash   string `json:"-"`
+	// Scopes are the permissions the key holds.
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  int64      `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedBy  *int64     `json:"revoked_by"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// Usable reports whether the key may authenticate at now.
+func (k *Key) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// TouchDue reports whether the last use recorded for the key is old enough to
+// be recorded again at now.
+func (k *Key) TouchDue(now time.Time) bool {
+	return k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= touchInterval
+}
@@ -0,0 +1,52 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"encore.app/internal/helper"
+)
+
+// keyPrefix marks our keys, so that they are recognised when leaked.
+const keyPrefix = "sms_"
+
+var errMalformed = errors.New("malformed api key")
+
+// Generate returns a new secret key and the prefix and hash to store. Keys
+// look like "sms_<prefix>_<secret>".
+func Generate() (key, prefix, hash string) {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	prefix = hex.EncodeToString(b)
+	key = keyPrefix + prefix + "_" + helper.RandomToken()
+	return key, prefix, Hash(key)
+}
+
+// Parse returns the prefix of key, to look up its stored hash.
+func Parse(key string) (string, error) {
+	rest, ok := strings.CutPrefix(key, keyPrefix)
+	if !ok {
+		return "", errMalformed
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" || secret == "" {
+		return "", errMalformed
+	}
+	return prefix, nil
+}
+
+// Hash is the stored form of a key. Keys are random, so a plain SHA-256
+// suffices.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches reports in constant time whether key hashes to hash.
+func Matches(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(hash)) == 1
+}
@@ -0,0 +1,83 @@
+package apikeys_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"encore.app/internal/apikeys"
+)
+
+func TestGenerateAndParse(t *testing.T) {
+	key, prefix, hash := apikeys.Generate()
+	if !strings.HasPrefix(key, "sms_"+prefix+"_") {
+		t.Fatalf("key %q does not start with its prefix %q", key, prefix)
+	}
+
+	got, err := apikeys.Parse(key)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got != prefix {
+		t.Fatalf("Parse() = %q, want %q", got, prefix)
+	}
+	if !apikeys.Matches(key, hash) {
+		t.Fatal("key does not match its hash")
+	}
+	if apikeys.Matches(key+"x", hash) {
+		t.Fatal("altered key matches the hash")
+	}
+}
+
+func TestParseRejectsMalformed(t *testing.T) {
+	for _, key := range []string{"", "sms_", "sms_abc", "sms__secret", "sms_abc_", "key_abc_secret"} {
+		if _, err := apikeys.Parse(key); err == nil {
+			t.Errorf("Parse(%q) accepted a malformed key", key)
+		}
+	}
+}
+
+func TestUsable(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name string
+		key  apikeys.Key
+		want bool
+	}{
+		{"no expiry", apikeys.Key{}, true},
+		{"not yet expired", apikeys.Key{ExpiresAt: &future}, true},
+		{"expired", apikeys.Key{ExpiresAt: &past}, false},
+		{"revoked", apikeys.Key{RevokedAt: &past}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.Usable(now); got != tt.want {
+				t.Fatalf("Usable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTouchDue(t *testing.T) {
+	now := time.Now()
+	recent, old := now.Add(-30*time.Second), now.Add(-2*time.Minute)
+
+	tests := []struct {
+		name string
+		key  apikeys.Key
+		want bool
+	}{
+		{"never used", apikeys.Key{}, true},
+		{"used recently", apikeys.Key{LastUsedAt: &recent}, false},
+		{"used a while ago", apikeys.Key{LastUsedAt: &old}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.TouchDue(now); got != tt.want {
+				t.Fatalf("TouchDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/dbx"
+)
+
+const table = "sms_api_keys"
+
+const selectColumns = "id, name, prefix, key_hash, CAST(scopes AS CHAR) AS scopes, created_by," +
+	" created_at, rotated_at, last_used_at, expires_at, revoked_by, revoked_at"
+
+type mysqlRepository struct {
+	db *dbx.DB
+}
+
+var _ Repository = (*mysqlRepository)(nil)
+
+// NewMySQLRepository returns a Repository backed by the provided dbx connection.
+func NewMySQLRepository(db *dbx.DB) Repository {
+	return &mysqlRepository{db: db}
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000")
+}
+
+func (r *mysqlRepository) Create(ctx context.Context, key *Key) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return fmt.Errorf("apikeys: encode scopes: %w", err)
+	}
+	var expiresAt interface{}
+	if key.ExpiresAt != nil {
+		expiresAt = formatTime(*key.ExpiresAt)
+	}
+
+	res, err := r.db.WithContext(ctx).
+		Insert(table, dbx.Params{
+			"name":       key.Name,
+			"prefix":     key.Prefix,
+			"key_hash":   key.Hash,
+			"scopes":     string(scopes),
+			"created_by": key.CreatedBy,
+			"created_at": formatTime(key.CreatedAt),
+			"expires_at": expiresAt,
+		}).Execute()
+	if err != nil {
+		return fmt.Errorf("apikeys: insert: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("apikeys: last insert id: %w", err)
+	}
+	key.ID = id
+	return nil
+}
+
+func (r *mysqlRepository) Get(ctx context.Context, id int64) (*Key, error) {
+	return r.findOne(ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE id = {:id}", selectColumns, table),
+		dbx.Params{"id": id},
+	)
+}
+
+func (r *mysqlRepository) FindByPrefix(ctx context.Context, prefix string) (*Key, error) {
+	return r.findOne(ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE prefix = {:prefix}", selectColumns, table),
+		dbx.Params{"prefix": prefix},
+	)
+}
+
+func (r *mysqlRepository) List(ctx context.Context) ([]Key, error) {
+	rows := []dbxKey{}
+	if err := r.db.WithContext(ctx).
+		NewQuery(fmt.Sprintf("SELECT %s FROM %s ORDER BY created_at DESC", selectColumns, table)).
+		All(&rows); err != nil {
+		return nil, fmt.Errorf("apikeys: select: %w", err)
+	}
+
+	keys := make([]Key, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, row.toKey())
+	}
+	return keys, nil
+}
+
+func (r *mysqlRepository) Rotate(ctx context.Context, id int64, prefix, hash string) (*Key, error) {
+	res, err := r.db.WithContext(ctx).
+		NewQuery(fmt.Sprintf(
+			"UPDATE %s SET prefix = {:prefix}, key_hash = {:hash}, rotated_at = {:at}"+
+				" WHERE id = {:id} AND revoked_at IS NULL", table)).
+		Bind(dbx.Params{
+			"prefix": prefix,
+			"hash":   hash,
+			"at":     formatTime(time.Now()),
+			"id":     id,
+		}).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("apikeys: rotate: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *mysqlRepository) Revoke(ctx context.Context, id, actorID int64) (*Key, error) {
+	res, err := r.db.WithContext(ctx).
+		NewQuery(fmt.Sprintf(
+			"UPDATE %s SET revoked_by = {:actor}, revoked_at = {:at}"+
+				" WHERE id = {:id} AND revoked_at IS NULL", table)).
+		Bind(dbx.Params{
+			"actor": actorID,
+			"at":    formatTime(time.Now()),
+			"id":    id,
+		}).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("apikeys: revoke: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *mysqlRepository) Touch(ctx context.Context, id int64) error {
+	now := time.Now()
+	_, err := r.db.WithContext(ctx).
+		NewQuery(fmt.Sprintf(
+			"UPDATE %s SET last_used_at = {:at}"+
+				" WHERE id = {:id} AND (last_used_at IS NULL OR last_used_at < {:before})", table)).
+		Bind(dbx.Params{
+			"at":     formatTime(now),
+			"before": formatTime(now.Add(-touchInterval)),
+			"id":     id,
+		}).
+		Execute()
+	if err != nil {
+		return fmt.Errorf("apikeys: touch: %w", err)
+	}
+	return nil
+}
+
+func (r *mysqlRepository) findOne(ctx context.Context, query string, params dbx.Params) (*Key, error) {
+	row := dbxKey{}
+	err := r.db.WithContext(ctx).NewQuery(query).Bind(params).One(&row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("apikeys: select: %w", err)
+	}
+	key := row.toKey()
+	return &key, nil
+}
+
+// ── scan type ─────────────────────────────────────────────────────────────────
+
+type dbxKey struct {
+	ID         int64         `db:"id"`
+	Name       string        `db:"name"`
+	Prefix     string        `db:"prefix"`
+	Hash       string        `db:"key_hash"`
+	Scopes     string        `db:"scopes"`
+	CreatedBy  int64         `db:"created_by"`
+	CreatedAt  time.Time     `db:"created_at"`
+	RotatedAt  sql.NullTime  `db:"rotated_at"`
+	LastUsedAt sql.NullTime  `db:"last_used_at"`
+	ExpiresAt  sql.NullTime  `db:"expires_at"`
+	RevokedBy  sql.NullInt64 `db:"revoked_by"`
+	RevokedAt  sql.NullTime  `db:"revoked_at"`
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
+
+func (k *dbxKey) toKey() Key {
+	key := Key{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Hash:       k.Hash,
+		Scopes:     []string{},
+		CreatedBy:  k.CreatedBy,
+		CreatedAt:  k.CreatedAt,
+		RotatedAt:  nullTime(k.RotatedAt),
+		LastUsedAt: nullTime(k.LastUsedAt),
+		ExpiresAt:  nullTime(k.ExpiresAt),
+		RevokedAt:  nullTime(k.RevokedAt),
+	}
+	// Scopes are written by Create only, so they always decode.
+	_ = json.Unmarshal([]byte(k.Scopes), &key.Scopes)
+	if k.RevokedBy.Valid {
+		v := k.RevokedBy.Int64
+		key.RevokedBy = &v
+	}
+	return key
+}
@@ -0,0 +1,30 @@
+package apikeys
+
+import "context"
+
+// Repository is the persistence contract for API keys.
+type Repository interface {
+	// Create stores a new key and fills in its ID.
+	Create(ctx context.Context, key *Key) error
+
+	// Get returns a key by ID, revoked or not.
+	Get(ctx context.Context, id int64) (*Key, error)
+
+	// FindByPrefix returns the key with the given prefix, revoked or not.
+	FindByPrefix(ctx context.Context, prefix string) (*Key, error)
+
+	// List returns every key, newest first.
+	List(ctx context.Context) ([]Key, error)
+
+	// Rotate replaces the secret of an active key. The old secret stops
+	// working at once. Returns ErrNotFound when the key has been revoked.
+	Rotate(ctx context.Context, id int64, prefix, hash string) (*Key, error)
+
+	// Revoke disables a key for good. Returns ErrNotFound when the key does
+	// not exist or has already been revoked.
+	Revoke(ctx context.Context, id, actorID int64) (*Key, error)
+
+	// Touch records that the key was used now. It writes at most once per
+	// minute and key.
+	Touch(ctx context.Context, id int64) error
+}
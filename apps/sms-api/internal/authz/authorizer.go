@@ -0,0 +1,97 @@
+package authz
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"encore.app/internal/entities"
+)
+
+// cacheTTL is how long the policy is kept in memory. A change made through
+// another instance takes effect here within that time.
+const cacheTTL = 30 * time.Second
+
+// Authorizer answers permission checks. It keeps the policy in memory so that
+// checks do not read Redis on every request.
+type Authorizer struct {
+	repo Repository
+
+	mu       sync.RWMutex
+	policy   Policy
+	loadedAt time.Time
+}
+
+func NewAuthorizer(repo Repository) *Authorizer {
+	return &Authorizer{repo: repo}
+}
+
+// Policy returns the policy in effect: the configured one, or DefaultPolicy.
+func (a *Authorizer) Policy(ctx context.Context) (Policy, error) {
+	a.mu.RLock()
+	policy, loadedAt := a.policy, a.loadedAt
+	a.mu.RUnlock()
+	if policy != nil && time.Since(loadedAt) < cacheTTL {
+		return policy, nil
+	}
+
+	policy, err := a.repo.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		policy = DefaultPolicy()
+	}
+	a.store(policy)
+	return policy, nil
+}
+
+// Can reports whether role has been granted perm.
+func (a *Authorizer) Can(ctx context.Context, role entities.UserRole, perm Permission) (bool, error) {
+	policy, err := a.Policy(ctx)
+	if err != nil {
+		return false, err
+	}
+	return policy.Allows(role, perm), nil
+}
+
+// Permits reports whether caller holds perm: API keys by their scopes, users
+// by their role.
+func (a *Authorizer) Permits(
+	ctx context.Context,
+	caller *entities.TokenPayload,
+	perm Permission,
+) (bool, error) {
+	if caller.Role == entities.RoleService {
+		return slices.Contains(caller.Scopes, string(perm)), nil
+	}
+	return a.Can(ctx, caller.Role, perm)
+}
+
+// SetPolicy validates and saves a policy.
+func (a *Authorizer) SetPolicy(ctx context.Context, policy Policy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if err := a.repo.Set(ctx, policy); err != nil {
+		return err
+	}
+	a.store(policy)
+	return nil
+}
+
+// ResetPolicy reverts to DefaultPolicy.
+func (a *Authorizer) ResetPolicy(ctx context.Context) error {
+	if err := a.repo.Delete(ctx); err != nil {
+		return err
+	}
+	a.store(DefaultPolicy())
+	return nil
+}
+
+func (a *Authorizer) store(policy Policy) {
+	a.mu.Lock()
+	a.policy, a.loadedAt = policy, time.Now()
+	a.mu.Unlock()
+}
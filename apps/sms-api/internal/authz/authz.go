@@ -0,0 +1,178 @@
+// Package authz decides which roles may perform which actions. Endpoints
+// require named permissions (grades.update, audit.read, …) and a policy,
+// configurable by admins at runtime, grants permissions to roles.
+package authz
+
+import (
+	"fmt"
+	"slices"
+
+	"encore.app/internal/entities"
+)
+
+// Permission names an action that a role can be granted.
+type Permission string
+
+const (
+	// Read and grade any course.
+	PermCoursesAccessAll Permission = "courses.access_all"
+	// Read and grade the courses one teaches.
+	PermCoursesAccessOwn Permission = "courses.access_own"
+	// Write grades of a course.
+	PermGradesUpdate Permission = "grades.update"
+	// Lock a course's grades, or one exam type in it.
+	PermGradesLock Permission = "grades.lock"
+	// Lift a grade lock.
+	PermGradesUnlock Permission = "grades.unlock"
+	// List the grade locks of a course.
+	PermGradeLocksRead Permission = "grades.locks.read"
+	// Read the consolidated grade report of a category.
+	PermReportsRead Permission = "reports.read"
+	// Queue background exports and download their files.
+	PermExportJobsRun Permission = "export.jobs.run"
+	// Print the transcript of any student, not just one's own.
+	PermTranscriptReadAny Permission = "transcript.read_any"
+	// List, upload, preview and delete export templates.
+	PermExportTemplateManage Permission = "export.template.manage"
+	// Set or remove the application language pack.
+	PermLangPackManage Permission = "config.langpack.manage"
+	// Read the grade coefficients of a category.
+	PermCoefficientsRead Permission = "config.coefficients.read"
+	// Set or remove the grade coefficients of a category.
+	PermCoefficientsManage Permission = "config.coefficients.manage"
+	// Read the audit log and its statistics.
+	PermAuditRead Permission = "audit.read"
+	// Purge old audit log entries.
+	PermAuditPurge Permission = "audit.purge"
+	// List and revoke the sessions of other users.
+	PermSessionsManage Permission = "sessions.manage"
+	// View the application as another user, without changing anything.
+	PermUsersImpersonate Permission = "users.impersonate"
+	// Create, rotate and revoke API keys of service accounts.
+	PermAPIKeysManage Permission = "apikeys.manage"
+	// Flush the cached Moodle data of a course or category.
+	PermCacheManage Permission = "cache.manage"
+	// Change this policy.
+	PermPermissionsManage Permission = "permissions.manage"
+)
+
+// PermissionInfo describes a permission for the admin UI.
+type PermissionInfo struct {
+	Name        Permission `json:"name"`
+	Description string     `json:"description"`
+}
+
+var permissions = []PermissionInfo{
+	{PermCoursesAccessAll, "Access the grades of every course"},
+	{PermCoursesAccessOwn, "Access the grades of courses one teaches"},
+	{PermGradesUpdate, "Update course grades"},
+	{PermGradesLock, "Lock course grades"},
+	{PermGradesUnlock, "Unlock course grades"},
+	{PermGradeLocksRead, "List course grade locks"},
+	{PermReportsRead, "Read category grade reports"},
+	{PermExportJobsRun, "Run background exports"},
+	{PermTranscriptReadAny, "Print the transcript of any student"},
+	{PermExportTemplateManage, "Manage export templates"},
+	{PermLangPackManage, "Manage the language pack"},
+	{PermCoefficientsRead, "Read grade coefficients"},
+	{PermCoefficientsManage, "Manage grade coefficients"},
+	{PermAuditRead, "Read the audit log"},
+	{PermAuditPurge, "Purge the audit log"},
+	{PermSessionsManage, "Manage user sessions"},
+	{PermUsersImpersonate, "View the application as another user"},
+	{PermAPIKeysManage, "Manage API keys"},
+	{PermCacheManage, "Flush cached Moodle data"},
+	{PermPermissionsManage, "Manage role permissions"},
+}
+
+// Permissions lists every permission, in display order.
+func Permissions() []PermissionInfo {
+	return slices.Clone(permissions)
+}
+
+// Valid reports whether p is a known permission.
+func (p Permission) Valid() bool {
+	return slices.ContainsFunc(permissions, func(i PermissionInfo) bool { return i.Name == p })
+}
+
+// Roles lists the roles a policy can grant permissions to.
+var Roles = []entities.UserRole{
+	entities.RoleAdmin,
+	entities.RoleManager,
+	entities.RoleTeacher,
+	entities.RoleStudent,
+}
+
+// Policy grants permissions to roles. Roles that are not listed have none.
+type Policy map[entities.UserRole][]Permission
+
+// DefaultPolicy is used until an admin configures a policy. It matches the
+// role checks the endpoints had before permissions existed.
+func DefaultPolicy() Policy {
+	return Policy{
+		entities.RoleAdmin: {
+			PermCoursesAccessAll,
+			PermGradesUpdate,
+			PermGradesLock,
+			PermGradesUnlock,
+			PermGradeLocksRead,
+			PermReportsRead,
+			PermExportJobsRun,
+			PermTranscriptReadAny,
+			PermExportTemplateManage,
+			PermLangPackManage,
+			PermCoefficientsRead,
+			PermCoefficientsManage,
+			PermAuditRead,
+			PermAuditPurge,
+			PermSessionsManage,
+			PermUsersImpersonate,
+			PermAPIKeysManage,
+			PermCacheManage,
+			PermPermissionsManage,
+		},
+		entities.RoleManager: {
+			PermCoursesAccessAll,
+			PermGradesUpdate,
+			PermGradesLock,
+			PermGradeLocksRead,
+			PermReportsRead,
+			PermExportJobsRun,
+			PermTranscriptReadAny,
+			PermExportTemplateManage,
+			PermCoefficientsRead,
+		},
+		entities.RoleTeacher: {
+			PermCoursesAccessOwn,
+			PermGradesUpdate,
+			PermGradeLocksRead,
+			PermReportsRead,
+			PermExportJobsRun,
+			PermCoefficientsRead,
+		},
+	}
+}
+
+// Allows reports whether role has been granted perm.
+func (p Policy) Allows(role entities.UserRole, perm Permission) bool {
+	return slices.Contains(p[role], perm)
+}
+
+// Validate rejects unknown roles and permissions. Admins must keep
+// permissions.manage so that a policy can always be changed back.
+func (p Policy) Validate() error {
+	for role, perms := range p {
+		if !slices.Contains(Roles, role) {
+			return fmt.Errorf("invalid role: %q", role)
+		}
+		for _, perm := range perms {
+			if !perm.Valid() {
+				return fmt.Errorf("invalid permission: %q", perm)
+			}
+		}
+	}
+	if !p.Allows(entities.RoleAdmin, PermPermissionsManage) {
+		return fmt.Errorf("%s must keep %s", entities.RoleAdmin, PermPermissionsManage)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package authz_test
+
+import (
+	"context"
+	"testing"
+
+	"encore.app/internal/authz"
+	"encore.app/internal/entities"
+)
+
+type memRepo struct {
+	policy authz.Policy
+	gets   int
+}
+
+func (r *memRepo) Get(context.Context) (authz.Policy, error) {
+	r.gets++
+	return r.policy, nil
+}
+
+func (r *memRepo) Set(_ context.Context, p authz.Policy) error {
+	r.policy = p
+	return nil
+}
+
+func (r *memRepo) Delete(context.Context) error {
+	r.policy = nil
+	return nil
+}
+
+func TestDefaultPolicyIsValid(t *testing.T) {
+	if err := authz.DefaultPolicy().Validate(); err != nil {
+		t.Fatalf("default policy invalid: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	admin := []authz.Permission{authz.PermPermissionsManage}
+	tests := []struct {
+		name    string
+		policy  authz.Policy
+		wantErr bool
+	}{
+		{"admin only", authz.Policy{entities.RoleAdmin: admin}, false},
+		{"unknown role", authz.Policy{entities.RoleAdmin: admin, "guest": nil}, true},
+		{
+			"unknown permission",
+			authz.Policy{entities.RoleAdmin: admin, entities.RoleTeacher: {"grades.delete"}},
+			true,
+		},
+		{"admin locked out", authz.Policy{entities.RoleAdmin: {authz.PermAuditRead}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	repo := &memRepo{}
+	a := authz.NewAuthorizer(repo)
+
+	ok, err := a.Can(ctx, entities.RoleManager, authz.PermGradesLock)
+	if err != nil || !ok {
+		t.Fatalf("manager should lock grades by default: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := a.Can(ctx, entities.RoleStudent, authz.PermGradesUpdate); ok {
+		t.Fatal("student should not update grades by default")
+	}
+	if repo.gets != 1 {
+		t.Fatalf("policy loaded %d times, want 1 (cached)", repo.gets)
+	}
+
+	policy := authz.Policy{
+		entities.RoleAdmin:   {authz.PermPermissionsManage},
+		entities.RoleTeacher: {authz.PermGradesLock},
+	}
+	if err := a.SetPolicy(ctx, policy); err != nil {
+		t.Fatalf("SetPolicy: %v", err)
+	}
+	if ok, _ := a.Can(ctx, entities.RoleManager, authz.PermGradesLock); ok {
+		t.Fatal("manager kept grades.lock")
+	}
+	if ok, _ := a.Can(ctx, entities.RoleTeacher, authz.PermGradesLock); !ok {
+		t.Fatal("teacher was not granted grades.lock")
+	}
+
+	if err := a.SetPolicy(ctx, authz.Policy{}); err == nil {
+		t.Fatal("SetPolicy accepted a policy that locks admins out")
+	}
+
+	if err := a.ResetPolicy(ctx); err != nil {
+		t.Fatalf("ResetPolicy: %v", err)
+	}
+	if ok, _ := a.Can(ctx, entities.RoleManager, authz.PermGradesLock); !ok {
+		t.Fatal("reset did not restore the default policy")
+	}
+}
+
+func TestPermitsAPIKeyByScopes(t *testing.T) {
+	ctx := context.Background()
+	a := authz.NewAuthorizer(&memRepo{})
+	key := &entities.TokenPayload{
+		Role:     entities.RoleService,
+		APIKeyID: 1,
+		Scopes:   []string{string(authz.PermCoursesAccessAll)},
+	}
+
+	if ok, _ := a.Permits(ctx, key, authz.PermCoursesAccessAll); !ok {
+		t.Fatal("key was not granted its scope")
+	}
+	if ok, _ := a.Permits(ctx, key, authz.PermGradesUpdate); ok {
+		t.Fatal("key was granted a permission outside its scopes")
+	}
+	admin := &entities.TokenPayload{UserID: 2, Role: entities.RoleAdmin}
+	if ok, _ := a.Permits(ctx, admin, authz.PermGradesUpdate); !ok {
+		t.Fatal("user permissions should come from the policy")
+	}
+}
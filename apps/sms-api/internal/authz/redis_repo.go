@@ -0,0 +1,103 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"encore.app/internal/helper"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	policyKey        = "authz:policy"
+	coursesKeyPrefix = "authz:courses:"
+)
+
+type redisRepository struct {
+	rdb *redis.Client
+}
+
+var (
+	_ Repository  = (*redisRepository)(nil)
+	_ CourseCache = (*redisRepository)(nil)
+)
+
+func NewRedisRepository(rdb *redis.Client) *redisRepository {
+	return &redisRepository{rdb: rdb}
+}
+
+func (r *redisRepository) Get(ctx context.Context) (Policy, error) {
+	val, err := r.rdb.Get(ctx, policyKey).Result()
+	if helper.IsKeyDoesNotExistErr(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("authz: get: %w", err)
+	}
+
+	policy := Policy{}
+	if err := json.Unmarshal([]byte(val), &policy); err != nil {
+		return nil, fmt.Errorf("authz: decode: %w", err)
+	}
+	return policy, nil
+}
+
+func (r *redisRepository) Set(ctx context.Context, policy Policy) error {
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("authz: encode: %w", err)
+	}
+	if err := r.rdb.Set(ctx, policyKey, b, 0).Err(); err != nil {
+		return fmt.Errorf("authz: set: %w", err)
+	}
+	return nil
+}
+
+func (r *redisRepository) Delete(ctx context.Context) error {
+	if err := r.rdb.Del(ctx, policyKey).Err(); err != nil {
+		return fmt.Errorf("authz: delete: %w", err)
+	}
+	return nil
+}
+
+func coursesKey(userID int64) string {
+	return coursesKeyPrefix + strconv.FormatInt(userID, 10)
+}
+
+func (r *redisRepository) GetCourses(ctx context.Context, userID int64) ([]int64, error) {
+	val, err := r.rdb.Get(ctx, coursesKey(userID)).Result()
+	if helper.IsKeyDoesNotExistErr(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("authz: get courses: %w", err)
+	}
+
+	courseIDs := []int64{}
+	if err := json.Unmarshal([]byte(val), &courseIDs); err != nil {
+		return nil, fmt.Errorf("authz: decode courses: %w", err)
+	}
+	return courseIDs, nil
+}
+
+func (r *redisRepository) SetCourses(
+	ctx context.Context,
+	userID int64,
+	courseIDs []int64,
+	ttl time.Duration,
+) error {
+	if courseIDs == nil {
+		courseIDs = []int64{}
+	}
+	b, err := json.Marshal(courseIDs)
+	if err != nil {
+		return fmt.Errorf("authz: encode courses: %w", err)
+	}
+	if err := r.rdb.Set(ctx, coursesKey(userID), b, ttl).Err(); err != nil {
+		return fmt.Errorf("authz: set courses: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package authz
+
+import (
+	"context"
+	"time"
+)
+
+// Repository stores the policy configured by admins.
+type Repository interface {
+	// Get returns the configured policy, or nil when the default applies.
+	Get(ctx context.Context) (Policy, error)
+	Set(ctx context.Context, policy Policy) error
+	Delete(ctx context.Context) error
+}
+
+// CourseCache remembers which courses a user teaches, so that course access
+// checks do not ask Moodle on every request.
+type CourseCache interface {
+	// GetCourses returns the cached course IDs of a user, or nil when none
+	// are cached.
+	GetCourses(ctx context.Context, userID int64) ([]int64, error)
+	SetCourses(ctx context.Context, userID int64, courseIDs []int64, ttl time.Duration) error
+}
@@ -0,0 +1,123 @@
+// Package breaker provides a circuit breaker that fails calls fast while a
+// dependency is down, instead of letting every request wait for its timeout.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Allow while the breaker is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a breaker.
+type State int
+
+const (
+	// Closed lets every call through.
+	Closed State = iota
+	// Open rejects every call until the cooldown has passed.
+	Open
+	// HalfOpen lets a single probe call through to test the dependency.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// Breaker opens after Threshold consecutive failures. After Cooldown it lets
+// one probe through: a success closes it again, a failure reopens it.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+}
+
+// New returns a closed breaker. A threshold below 1 is treated as 1.
+func New(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// State returns the current state.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow reports whether a call may be made, returning ErrOpen if not. Every
+// allowed call must be followed by Success, Failure or Release.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrOpen
+		}
+		b.state = HalfOpen
+		return nil
+	case HalfOpen:
+		// The probe is still running.
+		return ErrOpen
+	default:
+		return nil
+	}
+}
+
+// Success records a successful call. A successful probe closes the breaker;
+// a slow call that started before the breaker opened leaves it open.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case HalfOpen:
+		b.state = Closed
+		b.failures = 0
+	case Closed:
+		b.failures = 0
+	}
+}
+
+// Release records a call whose outcome says nothing about the dependency,
+// such as one cancelled by its caller. If it was the probe, the next call
+// probes instead.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == HalfOpen {
+		b.state = Open
+		b.openedAt = b.now().Add(-b.cooldown)
+	}
+}
+
+// Failure records a failed call. It opens the breaker once Threshold calls in
+// a row have failed, or at once when the failed call was the probe.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.threshold {
+		b.state = Open
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,76 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := New(3, time.Minute)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("call %d rejected while closed: %v", i, err)
+		}
+		b.Failure()
+	}
+	b.Success()
+	if b.failures != 0 {
+		t.Fatal("success did not reset the failure count")
+	}
+
+	for i := 0; i < 3; i++ {
+		_ = b.Allow()
+		b.Failure()
+	}
+	if b.State() != Open {
+		t.Fatalf("state = %s after 3 failures, want open", b.State())
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow() = %v while open, want ErrOpen", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe rejected after cooldown: %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatal("second call let through while probing")
+	}
+	b.Failure()
+	if b.State() != Open {
+		t.Fatalf("state = %s after failed probe, want open", b.State())
+	}
+
+	now = now.Add(time.Minute)
+	_ = b.Allow()
+	b.Release()
+	if err := b.Allow(); err != nil {
+		t.Fatalf("no new probe after the last one was released: %v", err)
+	}
+	b.Success()
+	if b.State() != Closed {
+		t.Fatalf("state = %s after successful probe, want closed", b.State())
+	}
+}
+
+func TestLateSuccessKeepsBreakerOpen(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := New(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	// A slow call starts, then another fails and opens the breaker.
+	_ = b.Allow()
+	_ = b.Allow()
+	b.Failure()
+	b.Success()
+	if b.State() != Open {
+		t.Fatalf("state = %s after a call from before the opening succeeded, want open", b.State())
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow() = %v before the cooldown, want ErrOpen", err)
+	}
+}
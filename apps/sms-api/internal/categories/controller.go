@@ -24,7 +24,7 @@ func (c *CategoryController) GetUserCategories(
 	mdlApiReq := &mdlapi.GetCategoriesRequest{UserID: int(req.UserId)}
 	mdlApiResp, err := c.useCase.GetCategories(ctx, mdlApiReq)
 	if err != nil {
-		return nil, err
+		return nil, mdlapi.ToAPIError(err)
 	}
 
 	data := make([]entities.Category, len(mdlApiResp.Categories))
@@ -32,7 +32,7 @@ func (c *CategoryController) GetUserCategories(
 		data[i] = *cat.ToAppCategory()
 	}
 
-	return &entities.GetUsersCategoriesResponse{Data: data}, nil
+	return &entities.GetUsersCategoriesResponse{Data: data, Source: string(mdlApiResp.Source)}, nil
 }
 
 // GetAllCategories returns all visible Moodle categories (admin / manager).
@@ -41,7 +41,7 @@ func (c *CategoryController) GetAllCategories(
 ) (*entities.GetUsersCategoriesResponse, error) {
 	mdlApiResp, err := c.useCase.GetAllCategories(ctx)
 	if err != nil {
-		return nil, err
+		return nil, mdlapi.ToAPIError(err)
 	}
 
 	data := make([]entities.Category, len(mdlApiResp.Categories))
@@ -49,7 +49,7 @@ func (c *CategoryController) GetAllCategories(
 		data[i] = *cat.ToAppCategory()
 	}
 
-	return &entities.GetUsersCategoriesResponse{Data: data}, nil
+	return &entities.GetUsersCategoriesResponse{Data: data, Source: string(mdlApiResp.Source)}, nil
 }
 
 // GetCategoryCoursesForAdmin returns all courses in a category (admin / manager).
@@ -60,7 +60,7 @@ func (c *CategoryController) GetCategoryCoursesForAdmin(
 	req := &mdlapi.GetCategoryCoursesRequest{CategoryID: int(categoryID)}
 	resp, err := c.useCase.GetAllCategoryCoursesForAdmin(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, mdlapi.ToAPIError(err)
 	}
 
 	data := make([]entities.Course, len(resp.Courses))
@@ -68,5 +68,5 @@ func (c *CategoryController) GetCategoryCoursesForAdmin(
 		data[i] = *c.ToAppCourse()
 	}
 
-	return &entities.GetUsersCoursesResponse{Data: data}, nil
+	return &entities.GetUsersCoursesResponse{Data: data, Source: string(resp.Source)}, nil
 }
@@ -0,0 +1,362 @@
+package categories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"encore.app/internal/courses"
+	"encore.app/internal/entities"
+	"encore.app/internal/logger"
+	"encore.app/internal/mdlapi"
+	"encore.app/internal/users"
+	"encore.dev/beta/errs"
+)
+
+var _ mdlapi.LocalTeacherProvider = (*DBTeacherProvider)(nil)
+
+// DBTeacherProvider reads categories and courses from Moodle's database
+// instead of its web services. It follows the rules of the teachercourses
+// plugin:
+//   - a teacher's categories are those of the courses where they hold a
+//     teacher or non-editing teacher role, plus all their ancestors;
+//   - a teacher's courses are those where they also have an active
+//     enrolment;
+//   - admins see every visible category and every course of a category.
+type DBTeacherProvider struct {
+	categoryRepo Repository
+	courseRepo   courses.Repository
+	userRepo     users.Repository
+}
+
+func NewDBTeacherProvider(
+	categoryRepo Repository,
+	courseRepo courses.Repository,
+	userRepo users.Repository,
+) *DBTeacherProvider {
+	return &DBTeacherProvider{categoryRepo: categoryRepo, courseRepo: courseRepo, userRepo: userRepo}
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+func (p *DBTeacherProvider) findUser(ctx context.Context, userID int) (*entities.MoodleUser, error) {
+	// Moodle falls back to the calling user; the database has none.
+	if userID == 0 {
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: "userid is required"}
+	}
+
+	user := &entities.MoodleUser{ID: int64(userID)}
+	if err := p.userRepo.FindOne(ctx, user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{Code: errs.NotFound, Message: fmt.Sprintf("user %d not found", userID)}
+		}
+		logger.ErrorContext(ctx, "DBTeacherProvider.findUser error", "err", err, "userId", userID)
+		return nil, err
+	}
+	return user, nil
+}
+
+// findCategory fails with NotFound unless the category exists.
+func (p *DBTeacherProvider) findCategory(ctx context.Context, categoryID int) (*Record, error) {
+	records, err := p.categoryRepo.FindByIDs(ctx, []int64{int64(categoryID)})
+	if err != nil {
+		logger.ErrorContext(ctx, "DBTeacherProvider.findCategory error", "err", err, "categoryId", categoryID)
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, &errs.Error{Code: errs.NotFound, Message: fmt.Sprintf("category %d not found", categoryID)}
+	}
+	return &records[0], nil
+}
+
+// ancestors returns the ids of a category's ancestors, read from its path.
+func ancestors(rec *Record) []int64 {
+	var ids []int64
+	for _, part := range strings.Split(strings.Trim(rec.Path, "/"), "/") {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err == nil && id != rec.ID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func toCategory(rec *Record, parentName string, courseIDs []int, courseCount int) mdlapi.Category {
+	return mdlapi.Category{
+		ID:          int(rec.ID),
+		Name:        rec.Name,
+		Description: htmlTag.ReplaceAllString(rec.Description.String, ""),
+		Parent:      int(rec.Parent),
+		ParentName:  parentName,
+		Path:        rec.Path,
+		Depth:       rec.Depth,
+		Visible:     rec.Visible,
+		SortOrder:   rec.SortOrder,
+		CourseCount: courseCount,
+		CourseIds:   courseIDs,
+		IdNumber:    rec.IDNumber.String,
+	}
+}
+
+// GetCategories returns the categories a user teaches in, with their
+// ancestors so that the tree can be drawn. Only the former list courses.
+func (p *DBTeacherProvider) GetCategories(
+	ctx context.Context,
+	req *mdlapi.GetCategoriesRequest,
+) (*mdlapi.GetCategoriesResponse, error) {
+	user, err := p.findUser(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &mdlapi.GetCategoriesResponse{
+		Userid:     int(user.ID),
+		Username:   user.Username,
+		Firstname:  user.FirstName,
+		Lastname:   user.LastName,
+		Email:      user.Email,
+		Categories: []mdlapi.Category{},
+		Source:     mdlapi.SourceDatabase,
+	}
+
+	taught, err := p.courseRepo.FindTaught(ctx, &courses.TaughtParams{UserID: user.ID})
+	if err != nil {
+		logger.ErrorContext(ctx, "DBTeacherProvider.GetCategories FindTaught error", "err", err, "userId", user.ID)
+		return nil, err
+	}
+	if len(taught) == 0 {
+		return resp, nil
+	}
+
+	taughtIn := map[int64][]int{}
+	var directIDs []int64
+	for _, c := range taught {
+		if _, ok := taughtIn[c.Category]; !ok {
+			directIDs = append(directIDs, c.Category)
+		}
+		taughtIn[c.Category] = append(taughtIn[c.Category], int(c.ID))
+	}
+
+	direct, err := p.categoryRepo.FindByIDs(ctx, directIDs)
+	if err != nil {
+		logger.ErrorContext(ctx, "DBTeacherProvider.GetCategories FindByIDs error", "err", err, "userId", user.ID)
+		return nil, err
+	}
+	ids := slices.Clone(directIDs)
+	for i := range direct {
+		for _, id := range ancestors(&direct[i]) {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	records, err := p.categoryRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		logger.ErrorContext(ctx, "DBTeacherProvider.GetCategories FindByIDs error", "err", err, "userId", user.ID)
+		return nil, err
+	}
+
+	for i := range records {
+		courseIDs := taughtIn[records[i].ID]
+		if courseIDs == nil {
+			courseIDs = []int{}
+		}
+		// Like the plugin, parent names are left out for teachers.
+		resp.Categories = append(resp.Categories, toCategory(&records[i], "", courseIDs, len(courseIDs)))
+	}
+	resp.Totalcategories = len(resp.Categories)
+	return resp, nil
+}
+
+// GetAllCategories returns every visible category.
+func (p *DBTeacherProvider) GetAllCategories(
+	ctx context.Context,
+	req *mdlapi.GetAllCategoriesRequest,
+) (*mdlapi.GetCategoriesResponse, error) {
+	records, err := p.categoryRepo.FindVisible(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "DBTeacherProvider.GetAllCategories FindVisible error", "err", err)
+		return nil, err
+	}
+
+	names := make(map[int64]string, len(records))
+	for _, rec := range records {
+		names[rec.ID] = rec.Name
+	}
+	// Parents may be hidden, and so not among the records.
+	var hiddenParents []int64
+	for _, rec := range records {
+		if _, ok := names[rec.Parent]; rec.Parent != 0 && !ok && !slices.Contains(hiddenParents, rec.Parent) {
+			hiddenParents = append(hiddenParents, rec.Parent)
+		}
+	}
+	parents, err := p.categoryRepo.FindByIDs(ctx, hiddenParents)
+	if err != nil {
+		logger.ErrorContext(ctx, "DBTeacherProvider.GetAllCategories FindByIDs error", "err", err)
+		return nil, err
+	}
+	for _, rec := range parents {
+		names[rec.ID] = rec.Name
+	}
+
+	resp := &mdlapi.GetCategoriesResponse{
+		Categories: make([]mdlapi.Category, len(records)),
+		Source:     mdlapi.SourceDatabase,
+	}
+	for i := range records {
+		resp.Categories[i] = toCategory(&records[i], names[records[i].Parent], []int{}, records[i].CourseCount)
+	}
+	resp.Totalcategories = len(resp.Categories)
+	return resp, nil
+}
+
+// GetCategoryCourses returns the courses a user teaches with an active
+// enrolment, in one category or, for CategoryID 0, in all.
+func (p *DBTeacherProvider) GetCategoryCourses(
+	ctx context.Context,
+	req *mdlapi.GetCategoryCoursesRequest,
+) (*mdlapi.GetCategoryCoursesResponse, error) {
+	user, err := p.findUser(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if req.CategoryID != 0 {
+		if _, err := p.findCategory(ctx, req.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	taught, err := p.courseRepo.FindTaught(ctx, &courses.TaughtParams{
+		UserID:          user.ID,
+		CategoryID:      int64(req.CategoryID),
+		ActiveEnrolment: true,
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "DBTeacherProvider.GetCategoryCourses FindTaught error",
+			"err", err, "userId", user.ID, "categoryId", req.CategoryID)
+		return nil, err
+	}
+
+	resp, err := p.coursesResponse(ctx, taught)
+	if err != nil {
+		return nil, err
+	}
+	resp.Userid = int(user.ID)
+	resp.Username = user.Username
+	resp.Firstname = user.FirstName
+	resp.Lastname = user.LastName
+	resp.Email = user.Email
+	return resp, nil
+}
+
+// GetAllCategoryCoursesForAdmin returns every course of a category.
+func (p *DBTeacherProvider) GetAllCategoryCoursesForAdmin(
+	ctx context.Context,
+	req *mdlapi.GetCategoryCoursesRequest,
+) (*mdlapi.GetCategoryCoursesResponse, error) {
+	if _, err := p.findCategory(ctx, req.CategoryID); err != nil {
+		return nil, err
+	}
+
+	records, err := p.courseRepo.FindInCategory(ctx, int64(req.CategoryID))
+	if err != nil {
+		logger.ErrorContext(ctx, "DBTeacherProvider.GetAllCategoryCoursesForAdmin FindInCategory error",
+			"err", err, "categoryId", req.CategoryID)
+		return nil, err
+	}
+	return p.coursesResponse(ctx, records)
+}
+
+// coursesResponse adds the category and custom fields of each course.
+func (p *DBTeacherProvider) coursesResponse(
+	ctx context.Context,
+	records []courses.Record,
+) (*mdlapi.GetCategoryCoursesResponse, error) {
+	courseIDs := make([]int64, len(records))
+	var categoryIDs []int64
+	for i, c := range records {
+		courseIDs[i] = c.ID
+		if !slices.Contains(categoryIDs, c.Category) {
+			categoryIDs = append(categoryIDs, c.Category)
+		}
+	}
+
+	cats, err := p.categoryRepo.FindByIDs(ctx, categoryIDs)
+	if err != nil {
+		logger.ErrorContext(ctx, "DBTeacherProvider.coursesResponse FindByIDs error", "err", err)
+		return nil, err
+	}
+	catByID := make(map[int64]*Record, len(cats))
+	for i := range cats {
+		catByID[cats[i].ID] = &cats[i]
+	}
+
+	fields, err := p.courseRepo.FindFields(ctx, courseIDs)
+	if err != nil {
+		logger.ErrorContext(ctx, "DBTeacherProvider.coursesResponse FindFields error", "err", err)
+		return nil, err
+	}
+	metadata := map[int64][]mdlapi.CourseMetadata{}
+	for _, f := range fields {
+		if value, ok := fieldValue(&f); ok {
+			metadata[f.CourseID] = append(metadata[f.CourseID], mdlapi.CourseMetadata{Name: f.Shortname, Value: value})
+		}
+	}
+
+	resp := &mdlapi.GetCategoryCoursesResponse{
+		Courses: make([]mdlapi.CategoryCourse, len(records)),
+		Source:  mdlapi.SourceDatabase,
+	}
+	for i, c := range records {
+		course := mdlapi.CategoryCourse{
+			ID:              int(c.ID),
+			Fullname:        c.Fullname,
+			Shortname:       c.Shortname,
+			Idnumber:        c.IDNumber,
+			Summary:         htmlTag.ReplaceAllString(c.Summary.String, ""),
+			Visible:         c.Visible,
+			Startdate:       int(c.StartDate),
+			Enddate:         int(c.EndDate),
+			Categoryid:      int(c.Category),
+			Categoryvisible: 1,
+			Metadata:        metadata[c.ID],
+		}
+		if course.Metadata == nil {
+			course.Metadata = []mdlapi.CourseMetadata{}
+		}
+		if cat, ok := catByID[c.Category]; ok {
+			course.Categoryname = cat.Name
+			course.Categorypath = cat.Path
+			course.Categoryvisible = cat.Visible
+		}
+		resp.Courses[i] = course
+	}
+	resp.Totalcourses = len(resp.Courses)
+	return resp, nil
+}
+
+// fieldValue returns the value of a numeric custom field such as "credit".
+// CourseMetadata holds an int, so fractions are truncated and other values
+// left out.
+func fieldValue(f *courses.Field) (int, bool) {
+	var raw string
+	switch f.Type {
+	case "select", "checkbox", "date":
+		return int(f.IntValue.Int64), f.IntValue.Valid
+	case "number":
+		return int(f.DecValue.Float64), f.DecValue.Valid
+	case "text":
+		raw = f.CharValue.String
+	default:
+		raw = f.Value.String
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
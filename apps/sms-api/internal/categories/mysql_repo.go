@@ -55,3 +55,45 @@ func (r *repository) Find(
 
 	return &entities.GetUsersCategoriesResponse{Data: resp}, nil
 }
+
+func (r *repository) selectRecords(ctx context.Context) *dbx.SelectQuery {
+	return r.db.WithContext(ctx).
+		Select(
+			"cat.id AS id",
+			"cat.name AS name",
+			"cat.idnumber AS idnumber",
+			"cat.description AS description",
+			"cat.parent AS parent",
+			"cat.path AS path",
+			"cat.depth AS depth",
+			"cat.visible AS visible",
+			"cat.sortorder AS sortorder",
+			"(SELECT COUNT(*) FROM mdl_course c WHERE c.category = cat.id AND c.id != 1) AS coursecount",
+		).
+		From("mdl_course_categories cat").
+		OrderBy("cat.name")
+}
+
+func (r *repository) FindByIDs(ctx context.Context, ids []int64) ([]Record, error) {
+	resp := make([]Record, 0)
+	if len(ids) == 0 {
+		return resp, nil
+	}
+
+	in := make([]interface{}, len(ids))
+	for i, id := range ids {
+		in[i] = id
+	}
+	if err := r.selectRecords(ctx).Where(dbx.In("cat.id", in...)).All(&resp); err != nil {
+		return nil, fmt.Errorf("categories: find by ids: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *repository) FindVisible(ctx context.Context) ([]Record, error) {
+	resp := make([]Record, 0)
+	if err := r.selectRecords(ctx).Where(dbx.HashExp{"cat.visible": 1}).All(&resp); err != nil {
+		return nil, fmt.Errorf("categories: find visible: %w", err)
+	}
+	return resp, nil
+}
@@ -2,6 +2,7 @@ package categories
 
 import (
 	"context"
+	"database/sql"
 
 	"encore.app/internal/entities"
 )
@@ -11,4 +12,26 @@ type Repository interface {
 		context.Context,
 		*entities.GetUsersCategoriesRequest,
 	) (*entities.GetUsersCategoriesResponse, error)
+	// FindByIDs returns the categories with the given ids, hidden ones
+	// included, ordered by name.
+	FindByIDs(ctx context.Context, ids []int64) ([]Record, error)
+	// FindVisible returns every visible category, ordered by name.
+	FindVisible(context.Context) ([]Record, error)
+}
+
+// Record is a row of mdl_course_categories.
+type Record struct {
+	ID          int64          `db:"id"`
+	Name        string         `db:"name"`
+	IDNumber    sql.NullString `db:"idnumber"`
+	Description sql.NullString `db:"description"`
+	Parent      int64          `db:"parent"`
+	// Path lists the ids of the category's ancestors and its own, e.g.
+	// "/1/4/9".
+	Path      string `db:"path"`
+	Depth     int    `db:"depth"`
+	Visible   int    `db:"visible"`
+	SortOrder int    `db:"sortorder"`
+	// CourseCount is the number of courses in the category.
+	CourseCount int `db:"coursecount"`
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"log/slog"
+	"os"
 	"strings"
 
 	"encore.app/internal/logger"
@@ -36,6 +37,8 @@ type Config struct {
 	MoodleApiConfig
 	OtelConfig
 	AuditConfig
+	SchoolConfig
+	WebhookConfig
 	ClientOriginUrl      string     `env:"CLIENT_ORIGIN_URL"      env-default:"http://localhost:3000" json:"client_origin_url"`
 	ClientOauth2Callback string     `env:"CLIENT_OAUTH2_CALLBACK" env-default:"oauth2/callback"       json:"client_oauth2_callback"`
 	Env                  string     `env:"ENV"                    env-default:"dev"                   json:"env"`
@@ -68,6 +71,8 @@ func (c *Config) LogValue() slog.Value {
 		slog.Any("cache_config", &c.CacheConfig),
 		slog.Any("db_config", &c.DatabaseConfig),
 		slog.Any("audit_config", &c.AuditConfig),
+		slog.Any("school_config", &c.SchoolConfig),
+		slog.Any("webhook_config", &c.WebhookConfig),
 	)
 }
 
@@ -94,6 +99,9 @@ func init() {
 	if err != nil {
 		logger.Error("Failed to load config: ", err)
 	}
+	if err := config.Validate(); err != nil {
+		log.Fatal("Invalid config: ", err)
+	}
 
 	logger.SetGlobalLogger(
 		logger.Default,
@@ -103,6 +111,18 @@ func init() {
 	logger.Info("Init config success", "config", config)
 }
 
+// Validate rejects invalid settings, and those that are only acceptable in
+// development.
+func (c *Config) Validate() error {
+	if err := c.MoodleApiConfig.validate(); err != nil {
+		return err
+	}
+	if c.Env != PROD {
+		return nil
+	}
+	return c.AuthnConfig.validateProd()
+}
+
 // GetConfig returns the singleton instance of Config
 func GetConfig() *Config {
 	return config
@@ -114,7 +134,8 @@ func loadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	if config.Env == "dev" {
+	// Without a .env, e.g. in package tests, the defaults apply.
+	if _, err := os.Stat(".env"); config.Env == "dev" && err == nil {
 		if err := cleanenv.ReadConfig(".env", config); err != nil {
 			return nil, err
 		}
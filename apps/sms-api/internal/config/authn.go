@@ -1,21 +1,58 @@
 package config
 
-import "log/slog"
+import (
+	"errors"
+	"log/slog"
+)
+
+// DefaultStateSecret is the development value of OAUTH2_STATE_SECRET. It is
+// refused when ENV=prod.
+const DefaultStateSecret = "state-secret"
 
 type AuthnConfig struct {
-	JWTRefreshToken    string `env:"JWT_REFRESH_SECRET"   env-default:"refresh-secret"`
-	JWTSecret          string `env:"JWT_SECRET"           env-default:"token-secret"`
+	// JWTKeysDir holds the PEM keys of JWT signing, one file per key named
+	// "<kid>.pem". Private keys (RSA or Ed25519, PKCS#8) sign and verify;
+	// public keys only verify, which keeps tokens of a retired key valid until
+	// they expire. When empty outside prod, an ephemeral key is generated.
+	JWTKeysDir string `env:"JWT_KEYS_DIR"`
+	// JWTSigningKID selects the key that signs new tokens. Defaults to the
+	// last private key by name, so naming keys by date rotates on deploy.
+	JWTSigningKID      string `env:"JWT_SIGNING_KID"`
 	TokenExpire        int    `env:"TOKEN_EXPIRE"         env-default:"30"`
 	RefreshTokenExpire int    `env:"REFRESH_TOKEN_EXPIRE" env-default:"84"`
+	// StateSecret signs the OAuth2 state of the Moodle login flow.
+	StateSecret string `env:"OAUTH2_STATE_SECRET" env-default:"state-secret"`
+	// UserInfoCacheTTL is how many seconds a user's role and status looked up
+	// on token refresh are reused before asking Moodle again.
+	UserInfoCacheTTL int `env:"USER_INFO_CACHE_TTL" env-default:"60"`
+	// ImpersonationTTL is how many minutes an admin may view the application
+	// as another user before having to impersonate them again.
+	ImpersonationTTL int `env:"IMPERSONATION_TTL" env-default:"15"`
 }
 
 var _ slog.LogValuer = (*AuthnConfig)(nil)
 
 func (c *AuthnConfig) LogValue() slog.Value {
 	return slog.GroupValue(
-		slog.String("JWT_REFRESH_SECRET", generateMaskedString(c.JWTRefreshToken)),
-		slog.String("JWT_SECRET", generateMaskedString(c.JWTSecret)),
+		slog.String("JWT_KEYS_DIR", c.JWTKeysDir),
+		slog.String("JWT_SIGNING_KID", c.JWTSigningKID),
 		slog.Int("TOKEN_EXPIRE", c.TokenExpire),
 		slog.Int("REFRESH_TOKEN_EXPIRE", c.RefreshTokenExpire),
+		slog.String("OAUTH2_STATE_SECRET", generateMaskedString(c.StateSecret)),
+		slog.Int("USER_INFO_CACHE_TTL", c.UserInfoCacheTTL),
+		slog.Int("IMPERSONATION_TTL", c.ImpersonationTTL),
 	)
 }
+
+// validateProd refuses development defaults that would let anyone forge
+// tokens or login states.
+func (c *AuthnConfig) validateProd() error {
+	var errs []error
+	if c.JWTKeysDir == "" {
+		errs = append(errs, errors.New("JWT_KEYS_DIR is required"))
+	}
+	if c.StateSecret == "" || c.StateSecret == DefaultStateSecret {
+		errs = append(errs, errors.New("OAUTH2_STATE_SECRET must be set to a non-default value"))
+	}
+	return errors.Join(errs...)
+}
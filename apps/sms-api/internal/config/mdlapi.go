@@ -1,10 +1,33 @@
 package config
 
-import "log/slog"
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Sources of course and category reads, see MoodleApiConfig.CourseSource.
+const (
+	CourseSourceWebService = "webservice"
+	CourseSourceDB         = "db"
+	CourseSourceFallback   = "fallback"
+)
 
 type MoodleApiConfig struct {
 	Url      string `env:"MOODLE_URL"       env-default:"http://localhost:8083"`
 	ApiToken string `env:"MOODLE_API_TOKEN" env-default:"4734d29fb1f9ca155217041ca581db0d"`
+	// Timeout is the default number of seconds a Moodle call may take. Slow
+	// functions such as exports are given more.
+	Timeout int `env:"MOODLE_TIMEOUT" env-default:"10"`
+	// MaxRetries is how often a failed read is retried. Writes never are.
+	MaxRetries int `env:"MOODLE_MAX_RETRIES" env-default:"2"`
+	// After BreakerThreshold failed calls in a row, calls fail at once for
+	// BreakerCooldown seconds before Moodle is tried again.
+	BreakerThreshold int `env:"MOODLE_BREAKER_THRESHOLD" env-default:"5"`
+	BreakerCooldown  int `env:"MOODLE_BREAKER_COOLDOWN"  env-default:"30"`
+	// CourseSource selects where course and category lists are read from:
+	// the Moodle web services, Moodle's database, or the web services with
+	// the database as fallback while Moodle is unreachable.
+	CourseSource string `env:"MOODLE_COURSE_SOURCE" env-default:"webservice"`
 }
 
 var _ slog.LogValuer = (*MoodleApiConfig)(nil)
@@ -13,5 +36,20 @@ func (c *MoodleApiConfig) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.String("MOODLE_URL", c.Url),
 		slog.String("MOODLE_API_TOKEN", generateMaskedString(c.ApiToken)),
+		slog.Int("MOODLE_TIMEOUT", c.Timeout),
+		slog.Int("MOODLE_MAX_RETRIES", c.MaxRetries),
+		slog.Int("MOODLE_BREAKER_THRESHOLD", c.BreakerThreshold),
+		slog.Int("MOODLE_BREAKER_COOLDOWN", c.BreakerCooldown),
+		slog.String("MOODLE_COURSE_SOURCE", c.CourseSource),
 	)
 }
+
+func (c *MoodleApiConfig) validate() error {
+	switch c.CourseSource {
+	case CourseSourceWebService, CourseSourceDB, CourseSourceFallback:
+		return nil
+	default:
+		return fmt.Errorf("MOODLE_COURSE_SOURCE must be %q, %q or %q, got %q",
+			CourseSourceWebService, CourseSourceDB, CourseSourceFallback, c.CourseSource)
+	}
+}
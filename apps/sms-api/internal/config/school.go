@@ -0,0 +1,28 @@
+package config
+
+import "log/slog"
+
+// SchoolConfig holds the school names printed in the header of generated
+// documents such as student transcripts.
+type SchoolConfig struct {
+	// Authority is the governing body printed above the school name.
+	Authority string `env:"SCHOOL_AUTHORITY" env-default:"TỔNG CỤC HẬU CẦN"`
+
+	// Name is the full school name.
+	Name string `env:"SCHOOL_NAME"      env-default:"TRƯỜNG CAO ĐẲNG HẬU CẦN 2"`
+
+	// Country and Motto form the national heading printed on the right.
+	Country string `env:"SCHOOL_COUNTRY"   env-default:"CỘNG HÒA XÃ HỘI CHỦ NGHĨA VIỆT NAM"`
+	Motto   string `env:"SCHOOL_MOTTO"     env-default:"Độc lập - Tự do - Hạnh phúc"`
+}
+
+var _ slog.LogValuer = (*SchoolConfig)(nil)
+
+func (c *SchoolConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("SCHOOL_AUTHORITY", c.Authority),
+		slog.String("SCHOOL_NAME", c.Name),
+		slog.String("SCHOOL_COUNTRY", c.Country),
+		slog.String("SCHOOL_MOTTO", c.Motto),
+	)
+}
@@ -0,0 +1,23 @@
+package config
+
+import "log/slog"
+
+// WebhookConfig holds settings for the webhook Moodle reports its events to.
+type WebhookConfig struct {
+	// Secret is shared with Moodle's local_smswebhook plugin, which signs
+	// every event with it. Without a secret every event is refused.
+	Secret string `env:"MOODLE_WEBHOOK_SECRET"`
+
+	// Tolerance is how many seconds an event's signature stays valid, so
+	// that a captured request cannot be replayed later. Default: 300.
+	Tolerance int `env:"MOODLE_WEBHOOK_TOLERANCE" env-default:"300"`
+}
+
+var _ slog.LogValuer = (*WebhookConfig)(nil)
+
+func (c *WebhookConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("MOODLE_WEBHOOK_SECRET", generateMaskedString(c.Secret)),
+		slog.Int("MOODLE_WEBHOOK_TOLERANCE", c.Tolerance),
+	)
+}
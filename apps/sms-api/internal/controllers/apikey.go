@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"context"
+
+	"encore.app/internal/apikeys"
+	"encore.app/internal/entities"
+	"encore.app/internal/usecases"
+)
+
+type APIKeyController struct {
+	useCase *usecases.APIKeyUseCase
+}
+
+func NewAPIKeyController(useCase *usecases.APIKeyUseCase) *APIKeyController {
+	return &APIKeyController{useCase: useCase}
+}
+
+func (c *APIKeyController) Create(
+	ctx context.Context,
+	actorID int64,
+	params *usecases.CreateKeyParams,
+) (*apikeys.Key, string, error) {
+	return c.useCase.Create(ctx, actorID, params)
+}
+
+func (c *APIKeyController) List(ctx context.Context) ([]apikeys.Key, error) {
+	return c.useCase.List(ctx)
+}
+
+func (c *APIKeyController) Rotate(ctx context.Context, id int64) (*apikeys.Key, string, error) {
+	return c.useCase.Rotate(ctx, id)
+}
+
+func (c *APIKeyController) Revoke(ctx context.Context, actorID, id int64) (*apikeys.Key, error) {
+	return c.useCase.Revoke(ctx, actorID, id)
+}
+
+func (c *APIKeyController) Authenticate(
+	ctx context.Context,
+	secret string,
+) (*entities.TokenPayload, error) {
+	return c.useCase.Authenticate(ctx, secret)
+}
@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"context"
+
+	"encore.app/internal/authz"
+	"encore.app/internal/usecases"
+)
+
+type AuthzController struct {
+	useCase *usecases.AuthzUseCase
+}
+
+func NewAuthzController(useCase *usecases.AuthzUseCase) *AuthzController {
+	return &AuthzController{useCase: useCase}
+}
+
+func (c *AuthzController) GetPolicy(ctx context.Context) (authz.Policy, error) {
+	return c.useCase.GetPolicy(ctx)
+}
+
+func (c *AuthzController) SetPolicy(ctx context.Context, policy authz.Policy) error {
+	return c.useCase.SetPolicy(ctx, policy)
+}
+
+func (c *AuthzController) ResetPolicy(ctx context.Context) error {
+	return c.useCase.ResetPolicy(ctx)
+}
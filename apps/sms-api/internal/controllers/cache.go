@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"context"
+
+	"encore.app/internal/usecases"
+)
+
+type CacheController struct {
+	useCase *usecases.CacheUseCase
+}
+
+func NewCacheController(useCase *usecases.CacheUseCase) *CacheController {
+	return &CacheController{useCase: useCase}
+}
+
+func (c *CacheController) Flush(ctx context.Context, courseID, categoryID *int64) (int, error) {
+	return c.useCase.Flush(ctx, courseID, categoryID)
+}
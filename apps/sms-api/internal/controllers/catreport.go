@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"context"
+
+	"encore.app/internal/entities"
+	"encore.app/internal/mdlapi"
+	"encore.app/internal/usecases"
+)
+
+type CategoryReportController struct {
+	useCase *usecases.CategoryReportUseCase
+}
+
+func NewCategoryReportController(useCase *usecases.CategoryReportUseCase) *CategoryReportController {
+	return &CategoryReportController{useCase: useCase}
+}
+
+func (c *CategoryReportController) GetReport(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	categoryID int64,
+) (*entities.CategoryReport, error) {
+	return moodleResult(c.useCase.GetReport(ctx, actor, int(categoryID)))
+}
+
+func (c *CategoryReportController) ExportReport(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	categoryID int64,
+) (*mdlapi.ExportCourseGradesResponse, error) {
+	return moodleResult(c.useCase.ExportReport(ctx, actor, int(categoryID)))
+}
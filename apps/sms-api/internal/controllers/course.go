@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"encore.app/internal/entities"
+	"encore.app/internal/gradehistory"
 	"encore.app/internal/mdlapi"
 	"encore.app/internal/usecases"
 )
@@ -16,23 +17,49 @@ func NewCourseController(useCase *usecases.CourseUseCase) *CourseController {
 	return &CourseController{useCase: useCase}
 }
 
+func (c *CourseController) CheckCourseAccess(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	courseID int64,
+) error {
+	return mdlapi.ToAPIError(c.useCase.CheckCourseAccess(ctx, actor, courseID))
+}
+
 func (c *CourseController) GetUserCourses(
 	ctx context.Context,
 	req *entities.GetUsersCoursesParams,
 ) (*entities.GetUsersCoursesResponse, error) {
-	return c.useCase.GetUserCourses(ctx, req)
+	return moodleResult(c.useCase.GetUserCourses(ctx, req))
 }
 
 func (c *CourseController) GetCourseDetails(
 	ctx context.Context,
 	req *entities.FindOneCourseParams,
 ) (*mdlapi.GetCourseGradesResponse, error) {
-	return c.useCase.GetUserCourseDetails(ctx, req)
+	return moodleResult(c.useCase.GetUserCourseDetails(ctx, req))
 }
 
 func (c *CourseController) UpdateCourseGrades(
 	ctx context.Context,
+	actor *entities.TokenPayload,
 	req *mdlapi.UpdateGradesRequest,
 ) (mdlapi.UpdateGradesResponse, error) {
-	return c.useCase.UpdateCourseGrades(ctx, req)
+	return moodleResult(c.useCase.UpdateCourseGrades(ctx, actor, req))
+}
+
+func (c *CourseController) BatchUpdateCourseGrades(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	courseID int,
+	activities []mdlapi.UpdateGradesRequest,
+) (*entities.BatchUpdateGradesResponse, error) {
+	return moodleResult(c.useCase.BatchUpdateCourseGrades(ctx, actor, courseID, activities))
+}
+
+func (c *CourseController) GetGradeHistory(
+	ctx context.Context,
+	courseID int64,
+	req *gradehistory.ListRequest,
+) (*gradehistory.ListResponse, error) {
+	return c.useCase.GetGradeHistory(ctx, courseID, req)
 }
@@ -21,7 +21,7 @@ func (c *ExportController) GetCourseTemplates(
 	ctx context.Context,
 	courseID int,
 ) (*mdlapi.GetCourseTemplatesResponse, error) {
-	return c.useCase.GetCourseTemplates(ctx, courseID)
+	return moodleResult(c.useCase.GetCourseTemplates(ctx, courseID))
 }
 
 func (c *ExportController) ExportCourseGrades(
@@ -29,26 +29,34 @@ func (c *ExportController) ExportCourseGrades(
 	courseID int,
 	templateID string,
 ) (*mdlapi.ExportCourseGradesResponse, error) {
-	return c.useCase.ExportCourseGrades(ctx, courseID, templateID)
+	return moodleResult(c.useCase.ExportCourseGrades(ctx, courseID, templateID))
+}
+
+func (c *ExportController) PreviewTemplate(
+	ctx context.Context,
+	courseID int,
+	templateID, filename, filedata string,
+) (*mdlapi.ExportCourseGradesResponse, error) {
+	return moodleResult(c.useCase.PreviewTemplate(ctx, courseID, templateID, filename, filedata))
 }
 
 func (c *ExportController) GetAllTemplates(
 	ctx context.Context,
 	templateType string,
 ) (*mdlapi.GetCourseTemplatesResponse, error) {
-	return c.useCase.GetAllTemplates(ctx, templateType)
+	return moodleResult(c.useCase.GetAllTemplates(ctx, templateType))
 }
 
 func (c *ExportController) UploadTemplate(
 	ctx context.Context,
 	req *mdlapi.UploadTemplateRequest,
 ) (*mdlapi.UploadTemplateResponse, error) {
-	return c.useCase.UploadTemplate(ctx, req)
+	return moodleResult(c.useCase.UploadTemplate(ctx, req))
 }
 
 func (c *ExportController) DeleteTemplate(
 	ctx context.Context,
 	templateType, templateID string,
 ) (*mdlapi.DeleteTemplateResponse, error) {
-	return c.useCase.DeleteTemplate(ctx, templateType, templateID)
+	return moodleResult(c.useCase.DeleteTemplate(ctx, templateType, templateID))
 }
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+
+	"encore.app/internal/entities"
+	"encore.app/internal/exportjobs"
+	"encore.app/internal/mdlapi"
+	"encore.app/internal/usecases"
+)
+
+type ExportJobController struct {
+	useCase *usecases.ExportJobUseCase
+}
+
+func NewExportJobController(useCase *usecases.ExportJobUseCase) *ExportJobController {
+	return &ExportJobController{useCase: useCase}
+}
+
+func (c *ExportJobController) CreateJob(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	courseID, categoryID int64,
+	templateID string,
+) (*exportjobs.Job, error) {
+	return c.useCase.CreateJob(ctx, actor, courseID, categoryID, templateID)
+}
+
+func (c *ExportJobController) GetJob(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	id string,
+) (*exportjobs.Job, error) {
+	return c.useCase.GetJob(ctx, actor, id)
+}
+
+func (c *ExportJobController) DownloadJob(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	id string,
+) (*mdlapi.ExportCourseGradesResponse, error) {
+	return c.useCase.DownloadJob(ctx, actor, id)
+}
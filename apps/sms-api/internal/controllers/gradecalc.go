@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"context"
+
+	"encore.app/internal/gradecalc"
+	"encore.app/internal/usecases"
+)
+
+type GradeCalcController struct {
+	useCase *usecases.GradeCalcUseCase
+}
+
+func NewGradeCalcController(useCase *usecases.GradeCalcUseCase) *GradeCalcController {
+	return &GradeCalcController{useCase: useCase}
+}
+
+func (c *GradeCalcController) GetCoefficients(
+	ctx context.Context,
+	categoryID int64,
+) (*gradecalc.Coefficients, error) {
+	return c.useCase.GetCoefficients(ctx, categoryID)
+}
+
+func (c *GradeCalcController) SetCoefficients(
+	ctx context.Context,
+	categoryID int64,
+	coef *gradecalc.Coefficients,
+) error {
+	return c.useCase.SetCoefficients(ctx, categoryID, coef)
+}
+
+func (c *GradeCalcController) DeleteCoefficients(ctx context.Context, categoryID int64) error {
+	return c.useCase.DeleteCoefficients(ctx, categoryID)
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"context"
+
+	"encore.app/internal/entities"
+	"encore.app/internal/gradelocks"
+	"encore.app/internal/usecases"
+)
+
+type GradeLockController struct {
+	useCase *usecases.GradeLockUseCase
+}
+
+func NewGradeLockController(useCase *usecases.GradeLockUseCase) *GradeLockController {
+	return &GradeLockController{useCase: useCase}
+}
+
+func (c *GradeLockController) LockGrades(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	courseID int64,
+	examType, reason string,
+) (*gradelocks.Lock, error) {
+	return c.useCase.LockGrades(ctx, actor, courseID, examType, reason)
+}
+
+func (c *GradeLockController) UnlockGrades(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	courseID, lockID int64,
+	reason string,
+) (*gradelocks.Lock, error) {
+	return c.useCase.UnlockGrades(ctx, actor, courseID, lockID, reason)
+}
+
+func (c *GradeLockController) GetLocks(
+	ctx context.Context,
+	courseID int64,
+) (*gradelocks.ListResponse, error) {
+	return c.useCase.GetLocks(ctx, courseID)
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"context"
+
+	"encore.app/internal/mdlevents"
+	"encore.app/internal/usecases"
+)
+
+type MoodleEventController struct {
+	useCase *usecases.MoodleEventUseCase
+}
+
+func NewMoodleEventController(useCase *usecases.MoodleEventUseCase) *MoodleEventController {
+	return &MoodleEventController{useCase: useCase}
+}
+
+func (c *MoodleEventController) Accept(
+	ctx context.Context,
+	timestamp, signature string,
+	body []byte,
+	publisher mdlevents.Publisher,
+) (*mdlevents.Event, bool, error) {
+	return c.useCase.Accept(ctx, timestamp, signature, body, publisher)
+}
+
+func (c *MoodleEventController) Invalidate(ctx context.Context, event *mdlevents.Event) error {
+	return c.useCase.Invalidate(ctx, event)
+}
+
+func (c *MoodleEventController) RecordGrade(ctx context.Context, event *mdlevents.Event) error {
+	return c.useCase.RecordGrade(ctx, event)
+}
@@ -0,0 +1,9 @@
+package controllers
+
+import "encore.app/internal/mdlapi"
+
+// moodleResult passes a use case result through, turning Moodle failures into
+// API errors with a matching code.
+func moodleResult[T any](v T, err error) (T, error) {
+	return v, mdlapi.ToAPIError(err)
+}
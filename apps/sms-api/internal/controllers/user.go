@@ -19,5 +19,13 @@ func (c *UserController) GetUserGrades(
 	ctx context.Context,
 	req *mdlapi.GetUserGradesRequest,
 ) (*mdlapi.GetUserGradesResponse, error) {
-	return c.useCase.GetStudentGrades(ctx, req)
+	return moodleResult(c.useCase.GetStudentGrades(ctx, req))
+}
+
+func (c *UserController) GetTranscript(
+	ctx context.Context,
+	userID int64,
+	format string,
+) (*mdlapi.ExportCourseGradesResponse, error) {
+	return moodleResult(c.useCase.GetTranscript(ctx, int(userID), format))
 }
@@ -3,6 +3,7 @@ package courses
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"encore.app/internal/entities"
 	"github.com/pocketbase/dbx"
@@ -117,3 +118,103 @@ func (r *repository) FindOne(
 
 	return resp, nil
 }
+
+var recordColumns = []string{
+	"c.id AS id",
+	"c.fullname AS fullname",
+	"c.shortname AS shortname",
+	"c.idnumber AS idnumber",
+	"c.summary AS summary",
+	"c.visible AS visible",
+	"c.startdate AS startdate",
+	"c.enddate AS enddate",
+	"c.category AS category",
+}
+
+func (r *repository) FindTaught(ctx context.Context, req *TaughtParams) ([]Record, error) {
+	whereConds := []dbx.Expression{
+		dbx.HashExp{"ra.userid": req.UserID},
+		dbx.NewExp("r.archetype IN ('editingteacher', 'teacher')"),
+		dbx.NewExp("c.id != 1"),
+	}
+	if req.CategoryID != 0 {
+		whereConds = append(whereConds, dbx.HashExp{"c.category": req.CategoryID})
+	}
+	if req.ActiveEnrolment {
+		whereConds = append(whereConds, dbx.NewExp(`EXISTS (
+			SELECT 1 FROM mdl_user_enrolments ue
+			JOIN mdl_enrol e ON e.id = ue.enrolid
+			WHERE ue.userid = {:userid} AND e.courseid = c.id
+			  AND ue.status = 0 AND e.status = 0
+			  AND ue.timestart < {:now} AND (ue.timeend = 0 OR ue.timeend > {:now}))`,
+			dbx.Params{"userid": req.UserID, "now": time.Now().Unix()}))
+	}
+
+	resp := make([]Record, 0)
+	err := r.db.WithContext(ctx).
+		Select(recordColumns...).
+		Distinct(true).
+		From("mdl_course c").
+		InnerJoin("mdl_context ctx", dbx.NewExp("ctx.instanceid = c.id AND ctx.contextlevel = 50")).
+		InnerJoin("mdl_role_assignments ra", dbx.NewExp("ra.contextid = ctx.id")).
+		InnerJoin("mdl_role r", dbx.NewExp("r.id = ra.roleid")).
+		Where(dbx.And(whereConds...)).
+		OrderBy("c.fullname").
+		All(&resp)
+	if err != nil {
+		return nil, fmt.Errorf("courses: find taught: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *repository) FindInCategory(ctx context.Context, categoryID int64) ([]Record, error) {
+	resp := make([]Record, 0)
+	err := r.db.WithContext(ctx).
+		Select(recordColumns...).
+		From("mdl_course c").
+		Where(dbx.And(
+			dbx.HashExp{"c.category": categoryID},
+			dbx.NewExp("c.id != 1"),
+		)).
+		OrderBy("c.fullname").
+		All(&resp)
+	if err != nil {
+		return nil, fmt.Errorf("courses: find in category: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *repository) FindFields(ctx context.Context, courseIDs []int64) ([]Field, error) {
+	resp := make([]Field, 0)
+	if len(courseIDs) == 0 {
+		return resp, nil
+	}
+
+	ids := make([]interface{}, len(courseIDs))
+	for i, id := range courseIDs {
+		ids[i] = id
+	}
+	err := r.db.WithContext(ctx).
+		Select(
+			"d.instanceid AS courseid",
+			"f.shortname AS shortname",
+			"f.type AS type",
+			"d.intvalue AS intvalue",
+			"d.decvalue AS decvalue",
+			"d.charvalue AS charvalue",
+			"d.value AS value",
+		).
+		From("mdl_customfield_data d").
+		InnerJoin("mdl_customfield_field f", dbx.NewExp("f.id = d.fieldid")).
+		InnerJoin("mdl_customfield_category fc", dbx.NewExp("fc.id = f.categoryid")).
+		Where(dbx.And(
+			dbx.HashExp{"fc.component": "core_course", "fc.area": "course"},
+			dbx.In("d.instanceid", ids...),
+		)).
+		OrderBy("d.instanceid", "fc.sortorder", "f.sortorder").
+		All(&resp)
+	if err != nil {
+		return nil, fmt.Errorf("courses: find fields: %w", err)
+	}
+	return resp, nil
+}
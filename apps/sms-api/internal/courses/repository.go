@@ -2,6 +2,7 @@ package courses
 
 import (
 	"context"
+	"database/sql"
 
 	"encore.app/internal/entities"
 )
@@ -12,4 +13,47 @@ type Repository interface {
 		*entities.GetUsersCoursesParams,
 	) (*entities.GetUsersCoursesResponse, error)
 	FindOne(context.Context, *entities.FindOneCourseParams) (*entities.Course, error)
+	// FindTaught returns the courses a user teaches, ordered by full name.
+	FindTaught(context.Context, *TaughtParams) ([]Record, error)
+	// FindInCategory returns every course of a category, ordered by full
+	// name.
+	FindInCategory(ctx context.Context, categoryID int64) ([]Record, error)
+	// FindFields returns the custom field values of the courses.
+	FindFields(ctx context.Context, courseIDs []int64) ([]Field, error)
+}
+
+// TaughtParams selects the courses where a user holds a teacher or
+// non-editing teacher role.
+type TaughtParams struct {
+	UserID int64
+	// CategoryID limits the courses to one category; 0 means all.
+	CategoryID int64
+	// ActiveEnrolment also requires an active enrolment in the course, as
+	// Moodle's enrol_get_users_courses does.
+	ActiveEnrolment bool
+}
+
+// Record is a row of mdl_course.
+type Record struct {
+	ID        int64          `db:"id"`
+	Fullname  string         `db:"fullname"`
+	Shortname string         `db:"shortname"`
+	IDNumber  string         `db:"idnumber"`
+	Summary   sql.NullString `db:"summary"`
+	Visible   int            `db:"visible"`
+	StartDate int64          `db:"startdate"`
+	EndDate   int64          `db:"enddate"`
+	Category  int64          `db:"category"`
+}
+
+// Field is the value of a course custom field. Which column holds it
+// depends on the field's type.
+type Field struct {
+	CourseID  int64           `db:"courseid"`
+	Shortname string          `db:"shortname"`
+	Type      string          `db:"type"`
+	IntValue  sql.NullInt64   `db:"intvalue"`
+	DecValue  sql.NullFloat64 `db:"decvalue"`
+	CharValue sql.NullString  `db:"charvalue"`
+	Value     sql.NullString  `db:"value"`
 }
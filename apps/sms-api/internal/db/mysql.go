@@ -17,3 +17,16 @@ func New(cfg *config.DatabaseConfig) (*dbx.DB, error) {
 
 	return db, nil
 }
+
+// Open is like New but does not ping the server, so callers that are built
+// at package init time don't fail while MySQL is still starting up.
+// Connections are established lazily on first use.
+func Open(cfg *config.DatabaseConfig) (*dbx.DB, error) {
+	db, err := dbx.Open("mysql", cfg.GetDNS())
+	if err != nil {
+		return nil, err
+	}
+	db.LogFunc = log.Printf
+
+	return db, nil
+}
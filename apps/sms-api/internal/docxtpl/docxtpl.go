@@ -0,0 +1,183 @@
+// Package docxtpl fills DOCX templates with data.
+//
+// Templates use two constructs, written as plain text anywhere in the
+// document body, headers or footers:
+//
+//   - {{path.to.value}} is replaced by the value at that path.
+//   - {{#name}} ... {{/name}} inside a table repeats the rows from the one
+//     holding {{#name}} to the one holding {{/name}} (possibly the same row)
+//     once per item of the list "name". Placeholders inside the rows are
+//     resolved against the item first, then against the enclosing data.
+//
+// Word often splits typed text across several runs; placeholders are merged
+// back into a single run before rendering, keeping the first run's formatting.
+package docxtpl
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrInvalidDocx is returned when the template is not a DOCX package.
+var ErrInvalidDocx = errors.New("docxtpl: not a valid DOCX file")
+
+const documentPart = "word/document.xml"
+
+// Render fills the template with data and returns the resulting DOCX file.
+// Placeholders whose path does not resolve render as empty text and loops
+// over missing lists render no rows.
+func Render(tpl []byte, data map[string]any) ([]byte, error) {
+	zr, err := openDocx(tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	root := &scope{data: data}
+
+	for _, f := range zr.File {
+		if !isTemplatePart(f.Name) {
+			if err := zw.Copy(f); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		content, err := readFile(f)
+		if err != nil {
+			return nil, err
+		}
+		rendered, err := renderPart(content, root)
+		if err != nil {
+			return nil, fmt.Errorf("docxtpl: %s: %w", f.Name, err)
+		}
+
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.Name,
+			Method:   zip.Deflate,
+			Modified: f.Modified,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(w, rendered); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// HasPlaceholders reports whether the template contains at least one
+// {{placeholder}}.
+func HasPlaceholders(tpl []byte) (bool, error) {
+	zr, err := openDocx(tpl)
+	if err != nil {
+		return false, err
+	}
+	for _, f := range zr.File {
+		if !isTemplatePart(f.Name) {
+			continue
+		}
+		content, err := readFile(f)
+		if err != nil {
+			return false, err
+		}
+		if placeholderRe.MatchString(plainText(mergeSplitPlaceholders(content))) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Unresolved returns the placeholders and loops of the template that do not
+// resolve against data, in document order and without duplicates. data is
+// typically sample data with at least one item in every list; list indexes
+// beyond the sample's length are accepted.
+func Unresolved(tpl []byte, data map[string]any) ([]string, error) {
+	zr, err := openDocx(tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		unresolved []string
+		seen       = make(map[string]bool)
+	)
+	report := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			unresolved = append(unresolved, name)
+		}
+	}
+
+	root := &scope{data: data, sample: true}
+	for _, f := range zr.File {
+		if !isTemplatePart(f.Name) {
+			continue
+		}
+		content, err := readFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if err := check(mergeSplitPlaceholders(content), root, report); err != nil {
+			return nil, fmt.Errorf("docxtpl: %s: %w", f.Name, err)
+		}
+	}
+	return unresolved, nil
+}
+
+func openDocx(tpl []byte) (*zip.Reader, error) {
+	zr, err := zip.NewReader(bytes.NewReader(tpl), int64(len(tpl)))
+	if err != nil {
+		return nil, ErrInvalidDocx
+	}
+	for _, f := range zr.File {
+		if f.Name == documentPart {
+			return zr, nil
+		}
+	}
+	return nil, ErrInvalidDocx
+}
+
+// isTemplatePart reports whether a package part may contain placeholders.
+func isTemplatePart(name string) bool {
+	if name == documentPart {
+		return true
+	}
+	dir, file := path.Split(name)
+	return dir == "word/" && path.Ext(file) == ".xml" &&
+		(strings.HasPrefix(file, "header") || strings.HasPrefix(file, "footer"))
+}
+
+func readFile(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func renderPart(xml string, root *scope) (string, error) {
+	xml = mergeSplitPlaceholders(xml)
+	xml, err := expandRowLoops(xml, root)
+	if err != nil {
+		return "", err
+	}
+	return replacePlaceholders(xml, root), nil
+}
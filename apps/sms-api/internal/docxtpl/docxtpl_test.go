@@ -0,0 +1,175 @@
+package docxtpl_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"encore.app/internal/docxtpl"
+)
+
+var tagRe = regexp.MustCompile(`<[^>]+>`)
+
+func run(text string) string {
+	return `<w:r><w:rPr><w:b/></w:rPr><w:t>` + text + `</w:t></w:r>`
+}
+
+func paragraph(runs ...string) string {
+	return `<w:p>` + strings.Join(runs, "") + `</w:p>`
+}
+
+func row(cells ...string) string {
+	var b strings.Builder
+	b.WriteString(`<w:tr>`)
+	for _, c := range cells {
+		b.WriteString(`<w:tc>` + paragraph(run(c)) + `</w:tc>`)
+	}
+	b.WriteString(`</w:tr>`)
+	return b.String()
+}
+
+func buildDocx(t *testing.T, body string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		"[Content_Types].xml": `<Types/>`,
+		"word/document.xml":   `<w:document><w:body>` + body + `</w:body></w:document>`,
+	}
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func documentXML(t *testing.T, docx []byte) string {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(docx), int64(len(docx)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "word/document.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		b, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	t.Fatal("word/document.xml missing from output")
+	return ""
+}
+
+func TestRenderPlaceholders(t *testing.T) {
+	tpl := buildDocx(t, paragraph(
+		run("Course: {{"), run("course."), run("fullname}} ({{course.id}})"),
+		run(" {{missing}}|{{course.note}}"),
+	))
+
+	out, err := docxtpl.Render(tpl, map[string]any{
+		"course": map[string]any{"fullname": "Math & Logic", "id": 7, "note": "{{course.id}}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := documentXML(t, out)
+	if text := tagRe.ReplaceAllString(got, ""); text != `Course: Math &amp; Logic (7) |&#123;&#123;course.id}}` {
+		t.Errorf("unexpected text %q", text)
+	}
+	if !strings.Contains(got, `<w:b/></w:rPr><w:t xml:space="preserve">Course: Math &amp; Logic</w:t>`) {
+		t.Errorf("merged placeholder lost the formatting of its first run:\n%s", got)
+	}
+}
+
+func TestRenderRowLoops(t *testing.T) {
+	tpl := buildDocx(t, `<w:tbl>`+
+		row("No.", "Name", "Grade")+
+		row("{{#students}}{{index}}", "{{fullname}}", "{{grade}} {{course.shortname}}{{/students}}")+
+		row("{{#modules}}{{name}}", "")+
+		row("{{grademax}}", "{{/modules}}")+
+		`</w:tbl>`)
+
+	out, err := docxtpl.Render(tpl, map[string]any{
+		"course": map[string]any{"shortname": "M1"},
+		"students": []map[string]any{
+			{"index": 1, "fullname": "An", "grade": 8.5},
+			{"index": 2, "fullname": "Binh", "grade": nil},
+		},
+		"modules": []any{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := documentXML(t, out)
+	for _, want := range []string{">1<", ">An<", ">8.5 M1<", ">2<", ">Binh<", "> M1<"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, "<w:tr>"); n != 3 {
+		t.Errorf("got %d rows, want header + 2 students", n)
+	}
+	if strings.Contains(got, "{{") {
+		t.Errorf("unrendered placeholders in output:\n%s", got)
+	}
+}
+
+func TestRenderUnclosedLoop(t *testing.T) {
+	tpl := buildDocx(t, `<w:tbl>`+row("{{#students}}{{fullname}}")+`</w:tbl>`)
+
+	if _, err := docxtpl.Render(tpl, map[string]any{}); err == nil {
+		t.Fatal("expected an error for an unclosed loop")
+	}
+}
+
+func TestRenderInvalidDocx(t *testing.T) {
+	if _, err := docxtpl.Render([]byte("not a zip"), nil); !errors.Is(err, docxtpl.ErrInvalidDocx) {
+		t.Fatalf("got %v, want ErrInvalidDocx", err)
+	}
+}
+
+func TestUnresolved(t *testing.T) {
+	tpl := buildDocx(t, paragraph(run("{{course.fullname}} {{course.teacher}}"))+
+		`<w:tbl>`+
+		row("{{#students}}{{fullname}}", "{{grades.7}} {{nickname}}{{/students}}")+
+		row("{{#teachers}}{{name}}{{/teachers}}")+
+		`</w:tbl>`+
+		paragraph(run("{{/students}}")))
+
+	got, err := docxtpl.Unresolved(tpl, map[string]any{
+		"course":   map[string]any{"fullname": "Math"},
+		"students": []map[string]any{{"fullname": "An", "grades": []any{8.0}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"nickname", "#teachers", "course.teacher", "/students"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Unresolved() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,248 @@
+package docxtpl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// scope resolves placeholder paths against the data of the current loop item,
+// falling back to the enclosing data.
+type scope struct {
+	data   map[string]any
+	parent *scope
+	// sample makes list indexes past the end resolve to the first item, so
+	// that templates can be checked against sample data with short lists.
+	sample bool
+}
+
+func (s *scope) lookup(name string) (any, bool) {
+	first, rest, _ := strings.Cut(name, ".")
+	for sc := s; sc != nil; sc = sc.parent {
+		v, ok := sc.data[first]
+		if !ok {
+			continue
+		}
+		if rest == "" {
+			return v, true
+		}
+		return walk(v, strings.Split(rest, "."), s.sample)
+	}
+	return nil, false
+}
+
+func walk(v any, path []string, sample bool) (any, bool) {
+	for _, key := range path {
+		switch c := v.(type) {
+		case map[string]any:
+			next, ok := c[key]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []map[string]any:
+			i, ok := index(key, len(c), sample)
+			if !ok {
+				return nil, false
+			}
+			v = c[i]
+		case []any:
+			i, ok := index(key, len(c), sample)
+			if !ok {
+				return nil, false
+			}
+			v = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func index(key string, n int, sample bool) (int, bool) {
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || n == 0 {
+		return 0, false
+	}
+	if i >= n {
+		return 0, sample
+	}
+	return i, true
+}
+
+// items converts a loop value into the data of each repetition.
+func items(v any) []map[string]any {
+	switch c := v.(type) {
+	case []map[string]any:
+		return c
+	case []any:
+		out := make([]map[string]any, 0, len(c))
+		for _, item := range c {
+			if m, ok := item.(map[string]any); ok {
+				out = append(out, m)
+			} else {
+				out = append(out, map[string]any{"value": item})
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// loop is a {{#name}} … {{/name}} block of table rows.
+type loop struct {
+	name string
+	// first and last are the indexes of the opening and closing rows.
+	first, last int
+	// body is the block's XML with both markers removed.
+	body string
+}
+
+// findLoop returns the loop opened in rows[i], or nil when the row opens none.
+func findLoop(xml string, rows []span, i int) (*loop, error) {
+	text := plainText(xml[rows[i].start:rows[i].end])
+	m := loopOpenRe.FindStringSubmatchIndex(text)
+	if m == nil {
+		return nil, nil
+	}
+	l := &loop{name: text[m[2]:m[3]], first: i, last: -1}
+	closeRe := regexp.MustCompile(`\{\{\s*/\s*` + regexp.QuoteMeta(l.name) + `\s*\}\}`)
+
+	if closeRe.MatchString(text[m[1]:]) {
+		l.last = i
+	} else {
+		for j := i + 1; j < len(rows); j++ {
+			if closeRe.MatchString(plainText(xml[rows[j].start:rows[j].end])) {
+				l.last = j
+				break
+			}
+		}
+	}
+	if l.last < 0 {
+		return nil, fmt.Errorf("loop %q is not closed in the same table", l.name)
+	}
+
+	body := xml[rows[i].start:rows[l.last].end]
+	body = strings.Replace(body, text[m[0]:m[1]], "", 1)
+	l.body = closeRe.ReplaceAllString(body, "")
+	return l, nil
+}
+
+// expandRowLoops repeats the table rows of every {{#name}} … {{/name}} block.
+func expandRowLoops(xml string, sc *scope) (string, error) {
+	rows := elements(xml, "w:tr")
+
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(rows); i++ {
+		l, err := findLoop(xml, rows, i)
+		if err != nil {
+			return "", err
+		}
+		if l == nil {
+			continue
+		}
+
+		b.WriteString(xml[last:rows[i].start])
+		v, _ := sc.lookup(l.name)
+		for _, item := range items(v) {
+			child := &scope{data: item, parent: sc, sample: sc.sample}
+			rendered, err := expandRowLoops(l.body, child)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(replacePlaceholders(rendered, child))
+		}
+		last = rows[l.last].end
+		i = l.last
+	}
+	b.WriteString(xml[last:])
+	return b.String(), nil
+}
+
+// replacePlaceholders substitutes every remaining placeholder. Stray loop
+// markers are dropped.
+func replacePlaceholders(xml string, sc *scope) string {
+	return placeholderRe.ReplaceAllStringFunc(xml, func(m string) string {
+		name := strings.TrimSpace(m[2 : len(m)-2])
+		if name == "" || name[0] == '#' || name[0] == '/' {
+			return ""
+		}
+		v, _ := sc.lookup(name)
+		return escapeText(format(v))
+	})
+}
+
+func format(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case *float64:
+		if x == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*x, 'f', -1, 64)
+	case map[string]any, []map[string]any, []any:
+		return ""
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// Braces are escaped too so that values never form new placeholders.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "{", "&#123;")
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// check reports every placeholder and loop of xml that does not resolve in sc.
+// Loop bodies are checked against the first item of their list.
+func check(xml string, sc *scope, report func(string)) error {
+	rows := elements(xml, "w:tr")
+
+	var rest strings.Builder
+	last := 0
+	for i := 0; i < len(rows); i++ {
+		l, err := findLoop(xml, rows, i)
+		if err != nil {
+			return err
+		}
+		if l == nil {
+			continue
+		}
+
+		v, _ := sc.lookup(l.name)
+		list := items(v)
+		if len(list) == 0 {
+			report("#" + l.name)
+		} else if err := check(l.body, &scope{data: list[0], parent: sc, sample: true}, report); err != nil {
+			return err
+		}
+
+		rest.WriteString(xml[last:rows[i].start])
+		last = rows[l.last].end
+		i = l.last
+	}
+	rest.WriteString(xml[last:])
+
+	for _, m := range placeholderRe.FindAllStringSubmatch(plainText(rest.String()), -1) {
+		name := strings.TrimSpace(m[1])
+		if name == "" {
+			continue
+		}
+		if name[0] == '#' || name[0] == '/' {
+			report(name)
+			continue
+		}
+		if _, ok := sc.lookup(name); !ok {
+			report(name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package docxtpl
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// textRe matches a <w:t> text node. The content never contains '<'.
+	textRe        = regexp.MustCompile(`(<w:t(?:\s[^>]*)?>)([^<]*)(</w:t>)`)
+	placeholderRe = regexp.MustCompile(`\{\{([^{}]*)\}\}`)
+	loopOpenRe    = regexp.MustCompile(`\{\{\s*#\s*([\w.]+)\s*\}\}`)
+)
+
+const preservedTextTag = `<w:t xml:space="preserve">`
+
+// span is the [start, end) byte range of an element in a part.
+type span struct {
+	start, end int
+}
+
+// elements returns the outermost <tag>…</tag> elements of xml in document
+// order. Self-closing elements are skipped since they hold no text.
+func elements(xml, tag string) []span {
+	open, closing := "<"+tag, "</"+tag+">"
+
+	var (
+		spans []span
+		depth int
+		start int
+	)
+	for i := 0; i < len(xml); {
+		switch {
+		case strings.HasPrefix(xml[i:], closing):
+			i += len(closing)
+			if depth > 0 {
+				depth--
+				if depth == 0 {
+					spans = append(spans, span{start, i})
+				}
+			}
+		case strings.HasPrefix(xml[i:], open) && isTagBoundary(xml, i+len(open)):
+			end := strings.IndexByte(xml[i:], '>')
+			if end < 0 {
+				return spans
+			}
+			if xml[i+end-1] != '/' {
+				if depth == 0 {
+					start = i
+				}
+				depth++
+			}
+			i += end + 1
+		default:
+			next := strings.IndexByte(xml[i+1:], '<')
+			if next < 0 {
+				return spans
+			}
+			i += next + 1
+		}
+	}
+	return spans
+}
+
+func isTagBoundary(xml string, i int) bool {
+	if i >= len(xml) {
+		return false
+	}
+	switch xml[i] {
+	case ' ', '>', '/', '\t', '\n', '\r':
+		return true
+	}
+	return false
+}
+
+// plainText returns the concatenated text nodes of an element.
+func plainText(xml string) string {
+	var b strings.Builder
+	for _, m := range textRe.FindAllStringSubmatch(xml, -1) {
+		b.WriteString(m[2])
+	}
+	return b.String()
+}
+
+// mergeSplitPlaceholders moves every placeholder that Word split across
+// several runs of a paragraph into the run where it starts.
+func mergeSplitPlaceholders(xml string) string {
+	paragraphs := elements(xml, "w:p")
+
+	var b strings.Builder
+	last := 0
+	for _, p := range paragraphs {
+		b.WriteString(xml[last:p.start])
+		b.WriteString(mergeParagraph(xml[p.start:p.end]))
+		last = p.end
+	}
+	b.WriteString(xml[last:])
+	return b.String()
+}
+
+func mergeParagraph(p string) string {
+	nodes := textRe.FindAllStringSubmatchIndex(p, -1)
+	if len(nodes) < 2 {
+		return p
+	}
+
+	// owner[i] is the index of the text node holding byte i of the paragraph
+	// text.
+	var (
+		full  strings.Builder
+		owner []int
+	)
+	for n, m := range nodes {
+		text := p[m[4]:m[5]]
+		full.WriteString(text)
+		for range len(text) {
+			owner = append(owner, n)
+		}
+	}
+
+	moved := make(map[int]bool)
+	for _, m := range placeholderRe.FindAllStringIndex(full.String(), -1) {
+		first := owner[m[0]]
+		if owner[m[1]-1] == first {
+			continue
+		}
+		for i := m[0]; i < m[1]; i++ {
+			if owner[i] != first {
+				moved[owner[i]] = true
+				owner[i] = first
+			}
+		}
+		moved[first] = true
+	}
+	if len(moved) == 0 {
+		return p
+	}
+
+	texts := make([]strings.Builder, len(nodes))
+	for i, c := range []byte(full.String()) {
+		texts[owner[i]].WriteByte(c)
+	}
+
+	var b strings.Builder
+	last := 0
+	for n, m := range nodes {
+		b.WriteString(p[last:m[0]])
+		if moved[n] {
+			b.WriteString(preservedTextTag)
+		} else {
+			b.WriteString(p[m[2]:m[3]])
+		}
+		b.WriteString(texts[n].String())
+		b.WriteString(p[m[6]:m[7]])
+		last = m[1]
+	}
+	b.WriteString(p[last:])
+	return b.String()
+}
@@ -15,6 +15,9 @@ const (
 	RoleManager UserRole = "manager"
 	RoleTeacher UserRole = "teacher"
 	RoleStudent UserRole = "student"
+	// RoleService is the synthetic role of API keys. It holds no permissions
+	// of its own; a key may do what its scopes allow.
+	RoleService UserRole = "service"
 )
 
 type UserInfo struct {
@@ -29,6 +32,9 @@ type UserInfo struct {
 	Phone1      string   `json:"phone1"`
 	Username    string   `json:"username"`
 	Role        UserRole `json:"role"`
+	// Suspended and Deleted users can no longer refresh their tokens.
+	Suspended bool `json:"suspended"`
+	Deleted   bool `json:"deleted"`
 }
 
 type MoodleOauth2AccessToken struct {
@@ -47,6 +53,9 @@ func (e *MoodleOauth2AccessToken) TableName() string {
 type CallbackResponse struct {
 	AccessToken  string `json:"accessToken"`
 	RefreshToken string `json:"refreshToken"`
+	// RefreshTokenID is the jti of RefreshToken, kept server-side to detect
+	// reuse. It is never sent to clients.
+	RefreshTokenID string `json:"-"`
 }
 
 // TokenPayload is embedded in JWT claims. Role is included so that
@@ -54,6 +63,19 @@ type CallbackResponse struct {
 type TokenPayload struct {
 	UserID int64    `json:"userID"`
 	Role   UserRole `json:"role"`
+	// SessionID links every token of a login to its server-side session.
+	SessionID string `json:"sid,omitempty"`
+	// Impersonator is the admin acting as UserID, on tokens issued by
+	// impersonation. Such tokens cannot change anything.
+	Impersonator int64 `json:"impersonator,omitempty"`
+	// APIKeyID and Scopes are set instead of UserID when the caller
+	// authenticated with an API key. They are never part of a JWT.
+	APIKeyID int64    `json:"-"`
+	Scopes   []string `json:"-"`
+	// TokenID is the jti of the verified token. It is filled in by
+	// TokenProvider.Verify, read by TokenProvider.GenTokens as the jti of
+	// the new refresh token, and not part of the claims.
+	TokenID string `json:"-"`
 }
 
 type HttpCallbackResponse struct {
@@ -20,4 +20,7 @@ type GetUsersCategoriesRequest struct {
 
 type GetUsersCategoriesResponse struct {
 	Data []Category `json:"data"`
+	// Source is where Moodle's data was read from: "webservice" or
+	// "database".
+	Source string `json:"source,omitempty"`
 }
@@ -0,0 +1,46 @@
+package entities
+
+// CategoryReport consolidates the averages of every student across the
+// courses of a category and its child categories.
+type CategoryReport struct {
+	CategoryID int             `json:"categoryid"`
+	Courses    []ReportCourse  `json:"courses"`
+	Students   []ReportStudent `json:"students"`
+	// Failures lists the courses whose grades could not be loaded. They are
+	// left out of Courses and of every average.
+	Failures []ReportCourseFailure `json:"failures,omitempty"`
+}
+
+type ReportCourse struct {
+	ID           int    `json:"id"`
+	Shortname    string `json:"shortname"`
+	Fullname     string `json:"fullname"`
+	CategoryID   int    `json:"categoryid"`
+	CategoryName string `json:"categoryname"`
+	// Credits weighs the course in the overall average; 1 when unset.
+	Credits float64 `json:"credits"`
+}
+
+type ReportCourseFailure struct {
+	CourseID int    `json:"courseid"`
+	Error    string `json:"error"`
+}
+
+// ReportStudent is one row of the report. Average is the credit-weighted mean
+// of the student's course averages; Passed requires every course to be
+// complete and passed.
+type ReportStudent struct {
+	StudentID int                   `json:"studentid"`
+	Fullname  string                `json:"fullname"`
+	Email     string                `json:"email"`
+	Courses   []ReportStudentCourse `json:"courses"`
+	Average   *float64              `json:"average"`
+	Passed    bool                  `json:"passed"`
+	Rank      int                   `json:"rank"`
+}
+
+// ReportStudentCourse is a student's result in one course of the report.
+type ReportStudentCourse struct {
+	CourseID int `json:"courseid"`
+	StudentAverage
+}
@@ -29,6 +29,9 @@ type GetUsersCoursesRequest struct {
 
 type GetUsersCoursesResponse struct {
 	Data []Course `json:"data"`
+	// Source is where Moodle's data was read from: "webservice" or
+	// "database".
+	Source string `json:"source,omitempty"`
 }
 
 type CourseDetails struct {
@@ -62,3 +65,28 @@ type GetUserCourseDetailsResponse struct {
 type UpdateCourseGradesResponse struct {
 	Data string `json:"data"`
 }
+
+// StudentGradeResult reports the outcome of writing one student's grade.
+type StudentGradeResult struct {
+	StudentID int     `json:"studentid"`
+	Grade     float64 `json:"grade"`
+	Success   bool    `json:"success"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ActivityGradesResult reports the outcome of one activity in a batch update.
+// Success is true only when every student grade in the activity was written.
+type ActivityGradesResult struct {
+	ActivityID int                  `json:"activityid"`
+	ItemNumber int                  `json:"itemnumber"`
+	Success    bool                 `json:"success"`
+	Error      string               `json:"error,omitempty"`
+	Students   []StudentGradeResult `json:"students"`
+}
+
+type BatchUpdateGradesResponse struct {
+	CourseID   int                    `json:"courseid"`
+	Succeeded  int                    `json:"succeeded"`
+	Failed     int                    `json:"failed"`
+	Activities []ActivityGradesResult `json:"activities"`
+}
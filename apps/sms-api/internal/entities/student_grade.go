@@ -29,3 +29,23 @@ type Grade struct {
 	ModuleID     int     `json:"moduleid"`
 	ModuleName   string  `json:"modulename"`
 }
+
+// StudentAverage is a student's weighted course average. Average is nil when
+// the student has no grade that counts toward the average.
+type StudentAverage struct {
+	StudentID int      `json:"studentid"`
+	Average   *float64 `json:"average"`
+	// Complete is true when the student has a grade for every weighted activity.
+	// Passed and Rank are only set for complete students.
+	Complete bool `json:"complete"`
+	Passed   bool `json:"passed"`
+	// Rank is the competition rank ("1224") among the complete students of the
+	// course, 1 = best.
+	Rank int `json:"rank"`
+}
+
+// CourseAverage is one course's entry in the averages of GET /users/grades.
+type CourseAverage struct {
+	CourseID int `json:"courseid"`
+	StudentAverage
+}
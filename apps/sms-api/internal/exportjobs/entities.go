@@ -0,0 +1,57 @@
+package exportjobs
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a job does not exist or has expired.
+var ErrNotFound = errors.New("export job not found")
+
+// Status is the lifecycle state of an export job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Job is an export running in the background. A job exports either one course
+// or every course of a category into a single ZIP archive.
+type Job struct {
+	ID         string `json:"id"`
+	CourseID   int64  `json:"course_id,omitempty"`
+	CategoryID int64  `json:"category_id,omitempty"`
+	TemplateID string `json:"template_id"`
+	Status     Status `json:"status"`
+	// Done and Total count the courses exported so far and overall.
+	Done  int `json:"done"`
+	Total int `json:"total"`
+	// Error describes why the job failed.
+	Error string `json:"error,omitempty"`
+	// Failures lists the courses of a category export that could not be
+	// exported; the archive contains the others.
+	Failures []CourseFailure `json:"failures,omitempty"`
+	Filename string          `json:"filename,omitempty"`
+	Mimetype string          `json:"mimetype,omitempty"`
+	// CreatedBy is the user who queued the job, or CreatedByKey the API key
+	// when a service did.
+	CreatedBy    int64      `json:"created_by"`
+	CreatedByKey int64      `json:"created_by_key,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// CourseFailure records a course that a category export skipped.
+type CourseFailure struct {
+	CourseID int    `json:"course_id"`
+	Error    string `json:"error"`
+}
+
+// Finished reports whether the job has stopped running.
+func (j *Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
@@ -0,0 +1,75 @@
+package exportjobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"encore.app/internal/helper"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix = "exports:jobs:"
+	// ttl bounds how long jobs and their files stay downloadable.
+	ttl = 24 * time.Hour
+)
+
+type redisRepository struct {
+	rdb *redis.Client
+}
+
+var _ Repository = (*redisRepository)(nil)
+
+func NewRedisRepository(rdb *redis.Client) *redisRepository {
+	return &redisRepository{rdb: rdb}
+}
+
+func jobKey(id string) string  { return keyPrefix + id }
+func fileKey(id string) string { return keyPrefix + id + ":file" }
+
+func (r *redisRepository) Save(ctx context.Context, job *Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("exportjobs: encode: %w", err)
+	}
+	if err := r.rdb.Set(ctx, jobKey(job.ID), b, ttl).Err(); err != nil {
+		return fmt.Errorf("exportjobs: save: %w", err)
+	}
+	return nil
+}
+
+func (r *redisRepository) Get(ctx context.Context, id string) (*Job, error) {
+	val, err := r.rdb.Get(ctx, jobKey(id)).Bytes()
+	if helper.IsKeyDoesNotExistErr(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("exportjobs: get: %w", err)
+	}
+
+	job := &Job{}
+	if err := json.Unmarshal(val, job); err != nil {
+		return nil, fmt.Errorf("exportjobs: decode: %w", err)
+	}
+	return job, nil
+}
+
+func (r *redisRepository) SaveFile(ctx context.Context, id string, data []byte) error {
+	if err := r.rdb.Set(ctx, fileKey(id), data, ttl).Err(); err != nil {
+		return fmt.Errorf("exportjobs: save file: %w", err)
+	}
+	return nil
+}
+
+func (r *redisRepository) GetFile(ctx context.Context, id string) ([]byte, error) {
+	data, err := r.rdb.Get(ctx, fileKey(id)).Bytes()
+	if helper.IsKeyDoesNotExistErr(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("exportjobs: get file: %w", err)
+	}
+	return data, nil
+}
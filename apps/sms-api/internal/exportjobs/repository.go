@@ -0,0 +1,12 @@
+package exportjobs
+
+import "context"
+
+// Repository stores export jobs and their finished files. Both expire after a
+// while; Get and GetFile return ErrNotFound afterwards.
+type Repository interface {
+	Save(ctx context.Context, job *Job) error
+	Get(ctx context.Context, id string) (*Job, error)
+	SaveFile(ctx context.Context, id string, data []byte) error
+	GetFile(ctx context.Context, id string) ([]byte, error)
+}
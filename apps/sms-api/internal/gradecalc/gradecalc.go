@@ -0,0 +1,164 @@
+// Package gradecalc computes weighted course averages from Moodle grades using
+// per-exam-type coefficients (e.g. 15P×1, 1T×2, Thi×3).
+package gradecalc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"encore.app/internal/entities"
+	"encore.app/internal/mdlapi"
+)
+
+// scale is the grading scale averages are expressed on. Grades of modules with
+// a different range are rescaled onto it before weighting.
+const scale = 10.0
+
+// Coefficients configures the average computation for one course category.
+type Coefficients struct {
+	// Weights maps an exam type label ("15P", "1T", "Thi") to its coefficient.
+	// Grades of exam types without a weight do not count.
+	Weights map[string]float64 `json:"weights"`
+	// PassMark is the minimum average (on the 10-point scale) to pass.
+	PassMark float64 `json:"passMark"`
+}
+
+// DefaultCoefficients is used for categories without their own configuration.
+func DefaultCoefficients() *Coefficients {
+	return &Coefficients{
+		Weights: map[string]float64{
+			mdlapi.Exam15M.String():   1,
+			mdlapi.Exam45M.String():   2,
+			mdlapi.ExamFinal.String(): 3,
+		},
+		PassMark: 5,
+	}
+}
+
+// Validate rejects unknown exam types, negative weights and out-of-scale marks.
+func (c *Coefficients) Validate() error {
+	if len(c.Weights) == 0 {
+		return fmt.Errorf("weights must not be empty")
+	}
+	total := 0.0
+	for k, w := range c.Weights {
+		if _, ok := mdlapi.ReverseExamTypeMap[k]; !ok {
+			return fmt.Errorf("invalid exam type: %q", k)
+		}
+		if w < 0 {
+			return fmt.Errorf("weight of %s must not be negative", k)
+		}
+		total += w
+	}
+	if total == 0 {
+		return fmt.Errorf("at least one weight must be positive")
+	}
+	if c.PassMark < 0 || c.PassMark > scale {
+		return fmt.Errorf("passMark must be between 0 and %g", scale)
+	}
+	return nil
+}
+
+// ComputeAverages returns the weighted average, pass/fail classification and
+// rank of every student in the course, in the order of details.Students.
+func ComputeAverages(
+	details *mdlapi.GetCourseGradesResponse,
+	coef *Coefficients,
+) []entities.StudentAverage {
+	// Only modules with a weighted exam type count toward the average.
+	weighted := make([]mdlapi.Module, 0, len(details.Modules))
+	for _, m := range details.Modules {
+		if m.ExamType != nil && coef.Weights[m.ExamType.String()] > 0 {
+			weighted = append(weighted, m)
+		}
+	}
+
+	averages := make([]entities.StudentAverage, len(details.Students))
+	for i := range details.Students {
+		s := &details.Students[i]
+		averages[i] = entities.StudentAverage{StudentID: s.ID, Complete: len(weighted) > 0}
+
+		var sum, weights float64
+		for _, m := range weighted {
+			g := s.FindGrade(m.Cmid, m.ItemNumber)
+			if g == nil {
+				averages[i].Complete = false
+				continue
+			}
+			w := coef.Weights[m.ExamType.String()]
+			sum += w * rescale(g.Grade, m.Grademin, m.Grademax)
+			weights += w
+		}
+
+		if weights == 0 {
+			averages[i].Complete = false
+			continue
+		}
+		avg := math.Round(sum/weights*100) / 100
+		averages[i].Average = &avg
+		// A student still missing grades has not passed yet, whatever the
+		// grades so far.
+		averages[i].Passed = averages[i].Complete && avg >= coef.PassMark
+	}
+
+	rank(averages)
+	return averages
+}
+
+// FindStudent returns the average of one student, or nil.
+func FindStudent(averages []entities.StudentAverage, studentID int) *entities.StudentAverage {
+	for i := range averages {
+		if averages[i].StudentID == studentID {
+			return &averages[i]
+		}
+	}
+	return nil
+}
+
+// rescale maps a grade from [grademin, grademax] onto [0, scale]. Grades of
+// modules without a usable range are taken as they are.
+func rescale(grade, grademin, grademax float64) float64 {
+	if grademax <= grademin || (grademin == 0 && grademax == scale) {
+		return grade
+	}
+	return (grade - grademin) * scale / (grademax - grademin)
+}
+
+// rank assigns competition ranks ("1224") by descending average. Students
+// with incomplete grades keep rank 0.
+func rank(averages []entities.StudentAverage) {
+	competitionRank(len(averages),
+		func(i int) *float64 {
+			if !averages[i].Complete {
+				return nil
+			}
+			return averages[i].Average
+		},
+		func(i, r int) { averages[i].Rank = r },
+	)
+}
+
+// competitionRank ranks n entries by descending average; entries without an
+// average are not ranked.
+func competitionRank(n int, average func(i int) *float64, setRank func(i, rank int)) {
+	ranked := make([]int, 0, n)
+	for i := range n {
+		if average(i) != nil {
+			ranked = append(ranked, i)
+		}
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return *average(ranked[a]) > *average(ranked[b])
+	})
+
+	prev := 0
+	for pos, i := range ranked {
+		r := pos + 1
+		if pos > 0 && *average(i) == *average(ranked[pos-1]) {
+			r = prev
+		}
+		setRank(i, r)
+		prev = r
+	}
+}
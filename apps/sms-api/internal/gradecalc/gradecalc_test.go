@@ -0,0 +1,135 @@
+package gradecalc_test
+
+import (
+	"fmt"
+	"testing"
+
+	"encore.app/internal/entities"
+	"encore.app/internal/gradecalc"
+	"encore.app/internal/mdlapi"
+)
+
+func module(cmid int, exam mdlapi.ExamType, grademin, grademax float64) mdlapi.Module {
+	return mdlapi.Module{Cmid: cmid, ExamType: &exam, Grademin: grademin, Grademax: grademax}
+}
+
+// student has a grade for each cmid in grades.
+func student(id int, grades map[int]float64) mdlapi.Student {
+	s := mdlapi.Student{ID: id}
+	for cmid, g := range grades {
+		s.Grades = append(s.Grades, mdlapi.Grade{ModuleID: cmid, Grade: g})
+	}
+	return s
+}
+
+func avg(v float64) *float64 { return &v }
+
+func format(a entities.StudentAverage) string {
+	average := "none"
+	if a.Average != nil {
+		average = fmt.Sprint(*a.Average)
+	}
+	return fmt.Sprintf("{student %d: average %s, complete %t, passed %t, rank %d}",
+		a.StudentID, average, a.Complete, a.Passed, a.Rank)
+}
+
+func TestComputeAverages(t *testing.T) {
+	quiz := module(1, mdlapi.Exam15M, 0, 10)
+	midterm := module(2, mdlapi.Exam45M, 0, 10)
+	final := module(3, mdlapi.ExamFinal, 0, 10)
+
+	tests := []struct {
+		name     string
+		modules  []mdlapi.Module
+		students []mdlapi.Student
+		want     []entities.StudentAverage
+	}{
+		{
+			name:     "weights",
+			modules:  []mdlapi.Module{quiz, midterm, final},
+			students: []mdlapi.Student{student(1, map[int]float64{1: 4, 2: 6, 3: 8})},
+			// (4×1 + 6×2 + 8×3) / 6
+			want: []entities.StudentAverage{{StudentID: 1, Average: avg(6.67), Complete: true, Passed: true, Rank: 1}},
+		},
+		{
+			name:    "missing grades",
+			modules: []mdlapi.Module{quiz, midterm, final},
+			students: []mdlapi.Student{
+				student(1, map[int]float64{1: 4, 3: 8}),
+				student(2, nil),
+			},
+			// (4×1 + 8×3) / 4
+			want: []entities.StudentAverage{
+				{StudentID: 1, Average: avg(7), Complete: false, Passed: false, Rank: 0},
+				{StudentID: 2},
+			},
+		},
+		{
+			name: "grademin",
+			modules: []mdlapi.Module{
+				module(1, mdlapi.Exam15M, 20, 100),
+				module(2, mdlapi.ExamFinal, 0, 20),
+			},
+			students: []mdlapi.Student{
+				student(1, map[int]float64{1: 20, 2: 0}),
+				student(2, map[int]float64{1: 60, 2: 15}),
+			},
+			// (0×1 + 0×3) / 4 and (5×1 + 7.5×3) / 4
+			want: []entities.StudentAverage{
+				{StudentID: 1, Average: avg(0), Complete: true, Passed: false, Rank: 2},
+				{StudentID: 2, Average: avg(6.88), Complete: true, Passed: true, Rank: 1},
+			},
+		},
+		{
+			name:    "incomplete students are not ranked",
+			modules: []mdlapi.Module{quiz, final},
+			students: []mdlapi.Student{
+				student(1, map[int]float64{1: 9}),
+				student(2, map[int]float64{1: 5, 3: 6}),
+			},
+			want: []entities.StudentAverage{
+				{StudentID: 1, Average: avg(9), Complete: false, Passed: false, Rank: 0},
+				{StudentID: 2, Average: avg(5.75), Complete: true, Passed: true, Rank: 1},
+			},
+		},
+		{
+			name:    "ranking ties",
+			modules: []mdlapi.Module{final},
+			students: []mdlapi.Student{
+				student(1, map[int]float64{3: 8}),
+				student(2, map[int]float64{3: 6}),
+				student(3, map[int]float64{3: 8}),
+				student(4, nil),
+				student(5, map[int]float64{3: 4}),
+			},
+			want: []entities.StudentAverage{
+				{StudentID: 1, Average: avg(8), Complete: true, Passed: true, Rank: 1},
+				{StudentID: 2, Average: avg(6), Complete: true, Passed: true, Rank: 3},
+				{StudentID: 3, Average: avg(8), Complete: true, Passed: true, Rank: 1},
+				{StudentID: 4},
+				{StudentID: 5, Average: avg(4), Complete: true, Passed: false, Rank: 4},
+			},
+		},
+		{
+			name:     "no weighted modules",
+			modules:  []mdlapi.Module{{Cmid: 9, Grademax: 10}},
+			students: []mdlapi.Student{student(1, map[int]float64{9: 10})},
+			want:     []entities.StudentAverage{{StudentID: 1}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			details := &mdlapi.GetCourseGradesResponse{Modules: tc.modules, Students: tc.students}
+			got := gradecalc.ComputeAverages(details, gradecalc.DefaultCoefficients())
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d averages, want %d", len(got), len(tc.want))
+			}
+			for i := range got {
+				if format(got[i]) != format(tc.want[i]) {
+					t.Errorf("got %s, want %s", format(got[i]), format(tc.want[i]))
+				}
+			}
+		})
+	}
+}
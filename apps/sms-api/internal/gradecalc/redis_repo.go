@@ -0,0 +1,60 @@
+package gradecalc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"encore.app/internal/helper"
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "grades:coefficients:"
+
+type redisRepository struct {
+	rdb *redis.Client
+}
+
+var _ Repository = (*redisRepository)(nil)
+
+func NewRedisRepository(rdb *redis.Client) *redisRepository {
+	return &redisRepository{rdb: rdb}
+}
+
+func key(categoryID int64) string {
+	return fmt.Sprintf("%s%d", keyPrefix, categoryID)
+}
+
+func (r *redisRepository) Get(ctx context.Context, categoryID int64) (*Coefficients, error) {
+	val, err := r.rdb.Get(ctx, key(categoryID)).Result()
+	if helper.IsKeyDoesNotExistErr(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gradecalc: get: %w", err)
+	}
+
+	coef := &Coefficients{}
+	if err := json.Unmarshal([]byte(val), coef); err != nil {
+		return nil, fmt.Errorf("gradecalc: decode: %w", err)
+	}
+	return coef, nil
+}
+
+func (r *redisRepository) Set(ctx context.Context, categoryID int64, coef *Coefficients) error {
+	b, err := json.Marshal(coef)
+	if err != nil {
+		return fmt.Errorf("gradecalc: encode: %w", err)
+	}
+	if err := r.rdb.Set(ctx, key(categoryID), b, 0).Err(); err != nil {
+		return fmt.Errorf("gradecalc: set: %w", err)
+	}
+	return nil
+}
+
+func (r *redisRepository) Delete(ctx context.Context, categoryID int64) error {
+	if err := r.rdb.Del(ctx, key(categoryID)).Err(); err != nil {
+		return fmt.Errorf("gradecalc: delete: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package gradecalc
+
+import (
+	"math"
+	"sort"
+
+	"encore.app/internal/entities"
+	"encore.app/internal/mdlapi"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// CourseResult is the gradebook of one course with its computed averages.
+type CourseResult struct {
+	Course   entities.ReportCourse
+	Details  *mdlapi.GetCourseGradesResponse
+	Averages []entities.StudentAverage
+}
+
+// Consolidate merges the results of several courses into one row per student,
+// sorted by name in Vietnamese order (given name first) and ranked by overall
+// average.
+func Consolidate(categoryID int, courses []CourseResult) *entities.CategoryReport {
+	report := &entities.CategoryReport{
+		CategoryID: categoryID,
+		Courses:    make([]entities.ReportCourse, len(courses)),
+		Students:   []entities.ReportStudent{},
+	}
+
+	index := make(map[int]int)
+	firstnames := []string{}
+	lastnames := []string{}
+	for i, c := range courses {
+		report.Courses[i] = c.Course
+		for j := range c.Details.Students {
+			s := &c.Details.Students[j]
+			k, ok := index[s.ID]
+			if !ok {
+				k = len(report.Students)
+				index[s.ID] = k
+				report.Students = append(report.Students, entities.ReportStudent{
+					StudentID: s.ID,
+					Fullname:  s.Fullname,
+					Email:     s.Email,
+					Courses:   []entities.ReportStudentCourse{},
+				})
+				firstnames = append(firstnames, s.Firstname)
+				lastnames = append(lastnames, s.Lastname)
+			}
+
+			result := entities.ReportStudentCourse{CourseID: c.Course.ID}
+			result.StudentID = s.ID
+			if a := FindStudent(c.Averages, s.ID); a != nil {
+				result.StudentAverage = *a
+			}
+			report.Students[k].Courses = append(report.Students[k].Courses, result)
+		}
+	}
+
+	credits := make(map[int]float64, len(courses))
+	for _, c := range courses {
+		credits[c.Course.ID] = c.Course.Credits
+	}
+	for i := range report.Students {
+		overall(&report.Students[i], credits)
+	}
+
+	order := make([]int, len(report.Students))
+	for i := range order {
+		order[i] = i
+	}
+	col := collate.New(language.Vietnamese)
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if c := col.CompareString(firstnames[i], firstnames[j]); c != 0 {
+			return c < 0
+		}
+		return col.CompareString(lastnames[i], lastnames[j]) < 0
+	})
+	sorted := make([]entities.ReportStudent, len(order))
+	for i, k := range order {
+		sorted[i] = report.Students[k]
+	}
+	report.Students = sorted
+
+	competitionRank(len(report.Students),
+		func(i int) *float64 { return report.Students[i].Average },
+		func(i, r int) { report.Students[i].Rank = r },
+	)
+	return report
+}
+
+// overall computes the credit-weighted average and pass status of a student.
+func overall(s *entities.ReportStudent, credits map[int]float64) {
+	var sum, weights float64
+	s.Passed = len(s.Courses) > 0
+	for _, c := range s.Courses {
+		if !c.Complete || !c.Passed {
+			s.Passed = false
+		}
+		if c.Average == nil {
+			continue
+		}
+		w := credits[c.CourseID]
+		if w <= 0 {
+			w = 1
+		}
+		sum += w * *c.Average
+		weights += w
+	}
+	if weights > 0 {
+		avg := math.Round(sum/weights*100) / 100
+		s.Average = &avg
+	}
+}
@@ -0,0 +1,11 @@
+package gradecalc
+
+import "context"
+
+// Repository stores the coefficients configured per course category.
+type Repository interface {
+	// Get returns the category's coefficients, or nil when none are set.
+	Get(ctx context.Context, categoryID int64) (*Coefficients, error)
+	Set(ctx context.Context, categoryID int64, coef *Coefficients) error
+	Delete(ctx context.Context, categoryID int64) error
+}
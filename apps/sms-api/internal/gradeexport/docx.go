@@ -0,0 +1,197 @@
+package gradeexport
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"encore.app/internal/docxtpl"
+	"encore.app/internal/entities"
+	"encore.app/internal/gradecalc"
+	"encore.app/internal/mdlapi"
+)
+
+const MimetypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// location is the school's timezone, used for the dates printed on reports.
+var location = time.FixedZone("ICT", 7*60*60)
+
+// IsNativeDOCX reports whether a stored template uses the {{placeholder}}
+// syntax rendered by the SMS. Older templates written for the Moodle plugin
+// use ${placeholder} and are still rendered by Moodle.
+func IsNativeDOCX(tpl []byte) bool {
+	ok, err := docxtpl.HasPlaceholders(tpl)
+	return err == nil && ok
+}
+
+// RenderCourseDOCX fills a DOCX template with the gradebook of one course.
+// See CourseData for the available placeholders.
+func RenderCourseDOCX(
+	tpl []byte,
+	details *mdlapi.GetCourseGradesResponse,
+	averages []entities.StudentAverage,
+) ([]byte, error) {
+	return docxtpl.Render(tpl, CourseData(details, averages, time.Now()))
+}
+
+// CourseData exposes a course gradebook to DOCX templates:
+//
+//	{{course.fullname}} {{course.shortname}} {{course.idnumber}} {{course.summary}}
+//	{{course.startdate}} {{course.enddate}}
+//	{{today.date}} {{today.day}} {{today.month}} {{today.year}}
+//	{{summary.students}} {{summary.passed}} {{summary.failed}}
+//	{{summary.incomplete}} {{summary.average}}
+//	{{#modules}} {{index}} {{name}} {{examtype}} {{grademax}} {{/modules}}
+//	{{#students}} {{index}} {{fullname}} {{firstname}} {{lastname}} {{username}}
+//	  {{email}} {{grades.N}} {{exams.15P}} {{exams.1T}} {{exams.Thi}}
+//	  {{average}} {{result}} {{rank}} {{/students}}
+//
+// grades.N is the student's grade in the N-th module (0-based, in the order of
+// the modules list); exams holds the plain average of each exam type.
+func CourseData(
+	details *mdlapi.GetCourseGradesResponse,
+	averages []entities.StudentAverage,
+	now time.Time,
+) map[string]any {
+	c := details.Course
+
+	modules := make([]map[string]any, len(details.Modules))
+	for i, m := range details.Modules {
+		examType := ""
+		if m.ExamType != nil {
+			examType = m.ExamType.String()
+		}
+		modules[i] = map[string]any{
+			"index":    i + 1,
+			"cmid":     m.Cmid,
+			"name":     m.Name,
+			"type":     m.Type,
+			"examtype": examType,
+			"grademin": m.Grademin,
+			"grademax": m.Grademax,
+		}
+	}
+
+	var (
+		passed, failed, incomplete int
+		sum                        float64
+		counted                    int
+	)
+	students := make([]map[string]any, len(details.Students))
+	for i := range details.Students {
+		s := &details.Students[i]
+
+		username := ""
+		if s.Username != nil {
+			username = *s.Username
+		}
+
+		grades := make([]any, len(details.Modules))
+		for j, m := range details.Modules {
+			if g := s.FindGrade(m.Cmid, m.ItemNumber); g != nil {
+				grades[j] = g.Grade
+			}
+		}
+
+		student := map[string]any{
+			"index":     i + 1,
+			"id":        s.ID,
+			"fullname":  s.Fullname,
+			"firstname": s.Firstname,
+			"lastname":  s.Lastname,
+			"username":  username,
+			"email":     s.Email,
+			"grades":    grades,
+			"exams":     examAverages(details.Modules, s),
+		}
+		if a := gradecalc.FindStudent(averages, s.ID); a != nil && a.Average != nil {
+			student["average"] = *a.Average
+			student["result"] = resultLabel(a)
+			student["rank"] = a.Rank
+			sum += *a.Average
+			counted++
+			switch {
+			case !a.Complete:
+				incomplete++
+			case a.Passed:
+				passed++
+			default:
+				failed++
+			}
+		} else {
+			incomplete++
+		}
+		students[i] = student
+	}
+
+	summary := map[string]any{
+		"students":   len(details.Students),
+		"passed":     passed,
+		"failed":     failed,
+		"incomplete": incomplete,
+	}
+	if counted > 0 {
+		summary["average"] = round2(sum / float64(counted))
+	}
+
+	now = now.In(location)
+	return map[string]any{
+		"course": map[string]any{
+			"id":        c.ID,
+			"fullname":  c.Fullname,
+			"shortname": c.Shortname,
+			"idnumber":  c.IDNumber,
+			"summary":   stripTags(c.Summary),
+			"startdate": formatDate(c.StartDate),
+			"enddate":   formatDate(c.EndDate),
+		},
+		"today": map[string]any{
+			"date":  now.Format("02/01/2006"),
+			"day":   now.Format("02"),
+			"month": now.Format("01"),
+			"year":  now.Year(),
+		},
+		"summary":  summary,
+		"modules":  modules,
+		"students": students,
+	}
+}
+
+// examAverages returns the plain average of a student's grades per exam type.
+func examAverages(modules []mdlapi.Module, s *mdlapi.Student) map[string]any {
+	sums := map[string]float64{}
+	counts := map[string]int{}
+	for _, m := range modules {
+		if m.ExamType == nil {
+			continue
+		}
+		if g := s.FindGrade(m.Cmid, m.ItemNumber); g != nil {
+			sums[m.ExamType.String()] += g.Grade
+			counts[m.ExamType.String()]++
+		}
+	}
+
+	exams := make(map[string]any, len(sums))
+	for k, sum := range sums {
+		exams[k] = round2(sum / float64(counts[k]))
+	}
+	return exams
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func formatDate(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).In(location).Format("02/01/2006")
+}
+
+var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
+
+func stripTags(html string) string {
+	return strings.TrimSpace(htmlTagRe.ReplaceAllString(html, ""))
+}
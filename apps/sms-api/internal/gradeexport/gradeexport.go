@@ -0,0 +1,71 @@
+// Package gradeexport renders course gradebooks into office documents without
+// going through the Moodle export plugin.
+package gradeexport
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"encore.app/internal/mdlapi"
+)
+
+// Template IDs of the exporters built into the SMS. They are listed next to
+// the templates stored in Moodle and selected through the same templateId.
+const (
+	NativeXLSXTemplateID = "native-xlsx"
+)
+
+const MimetypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// otherExamType groups modules without an exam type.
+const otherExamType = "Other"
+
+// examTypeOrder is the column group order of the gradebook.
+var examTypeOrder = []mdlapi.ExamType{mdlapi.Exam15M, mdlapi.Exam45M, mdlapi.ExamFinal}
+
+// ModuleGroup is a run of consecutive gradebook columns sharing an exam type.
+type ModuleGroup struct {
+	Label   string
+	Modules []mdlapi.Module
+}
+
+// GroupModules orders the course's modules by exam type (15P, 1T, Thi), keeping
+// Moodle's order within each group. Modules without an exam type come last.
+func GroupModules(modules []mdlapi.Module) []ModuleGroup {
+	groups := make([]ModuleGroup, 0, len(examTypeOrder)+1)
+	for _, et := range examTypeOrder {
+		g := ModuleGroup{Label: et.String()}
+		for _, m := range modules {
+			if m.ExamType != nil && *m.ExamType == et {
+				g.Modules = append(g.Modules, m)
+			}
+		}
+		if len(g.Modules) > 0 {
+			groups = append(groups, g)
+		}
+	}
+
+	other := ModuleGroup{Label: otherExamType}
+	for _, m := range modules {
+		if m.ExamType == nil {
+			other.Modules = append(other.Modules, m)
+		}
+	}
+	if len(other.Modules) > 0 {
+		groups = append(groups, other)
+	}
+	return groups
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^\p{L}\p{N}._-]+`)
+
+// Filename builds a download filename from the course shortname.
+func Filename(details *mdlapi.GetCourseGradesResponse, ext string) string {
+	name := details.Course.Shortname
+	if name == "" {
+		name = fmt.Sprintf("course-%d", details.Course.ID)
+	}
+	name = strings.Trim(unsafeFilenameChars.ReplaceAllString(name, "_"), "_")
+	return fmt.Sprintf("grades-%s.%s", name, ext)
+}
@@ -0,0 +1,102 @@
+package gradeexport
+
+import (
+	"bytes"
+
+	"encore.app/internal/entities"
+	"github.com/xuri/excelize/v2"
+)
+
+const reportSheet = "Report"
+
+// BuildCategoryReportXLSX renders a category report: one row per student with
+// the average of each course, the overall average, result and rank.
+func BuildCategoryReportXLSX(report *entities.CategoryReport) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", reportSheet); err != nil {
+		return nil, err
+	}
+	styles, err := newXLSXStyles(f)
+	if err != nil {
+		return nil, err
+	}
+
+	const headerRows = 2
+	summaryCol := studentColumns + len(report.Courses) + 1
+	lastCol := summaryCol + 2
+
+	w := &sheetWriter{f: f, sheet: reportSheet}
+
+	// Row 1: column titles; row 2: course full names under their shortnames.
+	for i, label := range []string{"No.", "Student", "Email"} {
+		w.set(i+1, 1, label)
+		w.merge(i+1, 1, i+1, 2)
+	}
+	courseCol := make(map[int]int, len(report.Courses))
+	for i, c := range report.Courses {
+		col := studentColumns + 1 + i
+		courseCol[c.ID] = col
+		w.set(col, 1, c.Shortname)
+		w.set(col, 2, c.Fullname)
+	}
+	for i, label := range []string{"Average", "Result", "Rank"} {
+		w.set(summaryCol+i, 1, label)
+		w.merge(summaryCol+i, 1, summaryCol+i, 2)
+	}
+	w.style(1, 1, lastCol, headerRows, styles.header)
+
+	for i, s := range report.Students {
+		row := headerRows + 1 + i
+		w.set(1, row, i+1)
+		w.set(2, row, s.Fullname)
+		w.set(3, row, s.Email)
+		for _, c := range s.Courses {
+			if c.Average != nil {
+				w.set(courseCol[c.CourseID], row, *c.Average)
+			}
+		}
+		if s.Average != nil {
+			w.set(summaryCol, row, *s.Average)
+			w.set(summaryCol+2, row, s.Rank)
+		}
+		if s.Passed {
+			w.set(summaryCol+1, row, "Passed")
+		} else {
+			w.set(summaryCol+1, row, "Not passed")
+		}
+	}
+	if len(report.Students) > 0 {
+		lastRow := headerRows + len(report.Students)
+		w.style(1, headerRows+1, lastCol, lastRow, styles.cell)
+		w.style(studentColumns+1, headerRows+1, summaryCol, lastRow, styles.grade)
+	}
+
+	w.width(1, 1, 6)
+	w.width(2, 2, 30)
+	w.width(3, 3, 28)
+	if len(report.Courses) > 0 {
+		w.width(studentColumns+1, studentColumns+len(report.Courses), 16)
+	}
+	w.width(summaryCol, lastCol, 12)
+
+	if w.err != nil {
+		return nil, w.err
+	}
+	if err := f.SetPanes(reportSheet, &excelize.Panes{
+		Freeze:      true,
+		XSplit:      studentColumns,
+		YSplit:      headerRows,
+		TopLeftCell: cellName(studentColumns+1, headerRows+1),
+		ActivePane:  "bottomRight",
+	}); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
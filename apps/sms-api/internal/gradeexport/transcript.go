@@ -0,0 +1,182 @@
+package gradeexport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"encore.app/internal/config"
+	"encore.app/internal/entities"
+	"encore.app/internal/mdlapi"
+)
+
+// FormatPDF is the other format transcripts can be built in, next to
+// FormatDOCX.
+const FormatPDF = "pdf"
+
+const MimetypePDF = "application/pdf"
+
+// Transcript is a student's grades across all of their courses, laid out for
+// printing.
+type Transcript struct {
+	School   config.SchoolConfig
+	UserID   int
+	Username string
+	Fullname string
+	Email    string
+	Courses  []TranscriptCourse
+	// Average is the credit-weighted average of the courses with an average.
+	Average *float64
+	Credits float64
+	Date    time.Time
+}
+
+// TranscriptCourse is one line of the transcript.
+type TranscriptCourse struct {
+	Shortname string
+	Fullname  string
+	Credits   float64
+	// Exams holds the student's grades of each exam type, in examTypeOrder.
+	Exams    [][]float64
+	Average  *float64
+	Result   string
+	Rank     int
+	Teachers []string
+}
+
+// NewTranscript builds the transcript of a student from GET /users/grades.
+// Course credits come from the "credit" course metadata and default to 1.
+func NewTranscript(
+	school config.SchoolConfig,
+	grades *mdlapi.GetUserGradesResponse,
+	now time.Time,
+) *Transcript {
+	t := &Transcript{
+		School:   school,
+		UserID:   grades.UserID,
+		Username: grades.Username,
+		Fullname: strings.TrimSpace(grades.LastName + " " + grades.FirstName),
+		Email:    grades.Email,
+		Courses:  make([]TranscriptCourse, 0, len(grades.Courses)),
+		Date:     now.In(location),
+	}
+
+	averages := make(map[int]*entities.CourseAverage, len(grades.Averages))
+	for i := range grades.Averages {
+		averages[grades.Averages[i].CourseID] = &grades.Averages[i]
+	}
+
+	var sum, weights float64
+	for _, c := range grades.Courses {
+		course := TranscriptCourse{
+			Shortname: c.ShortName,
+			Fullname:  c.CourseName,
+			Credits:   1,
+			Exams:     make([][]float64, len(examTypeOrder)),
+		}
+		for _, m := range c.Metadata {
+			if m.Name == "credit" && m.Value > 0 {
+				course.Credits = float64(m.Value)
+			}
+		}
+		for _, g := range c.Grades {
+			if g.ExamType == nil {
+				continue
+			}
+			for i, et := range examTypeOrder {
+				if *g.ExamType == et {
+					course.Exams[i] = append(course.Exams[i], g.Grade)
+				}
+			}
+		}
+		for _, teacher := range c.Teachers {
+			course.Teachers = append(course.Teachers, teacher.Fullname)
+		}
+		if a := averages[c.CourseID]; a != nil && a.Average != nil {
+			course.Average = a.Average
+			course.Result = resultLabel(&a.StudentAverage)
+			course.Rank = a.Rank
+			sum += course.Credits * *a.Average
+			weights += course.Credits
+		} else {
+			course.Result = resultLabel(&entities.StudentAverage{})
+		}
+		t.Credits += course.Credits
+		t.Courses = append(t.Courses, course)
+	}
+	if weights > 0 {
+		avg := round2(sum / weights)
+		t.Average = &avg
+	}
+	return t
+}
+
+// BuildTranscript renders the transcript as a PDF or DOCX document and returns
+// the file with its name and mimetype.
+func BuildTranscript(t *Transcript, format string) (data []byte, filename, mimetype string, err error) {
+	name := unsafeFilenameChars.ReplaceAllString(t.Username, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		name = strconv.Itoa(t.UserID)
+	}
+
+	switch format {
+	case FormatPDF:
+		data, err = buildTranscriptPDF(t)
+		mimetype = MimetypePDF
+	case FormatDOCX:
+		data, err = buildTranscriptDOCX(t)
+		mimetype = MimetypeDOCX
+	default:
+		return nil, "", "", fmt.Errorf("unsupported transcript format %q", format)
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+	return data, fmt.Sprintf("transcript-%s.%s", name, format), mimetype, nil
+}
+
+// transcriptColumns are the headers of the transcript table. The exam type
+// columns sit between Credits and Average.
+func transcriptColumns() []string {
+	cols := []string{"No.", "Course", "Credits"}
+	for _, et := range examTypeOrder {
+		cols = append(cols, et.String())
+	}
+	return append(cols, "Average", "Result", "Teachers")
+}
+
+// transcriptRow formats one course as the cells of transcriptColumns.
+func transcriptRow(i int, c *TranscriptCourse) []string {
+	row := []string{strconv.Itoa(i + 1), c.Fullname, formatNumber(c.Credits)}
+	for _, grades := range c.Exams {
+		cells := make([]string, len(grades))
+		for j, g := range grades {
+			cells[j] = formatNumber(g)
+		}
+		row = append(row, strings.Join(cells, "; "))
+	}
+	average := ""
+	if c.Average != nil {
+		average = formatNumber(*c.Average)
+	}
+	return append(row, average, c.Result, strings.Join(c.Teachers, ", "))
+}
+
+// transcriptSummary is printed below the table.
+func transcriptSummary(t *Transcript) []string {
+	average := "-"
+	if t.Average != nil {
+		average = formatNumber(*t.Average)
+	}
+	return []string{
+		fmt.Sprintf("Courses: %d", len(t.Courses)),
+		fmt.Sprintf("Total credits: %s", formatNumber(t.Credits)),
+		fmt.Sprintf("Weighted average: %s", average),
+	}
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(round2(v), 'f', -1, 64)
+}
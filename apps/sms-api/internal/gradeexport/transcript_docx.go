@@ -0,0 +1,128 @@
+package gradeexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`</Types>`
+
+const docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`</Relationships>`
+
+// transcriptWidths are the DOCX column widths of transcriptColumns, in
+// twentieths of a point. They add up to the text width of a landscape A4 page.
+var transcriptWidths = []int{600, 3600, 800, 1500, 1300, 1100, 1000, 1300, 3936}
+
+// buildTranscriptDOCX writes the transcript as a landscape A4 Word document.
+// The document is generated from scratch, so it needs no template.
+func buildTranscriptDOCX(t *Transcript) ([]byte, error) {
+	var body strings.Builder
+
+	// School heading on the left, national heading on the right.
+	body.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="5000" w:type="pct"/><w:tblLook w:val="0000"/></w:tblPr>`)
+	school := paragraph(t.School.Authority, "center", false) + paragraph(t.School.Name, "center", true)
+	nation := paragraph(t.School.Country, "center", true) + paragraph(t.School.Motto, "center", true)
+	body.WriteString(`<w:tr>` + docxCell(7568, school) + docxCell(7568, nation) + `</w:tr></w:tbl>`)
+
+	body.WriteString(paragraph("", "", false))
+	body.WriteString(paragraph("STUDENT TRANSCRIPT", "center", true))
+	body.WriteString(paragraph("Student: "+t.Fullname, "", false))
+	body.WriteString(paragraph("Username: "+t.Username, "", false))
+	body.WriteString(paragraph("Email: "+t.Email, "", false))
+
+	body.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="5000" w:type="pct"/><w:tblBorders>`)
+	for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+		fmt.Fprintf(&body, `<w:%s w:val="single" w:sz="4" w:space="0" w:color="000000"/>`, side)
+	}
+	body.WriteString(`</w:tblBorders></w:tblPr><w:tblGrid>`)
+	for _, w := range transcriptWidths {
+		fmt.Fprintf(&body, `<w:gridCol w:w="%d"/>`, w)
+	}
+	body.WriteString(`</w:tblGrid>`)
+
+	// The header row repeats on every printed page.
+	body.WriteString(`<w:tr><w:trPr><w:tblHeader/></w:trPr>`)
+	for i, label := range transcriptColumns() {
+		body.WriteString(docxCell(transcriptWidths[i], paragraph(label, "center", true)))
+	}
+	body.WriteString(`</w:tr>`)
+	for i := range t.Courses {
+		body.WriteString(`<w:tr><w:trPr><w:cantSplit/></w:trPr>`)
+		for j, cell := range transcriptRow(i, &t.Courses[i]) {
+			align := "center"
+			if j == 1 || j == len(transcriptWidths)-1 {
+				align = ""
+			}
+			body.WriteString(docxCell(transcriptWidths[j], paragraph(cell, align, false)))
+		}
+		body.WriteString(`</w:tr>`)
+	}
+	body.WriteString(`</w:tbl>`)
+
+	body.WriteString(paragraph("", "", false))
+	for _, line := range transcriptSummary(t) {
+		body.WriteString(paragraph(line, "", false))
+	}
+	body.WriteString(paragraph(fmt.Sprintf("Date: %s", t.Date.Format("02/01/2006")), "right", false))
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		body.String() +
+		`<w:sectPr><w:pgSz w:w="16838" w:h="11906" w:orient="landscape"/>` +
+		`<w:pgMar w:top="851" w:right="851" w:bottom="851" w:left="851" w:header="425" w:footer="425" w:gutter="0"/>` +
+		`</w:sectPr></w:body></w:document>`
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRels},
+		{"word/document.xml", document},
+	} {
+		w, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// docxCell wraps paragraphs in a table cell of the given width.
+func docxCell(width int, content string) string {
+	return fmt.Sprintf(`<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/></w:tcPr>%s</w:tc>`, width, content)
+}
+
+// paragraph returns a single-run paragraph in Times New Roman 12pt. align is
+// a w:jc value, or empty for the default left alignment.
+func paragraph(text, align string, bold bool) string {
+	var b strings.Builder
+	b.WriteString(`<w:p><w:pPr><w:spacing w:before="0" w:after="60"/>`)
+	if align != "" {
+		fmt.Fprintf(&b, `<w:jc w:val="%s"/>`, align)
+	}
+	b.WriteString(`</w:pPr><w:r><w:rPr><w:rFonts w:ascii="Times New Roman" w:hAnsi="Times New Roman" w:cs="Times New Roman"/>`)
+	if bold {
+		b.WriteString(`<w:b/>`)
+	}
+	b.WriteString(`<w:sz w:val="24"/></w:rPr><w:t xml:space="preserve">`)
+	_ = xml.EscapeText(&b, []byte(text))
+	b.WriteString(`</w:t></w:r></w:p>`)
+	return b.String()
+}
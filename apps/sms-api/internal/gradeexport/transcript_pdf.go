@@ -0,0 +1,144 @@
+package gradeexport
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// The core PDF fonts cannot print Vietnamese, so a Unicode font is embedded.
+// DejaVu is distributed under a free license; see fonts/LICENSE.
+var (
+	//go:embed fonts/DejaVuSerif.ttf
+	serifRegular []byte
+	//go:embed fonts/DejaVuSerif-Bold.ttf
+	serifBold []byte
+)
+
+const (
+	pdfFont       = "DejaVuSerif"
+	pdfMargin     = 10.0
+	pdfLineHeight = 5.0
+	pdfFontSize   = 9.0
+)
+
+// buildTranscriptPDF writes the transcript as a landscape A4 PDF. Column
+// widths follow the DOCX layout, scaled to the page.
+func buildTranscriptPDF(t *Transcript) ([]byte, error) {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(pdfFont, "", serifRegular)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", serifBold)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetXY(pdfMargin, -pdfMargin)
+		pdf.SetFont(pdfFont, "", 8)
+		pdf.CellFormat(0, 4, fmt.Sprintf("%d/{nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	textWidth := pageWidth - 2*pdfMargin
+	half := textWidth / 2
+
+	// School heading on the left, national heading on the right.
+	top := pdf.GetY()
+	pdf.SetFont(pdfFont, "", 11)
+	pdf.CellFormat(half, 6, t.School.Authority, "", 2, "C", false, 0, "")
+	pdf.SetFont(pdfFont, "B", 11)
+	pdf.CellFormat(half, 6, t.School.Name, "", 2, "C", false, 0, "")
+	pdf.SetXY(pdfMargin+half, top)
+	pdf.CellFormat(half, 6, t.School.Country, "", 2, "C", false, 0, "")
+	pdf.CellFormat(half, 6, t.School.Motto, "", 2, "C", false, 0, "")
+	pdf.SetXY(pdfMargin, top+18)
+
+	pdf.SetFont(pdfFont, "B", 14)
+	pdf.CellFormat(textWidth, 8, "STUDENT TRANSCRIPT", "", 1, "C", false, 0, "")
+	pdf.SetFont(pdfFont, "", 11)
+	for _, line := range []string{
+		"Student: " + t.Fullname,
+		"Username: " + t.Username,
+		"Email: " + t.Email,
+	} {
+		pdf.CellFormat(textWidth, 6, line, "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(2)
+
+	widths := make([]float64, len(transcriptWidths))
+	total := 0
+	for _, w := range transcriptWidths {
+		total += w
+	}
+	for i, w := range transcriptWidths {
+		widths[i] = float64(w) * textWidth / float64(total)
+	}
+
+	// row draws one table row, moving to a new page (and repeating the
+	// header) when it does not fit.
+	var (
+		header []string
+		row    func(cells []string, bold bool)
+	)
+	row = func(cells []string, bold bool) {
+		style := ""
+		if bold {
+			style = "B"
+		}
+		pdf.SetFont(pdfFont, style, pdfFontSize)
+
+		lines := make([][]string, len(cells))
+		height := pdfLineHeight
+		for i, cell := range cells {
+			lines[i] = pdf.SplitText(cell, widths[i]-2)
+			if h := float64(len(lines[i])) * pdfLineHeight; h > height {
+				height = h
+			}
+		}
+		if pdf.GetY()+height > pageHeight-pdfMargin-5 {
+			pdf.AddPage()
+			if !bold {
+				row(header, true)
+				pdf.SetFont(pdfFont, style, pdfFontSize)
+			}
+		}
+
+		x, y := pdf.GetX(), pdf.GetY()
+		for i := range cells {
+			align := "C"
+			if !bold && (i == 1 || i == len(cells)-1) {
+				align = "L"
+			}
+			pdf.Rect(x, y, widths[i], height, "D")
+			for j, line := range lines[i] {
+				pdf.SetXY(x+1, y+float64(j)*pdfLineHeight)
+				pdf.CellFormat(widths[i]-2, pdfLineHeight, line, "", 0, align, false, 0, "")
+			}
+			x += widths[i]
+		}
+		pdf.SetXY(pdfMargin, y+height)
+	}
+
+	header = transcriptColumns()
+	row(header, true)
+	for i := range t.Courses {
+		row(transcriptRow(i, &t.Courses[i]), false)
+	}
+
+	pdf.Ln(4)
+	pdf.SetFont(pdfFont, "", 11)
+	for _, line := range append(transcriptSummary(t), "Date: "+t.Date.Format("02/01/2006")) {
+		if pdf.GetY()+6 > pageHeight-pdfMargin-5 {
+			pdf.AddPage()
+		}
+		pdf.CellFormat(textWidth, 6, line, "", 1, "L", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
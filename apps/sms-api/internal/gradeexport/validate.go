@@ -0,0 +1,125 @@
+package gradeexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"encore.app/internal/docxtpl"
+	"encore.app/internal/entities"
+	"encore.app/internal/mdlapi"
+)
+
+// MaxTemplateSize is the largest template file accepted for upload.
+const MaxTemplateSize = 10 << 20
+
+// Template file formats, named after their extension.
+const (
+	FormatDOCX = "docx"
+	FormatXLSX = "xlsx"
+)
+
+// CourseTemplateType is the export template type rendered with course data.
+const CourseTemplateType = "course"
+
+// ValidateTemplate checks an uploaded template before it is stored: it must
+// be a DOCX or XLSX package no larger than MaxTemplateSize whose extension
+// matches its content. Course DOCX templates written for the SMS renderer must
+// only use known placeholders. It returns the detected format.
+func ValidateTemplate(templateType, filename string, data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", errors.New("template file is empty")
+	}
+	if len(data) > MaxTemplateSize {
+		return "", fmt.Errorf("template file is larger than %d MB", MaxTemplateSize>>20)
+	}
+
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	if ext != FormatDOCX && ext != FormatXLSX {
+		return "", fmt.Errorf("unsupported template extension %q, expected .docx or .xlsx", filepath.Ext(filename))
+	}
+
+	format, err := detectFormat(data)
+	if err != nil {
+		return "", err
+	}
+	if format != ext {
+		return "", fmt.Errorf("file content is %s but the filename has extension .%s", format, ext)
+	}
+
+	if format == FormatDOCX && templateType == CourseTemplateType {
+		if err := CheckCourseDOCX(data); err != nil {
+			return "", err
+		}
+	}
+	return format, nil
+}
+
+// CheckCourseDOCX rejects a {{placeholder}} template that uses placeholders
+// or loops not provided by CourseData. Templates without any {{placeholder}}
+// are left to the Moodle plugin and pass unchecked.
+func CheckCourseDOCX(tpl []byte) error {
+	if !IsNativeDOCX(tpl) {
+		return nil
+	}
+	unknown, err := docxtpl.Unresolved(tpl, CourseData(sampleCourse(), sampleAverages(), time.Now()))
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown template placeholders: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// detectFormat identifies an OOXML package by its main part.
+func detectFormat(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", errors.New("file is not an Office Open XML document")
+	}
+
+	parts := make(map[string]bool, len(zr.File))
+	for _, f := range zr.File {
+		parts[f.Name] = true
+	}
+	switch {
+	case !parts["[Content_Types].xml"]:
+		return "", errors.New("file is not an Office Open XML document")
+	case parts["word/document.xml"]:
+		return FormatDOCX, nil
+	case parts["xl/workbook.xml"]:
+		return FormatXLSX, nil
+	}
+	return "", errors.New("file is neither a Word document nor an Excel workbook")
+}
+
+// sampleCourse is a course with one module of every exam type and one graded
+// student, used to check which placeholders resolve.
+func sampleCourse() *mdlapi.GetCourseGradesResponse {
+	details := &mdlapi.GetCourseGradesResponse{}
+	username := "student"
+	student := mdlapi.Student{ID: 1, Fullname: "Student", Username: &username}
+
+	for i, et := range examTypeOrder {
+		examType := et
+		details.Modules = append(details.Modules, mdlapi.Module{
+			Cmid:     i + 1,
+			Name:     et.String(),
+			Grademax: 10,
+			ExamType: &examType,
+		})
+		student.Grades = append(student.Grades, mdlapi.Grade{ModuleID: i + 1, Grade: 10})
+	}
+	details.Students = []mdlapi.Student{student}
+	return details
+}
+
+func sampleAverages() []entities.StudentAverage {
+	average := 10.0
+	return []entities.StudentAverage{{StudentID: 1, Average: &average, Complete: true, Passed: true, Rank: 1}}
+}
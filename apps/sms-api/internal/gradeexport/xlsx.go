@@ -0,0 +1,233 @@
+package gradeexport
+
+import (
+	"bytes"
+	"fmt"
+
+	"encore.app/internal/entities"
+	"encore.app/internal/gradecalc"
+	"encore.app/internal/mdlapi"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	gradebookSheet = "Gradebook"
+	// headerRows is the number of rows above the first student: the course
+	// title, the exam type groups and the module names.
+	headerRows = 3
+	// studentColumns is the number of columns before the first grade column.
+	studentColumns = 3
+)
+
+// BuildXLSX renders the gradebook of one course: one column per module grouped
+// by exam type, followed by the weighted average, result and rank of each
+// student. The header rows and student columns are frozen.
+func BuildXLSX(
+	details *mdlapi.GetCourseGradesResponse,
+	averages []entities.StudentAverage,
+) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", gradebookSheet); err != nil {
+		return nil, err
+	}
+
+	styles, err := newXLSXStyles(f)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := GroupModules(details.Modules)
+	gradeColumns := 0
+	for _, g := range groups {
+		gradeColumns += len(g.Modules)
+	}
+	summaryCol := studentColumns + gradeColumns + 1
+	lastCol := summaryCol + 2
+
+	w := &sheetWriter{f: f, sheet: gradebookSheet}
+
+	// Row 1: course title across the whole table.
+	w.set(1, 1, details.Course.Fullname)
+	w.merge(1, 1, lastCol, 1)
+	w.style(1, 1, lastCol, 1, styles.title)
+
+	// Rows 2-3: student columns and summary columns span both header rows,
+	// grade columns have their exam type on row 2 and module name on row 3.
+	for i, label := range []string{"No.", "Student", "Email"} {
+		w.set(i+1, 2, label)
+		w.merge(i+1, 2, i+1, 3)
+	}
+	col := studentColumns + 1
+	for _, g := range groups {
+		w.set(col, 2, g.Label)
+		w.merge(col, 2, col+len(g.Modules)-1, 2)
+		for _, m := range g.Modules {
+			w.set(col, 3, m.Name)
+			col++
+		}
+	}
+	for i, label := range []string{"Average", "Result", "Rank"} {
+		w.set(summaryCol+i, 2, label)
+		w.merge(summaryCol+i, 2, summaryCol+i, 3)
+	}
+	w.style(1, 2, lastCol, headerRows, styles.header)
+
+	// Student rows.
+	for i := range details.Students {
+		s := &details.Students[i]
+		row := headerRows + 1 + i
+
+		w.set(1, row, i+1)
+		w.set(2, row, s.Fullname)
+		w.set(3, row, s.Email)
+
+		col := studentColumns + 1
+		for _, g := range groups {
+			for _, m := range g.Modules {
+				if grade := s.FindGrade(m.Cmid, m.ItemNumber); grade != nil {
+					w.set(col, row, grade.Grade)
+				}
+				col++
+			}
+		}
+
+		if a := gradecalc.FindStudent(averages, s.ID); a != nil && a.Average != nil {
+			w.set(summaryCol, row, *a.Average)
+			w.set(summaryCol+1, row, resultLabel(a))
+			w.set(summaryCol+2, row, a.Rank)
+		}
+	}
+	if len(details.Students) > 0 {
+		lastRow := headerRows + len(details.Students)
+		w.style(1, headerRows+1, lastCol, lastRow, styles.cell)
+		w.style(studentColumns+1, headerRows+1, summaryCol, lastRow, styles.grade)
+	}
+
+	w.width(1, 1, 6)
+	w.width(2, 2, 30)
+	w.width(3, 3, 28)
+	if gradeColumns > 0 {
+		w.width(studentColumns+1, studentColumns+gradeColumns, 12)
+	}
+	w.width(summaryCol, lastCol, 10)
+
+	if w.err != nil {
+		return nil, w.err
+	}
+	if err := f.SetPanes(gradebookSheet, &excelize.Panes{
+		Freeze:      true,
+		XSplit:      studentColumns,
+		YSplit:      headerRows,
+		TopLeftCell: cellName(studentColumns+1, headerRows+1),
+		ActivePane:  "bottomRight",
+	}); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func resultLabel(a *entities.StudentAverage) string {
+	switch {
+	case !a.Complete:
+		return "Incomplete"
+	case a.Passed:
+		return "Passed"
+	default:
+		return "Failed"
+	}
+}
+
+type xlsxStyles struct {
+	title, header, cell, grade int
+}
+
+func newXLSXStyles(f *excelize.File) (*xlsxStyles, error) {
+	border := []excelize.Border{
+		{Type: "left", Color: "000000", Style: 1},
+		{Type: "top", Color: "000000", Style: 1},
+		{Type: "right", Color: "000000", Style: 1},
+		{Type: "bottom", Color: "000000", Style: 1},
+	}
+	center := &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true}
+
+	var (
+		s   xlsxStyles
+		err error
+	)
+	if s.title, err = f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 14},
+		Alignment: center,
+	}); err != nil {
+		return nil, err
+	}
+	if s.header, err = f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}},
+		Border:    border,
+		Alignment: center,
+	}); err != nil {
+		return nil, err
+	}
+	if s.cell, err = f.NewStyle(&excelize.Style{Border: border}); err != nil {
+		return nil, err
+	}
+	decimals := "0.00"
+	if s.grade, err = f.NewStyle(&excelize.Style{
+		Border:       border,
+		Alignment:    &excelize.Alignment{Horizontal: "center"},
+		CustomNumFmt: &decimals,
+	}); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// sheetWriter keeps the first error of a series of cell operations so the
+// layout code above can stay linear.
+type sheetWriter struct {
+	f     *excelize.File
+	sheet string
+	err   error
+}
+
+func (w *sheetWriter) set(col, row int, value any) {
+	if w.err == nil {
+		w.err = w.f.SetCellValue(w.sheet, cellName(col, row), value)
+	}
+}
+
+func (w *sheetWriter) merge(col1, row1, col2, row2 int) {
+	if w.err == nil && (col1 != col2 || row1 != row2) {
+		w.err = w.f.MergeCell(w.sheet, cellName(col1, row1), cellName(col2, row2))
+	}
+}
+
+func (w *sheetWriter) style(col1, row1, col2, row2, style int) {
+	if w.err == nil {
+		w.err = w.f.SetCellStyle(w.sheet, cellName(col1, row1), cellName(col2, row2), style)
+	}
+}
+
+func (w *sheetWriter) width(col1, col2 int, width float64) {
+	if w.err != nil {
+		return
+	}
+	start, _ := excelize.ColumnNumberToName(col1)
+	end, _ := excelize.ColumnNumberToName(col2)
+	w.err = w.f.SetColWidth(w.sheet, start, end, width)
+}
+
+func cellName(col, row int) string {
+	name, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		panic(fmt.Sprintf("gradeexport: invalid cell %d,%d: %v", col, row, err))
+	}
+	return name
+}
@@ -0,0 +1,48 @@
+package gradehistory
+
+import "time"
+
+// ActorRoleMoodle marks changes made in Moodle itself rather than through the
+// SMS. Their ActorID is the Moodle user who made them.
+const ActorRoleMoodle = "moodle"
+
+// Entry is one recorded change of a single student's grade on one activity.
+// OldGrade is nil when the student had no grade before the change. EventID is
+// the Moodle event a change made in Moodle was recorded from; each event is
+// recorded once, however often it is delivered.
+type Entry struct {
+	ID         int64     `json:"id"                 db:"id"`
+	CourseID   int64     `json:"course_id"          db:"course_id"`
+	ActivityID int64     `json:"activity_id"        db:"activity_id"`
+	ItemNumber int       `json:"item_number"        db:"item_number"`
+	StudentID  int64     `json:"student_id"         db:"student_id"`
+	OldGrade   *float64  `json:"old_grade"          db:"old_grade"`
+	NewGrade   float64   `json:"new_grade"          db:"new_grade"`
+	ActorID    int64     `json:"actor_id"           db:"actor_id"`
+	ActorRole  string    `json:"actor_role"         db:"actor_role"`
+	EventID    string    `json:"event_id,omitempty" db:"event_id"`
+	ChangedAt  time.Time `json:"changed_at"         db:"changed_at"`
+}
+
+// ListRequest is the query shape for GET /courses/:id/grades/history.
+// The course ID comes from the path; the other filters are optional.
+type ListRequest struct {
+	Page  int `json:"page"  query:"page"`
+	Limit int `json:"limit" query:"limit"`
+
+	// Repeat a filter to match any of its values. Use slices so Encore can
+	// serialise an absent value as nil.
+	StudentID  []int64 `json:"student_id,omitempty"  query:"student_id"`
+	ActivityID []int64 `json:"activity_id,omitempty" query:"activity_id"`
+
+	From time.Time `json:"from,omitempty" query:"from"`
+	To   time.Time `json:"to,omitempty"   query:"to"`
+}
+
+type ListResponse struct {
+	Data       []Entry `json:"data"`
+	Total      int64   `json:"total"`
+	Page       int     `json:"page"`
+	Limit      int     `json:"limit"`
+	TotalPages int     `json:"total_pages"`
+}
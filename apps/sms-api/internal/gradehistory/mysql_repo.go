@@ -0,0 +1,185 @@
+package gradehistory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+)
+
+const table = "sms_grade_history"
+
+// insertSQL turns a duplicate event_id into a no-op. INSERT IGNORE would also
+// downgrade every other error to a warning.
+var insertSQL = "INSERT INTO " + table + " (course_id, activity_id, item_number, student_id," +
+	" old_grade, new_grade, actor_id, actor_role, event_id, changed_at)" +
+	" VALUES ({:course_id}, {:activity_id}, {:item_number}, {:student_id}," +
+	" {:old_grade}, {:new_grade}, {:actor_id}, {:actor_role}, {:event_id}, {:changed_at})" +
+	" ON DUPLICATE KEY UPDATE id = id"
+
+type mysqlRepository struct {
+	db *dbx.DB
+}
+
+var _ Repository = (*mysqlRepository)(nil)
+
+// NewMySQLRepository returns a Repository backed by the provided dbx connection.
+func NewMySQLRepository(db *dbx.DB) Repository {
+	return &mysqlRepository{db: db}
+}
+
+func (r *mysqlRepository) SaveAll(ctx context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Transactional(func(tx *dbx.Tx) error {
+		for _, e := range entries {
+			var oldVal interface{}
+			if e.OldGrade != nil {
+				oldVal = *e.OldGrade
+			}
+
+			var eventID interface{}
+			if e.EventID != "" {
+				eventID = e.EventID
+			}
+
+			// A redelivered Moodle event hits the unique event_id and is
+			// skipped.
+			_, err := tx.NewQuery(insertSQL).Bind(dbx.Params{
+				"course_id":   e.CourseID,
+				"activity_id": e.ActivityID,
+				"item_number": e.ItemNumber,
+				"student_id":  e.StudentID,
+				"old_grade":   oldVal,
+				"new_grade":   e.NewGrade,
+				"actor_id":    e.ActorID,
+				"actor_role":  e.ActorRole,
+				"event_id":    eventID,
+				"changed_at":  e.ChangedAt.UTC().Format("2006-01-02 15:04:05.000"),
+			}).Execute()
+			if err != nil {
+				return fmt.Errorf("gradehistory: insert: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *mysqlRepository) List(
+	ctx context.Context,
+	courseID int64,
+	req *ListRequest,
+) (*ListResponse, error) {
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	limit := req.Limit
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	parts := []string{"course_id = {:course}"}
+	params := dbx.Params{"course": courseID}
+	if len(req.StudentID) > 0 {
+		parts = append(parts, dbx.In("student_id", anys(req.StudentID)...).Build(r.db, params))
+	}
+	if len(req.ActivityID) > 0 {
+		parts = append(parts, dbx.In("activity_id", anys(req.ActivityID)...).Build(r.db, params))
+	}
+	if !req.From.IsZero() {
+		parts = append(parts, "changed_at >= {:from}")
+		params["from"] = req.From.UTC().Format("2006-01-02 15:04:05.000")
+	}
+	if !req.To.IsZero() {
+		parts = append(parts, "changed_at <= {:to}")
+		params["to"] = req.To.UTC().Format("2006-01-02 15:04:05.000")
+	}
+	where := "WHERE " + strings.Join(parts, " AND ")
+
+	var total int64
+	if err := r.db.WithContext(ctx).
+		NewQuery(fmt.Sprintf("SELECT COUNT(*) FROM %s %s", table, where)).
+		Bind(params).
+		Row(&total); err != nil {
+		return nil, fmt.Errorf("gradehistory: count: %w", err)
+	}
+
+	selectSQL := fmt.Sprintf(
+		"SELECT id, course_id, activity_id, item_number, student_id, old_grade, new_grade,"+
+			" actor_id, actor_role, event_id, changed_at"+
+			" FROM %s %s ORDER BY changed_at DESC, id DESC LIMIT %d OFFSET %d",
+		table, where, limit, (page-1)*limit,
+	)
+
+	rows := []dbxEntry{}
+	if err := r.db.WithContext(ctx).
+		NewQuery(selectSQL).
+		Bind(params).
+		All(&rows); err != nil {
+		return nil, fmt.Errorf("gradehistory: select: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, row.toEntry())
+	}
+
+	return &ListResponse{
+		Data:       entries,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+	}, nil
+}
+
+func anys(ids []int64) []interface{} {
+	in := make([]interface{}, len(ids))
+	for i, id := range ids {
+		in[i] = id
+	}
+	return in
+}
+
+// ── scan type ─────────────────────────────────────────────────────────────────
+
+type dbxEntry struct {
+	ID         int64           `db:"id"`
+	CourseID   int64           `db:"course_id"`
+	ActivityID int64           `db:"activity_id"`
+	ItemNumber int             `db:"item_number"`
+	StudentID  int64           `db:"student_id"`
+	OldGrade   sql.NullFloat64 `db:"old_grade"`
+	NewGrade   float64         `db:"new_grade"`
+	ActorID    int64           `db:"actor_id"`
+	ActorRole  string          `db:"actor_role"`
+	EventID    sql.NullString  `db:"event_id"`
+	ChangedAt  time.Time       `db:"changed_at"`
+}
+
+func (e *dbxEntry) toEntry() Entry {
+	entry := Entry{
+		ID:         e.ID,
+		CourseID:   e.CourseID,
+		ActivityID: e.ActivityID,
+		ItemNumber: e.ItemNumber,
+		StudentID:  e.StudentID,
+		NewGrade:   e.NewGrade,
+		ActorID:    e.ActorID,
+		ActorRole:  e.ActorRole,
+		EventID:    e.EventID.String,
+		ChangedAt:  e.ChangedAt,
+	}
+	if e.OldGrade.Valid {
+		old := e.OldGrade.Float64
+		entry.OldGrade = &old
+	}
+	return entry
+}
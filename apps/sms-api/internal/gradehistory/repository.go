@@ -0,0 +1,13 @@
+package gradehistory
+
+import "context"
+
+// Repository is the persistence contract for grade history entries.
+type Repository interface {
+	// SaveAll persists the entries of one grade update atomically.
+	SaveAll(ctx context.Context, entries []Entry) error
+
+	// List returns a paginated, filtered slice of a course's history,
+	// newest first.
+	List(ctx context.Context, courseID int64, req *ListRequest) (*ListResponse, error)
+}
@@ -0,0 +1,52 @@
+package gradelocks
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a lock does not exist or is no longer active.
+var ErrNotFound = errors.New("grade lock not found")
+
+// ConflictError is returned by Create when an active lock of the course
+// already covers the exam type of the new one.
+type ConflictError struct {
+	// ExamType is the scope of the covering lock, empty for the whole course.
+	ExamType string
+}
+
+func (e *ConflictError) Error() string {
+	if e.ExamType == "" {
+		return "grade lock conflict: course is locked"
+	}
+	return "grade lock conflict: " + e.ExamType + " is locked"
+}
+
+// Lock freezes the grades of a course, or of a single exam type within it.
+// An empty ExamType locks every activity of the course.
+type Lock struct {
+	ID           int64      `json:"id"            db:"id"`
+	CourseID     int64      `json:"course_id"     db:"course_id"`
+	ExamType     string     `json:"exam_type"     db:"exam_type"`
+	Reason       string     `json:"reason"        db:"reason"`
+	LockedBy     int64      `json:"locked_by"     db:"locked_by"`
+	LockedAt     time.Time  `json:"locked_at"     db:"locked_at"`
+	UnlockedBy   *int64     `json:"unlocked_by"   db:"unlocked_by"`
+	UnlockedAt   *time.Time `json:"unlocked_at"   db:"unlocked_at"`
+	UnlockReason *string    `json:"unlock_reason" db:"unlock_reason"`
+}
+
+// Active reports whether the lock has not been lifted yet.
+func (l *Lock) Active() bool { return l.UnlockedAt == nil }
+
+// Covers reports whether the lock applies to an activity of the given exam
+// type. An empty examType (activity without exam type) is only covered by a
+// course-wide lock.
+func (l *Lock) Covers(examType string) bool {
+	return l.Active() && (l.ExamType == "" || l.ExamType == examType)
+}
+
+// ListResponse is returned by GET /courses/:id/locks.
+type ListResponse struct {
+	Data []Lock `json:"data"`
+}
@@ -0,0 +1,206 @@
+package gradelocks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pocketbase/dbx"
+)
+
+const table = "sms_grade_locks"
+
+// MySQL error numbers Create handles.
+const (
+	errDuplicateEntry = 1062
+	errDeadlock       = 1213
+)
+
+// maxCreateAttempts bounds the retries of a Create that lost a deadlock.
+const maxCreateAttempts = 3
+
+const selectColumns = "id, course_id, exam_type, reason, locked_by, locked_at," +
+	" unlocked_by, unlocked_at, unlock_reason"
+
+type mysqlRepository struct {
+	db *dbx.DB
+}
+
+var _ Repository = (*mysqlRepository)(nil)
+
+// NewMySQLRepository returns a Repository backed by the provided dbx connection.
+func NewMySQLRepository(db *dbx.DB) Repository {
+	return &mysqlRepository{db: db}
+}
+
+func (r *mysqlRepository) Create(ctx context.Context, lock *Lock) error {
+	var err error
+	for range maxCreateAttempts {
+		err = r.db.WithContext(ctx).Transactional(func(tx *dbx.Tx) error {
+			return create(tx, lock)
+		})
+		// Two locks of a course with no active lock yet deadlock on the gap
+		// their FOR UPDATE reads lock; the one rolled back tries again.
+		if !isMySQLError(err, errDeadlock) {
+			break
+		}
+	}
+	return err
+}
+
+// create checks the active locks of the course and stores the new one while
+// holding them, so that two requests cannot both pass the check.
+func create(tx *dbx.Tx, lock *Lock) error {
+	rows := []dbxLock{}
+	err := tx.NewQuery(fmt.Sprintf(
+		"SELECT %s FROM %s WHERE course_id = {:course} AND unlocked_at IS NULL FOR UPDATE",
+		selectColumns, table)).
+		Bind(dbx.Params{"course": lock.CourseID}).
+		All(&rows)
+	if err != nil {
+		return fmt.Errorf("gradelocks: select: %w", err)
+	}
+	for _, row := range rows {
+		if l := row.toLock(); l.Covers(lock.ExamType) {
+			return &ConflictError{ExamType: l.ExamType}
+		}
+	}
+
+	res, err := tx.Insert(table, dbx.Params{
+		"course_id": lock.CourseID,
+		"exam_type": lock.ExamType,
+		"reason":    lock.Reason,
+		"locked_by": lock.LockedBy,
+		"locked_at": lock.LockedAt.UTC().Format("2006-01-02 15:04:05.000"),
+	}).Execute()
+	// uq_active_key holds one active lock per course and exam type.
+	if isMySQLError(err, errDuplicateEntry) {
+		return &ConflictError{ExamType: lock.ExamType}
+	}
+	if err != nil {
+		return fmt.Errorf("gradelocks: insert: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("gradelocks: last insert id: %w", err)
+	}
+	lock.ID = id
+	return nil
+}
+
+func (r *mysqlRepository) FindActive(ctx context.Context, courseID int64) ([]Lock, error) {
+	return r.find(ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE course_id = {:course} AND unlocked_at IS NULL"+
+			" ORDER BY locked_at DESC", selectColumns, table),
+		courseID,
+	)
+}
+
+func (r *mysqlRepository) FindAll(ctx context.Context, courseID int64) ([]Lock, error) {
+	return r.find(ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE course_id = {:course} ORDER BY locked_at DESC",
+			selectColumns, table),
+		courseID,
+	)
+}
+
+func (r *mysqlRepository) Unlock(
+	ctx context.Context,
+	courseID, lockID, actorID int64,
+	reason string,
+) (*Lock, error) {
+	res, err := r.db.WithContext(ctx).
+		NewQuery(fmt.Sprintf(
+			"UPDATE %s SET unlocked_by = {:actor}, unlocked_at = {:at}, unlock_reason = {:reason}"+
+				" WHERE id = {:id} AND course_id = {:course} AND unlocked_at IS NULL", table)).
+		Bind(dbx.Params{
+			"actor":  actorID,
+			"at":     time.Now().UTC().Format("2006-01-02 15:04:05.000"),
+			"reason": reason,
+			"id":     lockID,
+			"course": courseID,
+		}).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("gradelocks: unlock: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+
+	row := dbxLock{}
+	err = r.db.WithContext(ctx).
+		NewQuery(fmt.Sprintf("SELECT %s FROM %s WHERE id = {:id}", selectColumns, table)).
+		Bind(dbx.Params{"id": lockID}).
+		One(&row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gradelocks: select: %w", err)
+	}
+	lock := row.toLock()
+	return &lock, nil
+}
+
+func (r *mysqlRepository) find(ctx context.Context, query string, courseID int64) ([]Lock, error) {
+	rows := []dbxLock{}
+	if err := r.db.WithContext(ctx).
+		NewQuery(query).
+		Bind(dbx.Params{"course": courseID}).
+		All(&rows); err != nil {
+		return nil, fmt.Errorf("gradelocks: select: %w", err)
+	}
+
+	locks := make([]Lock, 0, len(rows))
+	for _, row := range rows {
+		locks = append(locks, row.toLock())
+	}
+	return locks, nil
+}
+
+func isMySQLError(err error, number uint16) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == number
+}
+
+// ── scan type ─────────────────────────────────────────────────────────────────
+
+type dbxLock struct {
+	ID           int64          `db:"id"`
+	CourseID     int64          `db:"course_id"`
+	ExamType     string         `db:"exam_type"`
+	Reason       string         `db:"reason"`
+	LockedBy     int64          `db:"locked_by"`
+	LockedAt     time.Time      `db:"locked_at"`
+	UnlockedBy   sql.NullInt64  `db:"unlocked_by"`
+	UnlockedAt   sql.NullTime   `db:"unlocked_at"`
+	UnlockReason sql.NullString `db:"unlock_reason"`
+}
+
+func (l *dbxLock) toLock() Lock {
+	lock := Lock{
+		ID:       l.ID,
+		CourseID: l.CourseID,
+		ExamType: l.ExamType,
+		Reason:   l.Reason,
+		LockedBy: l.LockedBy,
+		LockedAt: l.LockedAt,
+	}
+	if l.UnlockedBy.Valid {
+		v := l.UnlockedBy.Int64
+		lock.UnlockedBy = &v
+	}
+	if l.UnlockedAt.Valid {
+		v := l.UnlockedAt.Time
+		lock.UnlockedAt = &v
+	}
+	if l.UnlockReason.Valid {
+		v := l.UnlockReason.String
+		lock.UnlockReason = &v
+	}
+	return lock
+}
@@ -0,0 +1,20 @@
+package gradelocks
+
+import "context"
+
+// Repository is the persistence contract for grade locks.
+type Repository interface {
+	// Create stores a new active lock and fills in its ID. It returns a
+	// *ConflictError when an active lock of the course covers its exam type.
+	Create(ctx context.Context, lock *Lock) error
+
+	// FindActive returns the active locks of a course.
+	FindActive(ctx context.Context, courseID int64) ([]Lock, error)
+
+	// FindAll returns every lock of a course, active and lifted, newest first.
+	FindAll(ctx context.Context, courseID int64) ([]Lock, error)
+
+	// Unlock lifts an active lock. Returns ErrNotFound when the lock does not
+	// belong to the course or has already been lifted.
+	Unlock(ctx context.Context, courseID, lockID, actorID int64, reason string) (*Lock, error)
+}
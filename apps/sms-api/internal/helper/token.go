@@ -0,0 +1,13 @@
+package helper
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
+// RandomToken returns 32 random bytes encoded for use in URLs.
+func RandomToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
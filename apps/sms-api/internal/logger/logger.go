@@ -26,7 +26,15 @@ func SetGlobalLogger(loggerType LoggerType, options ...Option) {
 		env = "dev"
 	}
 
-	var logger Logger = initRlogAndOtelLogger(env, options...)
+	var logger Logger
+	// rlog and the Encore trace fields need the Encore runtime, which
+	// `go test` does not provide; run plain package tests with
+	// LOG_FORMAT=text.
+	if os.Getenv("LOG_FORMAT") == "text" {
+		logger = initTextLogger(options...)
+	} else {
+		logger = initRlogAndOtelLogger(env, options...)
+	}
 	switch loggerType {
 	/* case File:
 		logger, err = NewFileLogger("app.log")
@@ -60,6 +68,11 @@ func initRlogLogger(env string, options ...Option) *SlogLogger {
 	return NewSlogLoggerWithRlog("sms-api", allOptions...)
 }
 
+func initTextLogger(options ...Option) *SlogLogger {
+	cfg := newConfig(append([]Option{WithLevel(slog.LevelDebug)}, options...))
+	return &SlogLogger{logger: slog.New(slog.NewTextHandler(os.Stderr, cfg.HandlerOptions))}
+}
+
 func initDefaultLogger(env string, options ...Option) *SlogLogger {
 	logLevel := slog.LevelDebug
 	if env == "prod" {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 
+	"encore.app/internal/entities"
 	"encore.app/internal/logger"
 )
 
@@ -84,6 +85,8 @@ type GetCourseGradesResponse struct {
 	} `json:"course"`
 	Modules  []Module  `json:"modules"`
 	Students []Student `json:"students"`
+	// Averages is computed by the SMS, never sent by Moodle.
+	Averages []entities.StudentAverage `json:"averages,omitempty"`
 }
 
 type Module struct {
@@ -118,6 +121,43 @@ type Grade struct {
 	ModuleName   string    `json:"modulename"`
 }
 
+// FindModule returns the graded module matching a course-module id and grade
+// item number, or nil when the course has no such activity.
+func (r *GetCourseGradesResponse) FindModule(cmid, itemNumber int) *Module {
+	for i := range r.Modules {
+		if r.Modules[i].Cmid == cmid && r.Modules[i].ItemNumber == itemNumber {
+			return &r.Modules[i]
+		}
+	}
+	return nil
+}
+
+// FindStudent returns the enrolled student with the given user id, or nil.
+func (r *GetCourseGradesResponse) FindStudent(id int) *Student {
+	for i := range r.Students {
+		if r.Students[i].ID == id {
+			return &r.Students[i]
+		}
+	}
+	return nil
+}
+
+// FindGrade returns the student's grade for a course-module id and grade item
+// number, or nil when no grade has been recorded yet.
+func (s *Student) FindGrade(cmid, itemNumber int) *Grade {
+	for i := range s.Grades {
+		if s.Grades[i].ModuleID == cmid && s.Grades[i].ItemNumber == itemNumber {
+			return &s.Grades[i]
+		}
+	}
+	return nil
+}
+
+// InRange reports whether grade lies within the module's [Grademin, Grademax].
+func (m *Module) InRange(grade float64) bool {
+	return grade >= m.Grademin && grade <= m.Grademax
+}
+
 type LocalCourseGrades interface {
 	GetCourseDetails(context.Context, *GetCourseGradesRequest) (*GetCourseGradesResponse, error)
 }
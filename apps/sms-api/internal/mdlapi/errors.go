@@ -0,0 +1,67 @@
+package mdlapi
+
+import (
+	"errors"
+	"fmt"
+
+	"encore.app/internal/breaker"
+	"encore.dev/beta/errs"
+)
+
+// ErrUnavailable is returned without calling Moodle while the circuit breaker
+// is open, after Moodle failed repeatedly.
+var ErrUnavailable = fmt.Errorf("moodle unavailable: %w", breaker.ErrOpen)
+
+// MoodleError is an exception raised by a Moodle web service function. Moodle
+// reports them in the response body, often with status 200.
+type MoodleError struct {
+	Function  string `json:"-"`
+	Exception string `json:"exception"`
+	ErrorCode string `json:"errorcode"`
+	Message   string `json:"message"`
+	DebugInfo string `json:"debuginfo,omitempty"`
+}
+
+func (e *MoodleError) Error() string {
+	return fmt.Sprintf("moodle %s: %s: %s", e.Function, e.ErrorCode, e.Message)
+}
+
+// ErrCode maps the Moodle error code to the code of our API. Codes that do
+// not concern the caller, such as an invalid web service token, are internal.
+func (e *MoodleError) ErrCode() errs.ErrCode {
+	switch e.ErrorCode {
+	case "invalidparameter", "invalidresponse", "missingparam":
+		return errs.InvalidArgument
+	case "invalidrecord", "invalidrecordunknown", "invalidcourseid", "invaliduser",
+		"coursenotfound", "usernotfound", "templatenotfound":
+		return errs.NotFound
+	case "nopermissions", "accessexception", "requireloginerror", "notingroup":
+		return errs.PermissionDenied
+	case "servicenotavailable", "sitemaintenance":
+		return errs.Unavailable
+	default:
+		return errs.Internal
+	}
+}
+
+// ToAPIError turns Moodle failures into API errors: exceptions by their error
+// code and an open circuit breaker as Unavailable. Other errors are returned
+// unchanged.
+func ToAPIError(err error) error {
+	var moodleErr *MoodleError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &moodleErr):
+		code := moodleErr.ErrCode()
+		msg := moodleErr.Message
+		if code == errs.Internal {
+			msg = errs.Internal.String()
+		}
+		return errs.WrapCode(err, code, msg)
+	case errors.Is(err, ErrUnavailable):
+		return errs.WrapCode(err, errs.Unavailable, "Moodle is unavailable, please try again later")
+	default:
+		return err
+	}
+}
@@ -61,6 +61,19 @@ type DeleteTemplateResponse struct {
 	Success bool `json:"success"`
 }
 
+// GetTemplateContentRequest identifies a template whose file is downloaded.
+type GetTemplateContentRequest struct {
+	Type       string `json:"type"`
+	TemplateID string `json:"templateid"`
+}
+
+// GetTemplateContentResponse carries the stored template file.
+type GetTemplateContentResponse struct {
+	Name     string `json:"name"`
+	Format   string `json:"format"`
+	Filedata string `json:"filedata"` // base64
+}
+
 // ExportProvider abstracts all export-related Moodle API calls.
 type ExportProvider interface {
 	GetCourseTemplates(context.Context, *GetCourseTemplatesRequest) (*GetCourseTemplatesResponse, error)
@@ -68,4 +81,5 @@ type ExportProvider interface {
 	GetAllTemplates(context.Context, *GetAllTemplatesRequest) (*GetCourseTemplatesResponse, error)
 	UploadTemplate(context.Context, *UploadTemplateRequest) (*UploadTemplateResponse, error)
 	DeleteTemplate(context.Context, *DeleteTemplateRequest) (*DeleteTemplateResponse, error)
+	GetTemplateContent(context.Context, *GetTemplateContentRequest) (*GetTemplateContentResponse, error)
 }
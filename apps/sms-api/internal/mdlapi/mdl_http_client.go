@@ -4,49 +4,104 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"time"
 
+	"encore.app/internal/breaker"
 	"encore.app/internal/config"
 	"encore.app/internal/logger"
 )
 
+// functionTimeouts gives slow functions more time than the configured default.
+var functionTimeouts = map[string]time.Duration{
+	EXPORT_COURSE_GRADES: 60 * time.Second,
+	GET_TEMPLATE_CONTENT: 30 * time.Second,
+	UPLOAD_TEMPLATE:      30 * time.Second,
+}
+
+// readFunctions change nothing in Moodle, so a failed call can be retried.
+// Writes are never retried: Moodle may have applied them before failing.
+// Exports are left out too: a retried export renders the whole gradebook again
+// and can hold the request for several times its long timeout.
+var readFunctions = map[string]bool{
+	GET_ENROLLED_USERS:        true,
+	GET_USER_GRADE_ITEMS:      true,
+	GET_CUSTOM_COURSE_DETAILS: true,
+	GET_STUDENT_GRADES:        true,
+	GET_CATEGORIES:            true,
+	GET_CATEGORY_COURSES:      true,
+	GET_USER_INFO:             true,
+	GET_ALL_CATEGORIES:        true,
+	GET_ALL_CATEGORY_COURSES:  true,
+	GET_COURSE_TEMPLATES:      true,
+	GET_ALL_TEMPLATES:         true,
+	GET_TEMPLATE_CONTENT:      true,
+}
+
+const (
+	defaultTimeout = 10 * time.Second
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 2 * time.Second
+)
+
+type timeoutKey struct{}
+
+// WithTimeout returns a context whose Moodle calls may take up to d instead of
+// the function's usual timeout. Background jobs use it for slow functions
+// such as exports.
+func WithTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
 type moodleHttpClient struct {
-	client  *http.Client
-	baseURL string
-	token   string
+	client     *http.Client
+	breaker    *breaker.Breaker
+	baseURL    string
+	token      string
+	timeout    time.Duration
+	maxRetries int
 }
 
 var _ MoodleApi = (*moodleHttpClient)(nil)
 
 func New(cfg *config.MoodleApiConfig) *moodleHttpClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Every call goes to the same host; keep enough connections open for the
+	// concurrent requests of a busy grading period.
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 32
+	transport.IdleConnTimeout = 90 * time.Second
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
 	return &moodleHttpClient{
-		client:  &http.Client{Timeout: 10 * time.Second},
-		baseURL: cfg.Url,
-		token:   cfg.ApiToken,
+		client:     &http.Client{Transport: transport},
+		breaker:    breaker.New(cfg.BreakerThreshold, time.Duration(cfg.BreakerCooldown)*time.Second),
+		baseURL:    cfg.Url,
+		token:      cfg.ApiToken,
+		timeout:    timeout,
+		maxRetries: max(cfg.MaxRetries, 0),
 	}
 }
 
+// Do calls a Moodle function. Reads that fail on the network, time out or
+// get a 429 or 5xx status are retried with jittered backoff; each attempt
+// gets the full timeout. Exceptions raised by Moodle are returned as
+// *MoodleError and ErrUnavailable is returned while the circuit breaker is
+// open.
 func (m *moodleHttpClient) Do(ctx context.Context, fn string, payload any, output any) error {
-	url := fmt.Sprintf("%s/webservice/restful/server.php/%s", m.baseURL, fn)
-	client := http.Client{Timeout: 10 * time.Second}
-
-	// Encode request body
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", m.token)
 	logger.InfoContext(
 		ctx,
 		"moodleHttpClient request to moodle",
@@ -56,26 +111,124 @@ func (m *moodleHttpClient) Do(ctx context.Context, fn string, payload any, outpu
 		fn,
 	)
 
-	resp, err := client.Do(req)
+	attempts := 1
+	if readFunctions[fn] {
+		attempts += m.maxRetries
+	}
+	for attempt := 1; ; attempt++ {
+		if err := m.breaker.Allow(); err != nil {
+			logger.WarnContext(ctx, "moodleHttpClient circuit open", "function", fn)
+			return fmt.Errorf("moodle %s: %w", fn, ErrUnavailable)
+		}
+
+		status, err := m.call(ctx, fn, body, output)
+		// Moodle answered, even if with an error: neither retry nor count it
+		// against the breaker.
+		var moodleErr *MoodleError
+		reached := errors.As(err, &moodleErr) || (status != 0 && !retryableStatus(status))
+		switch {
+		case err == nil || reached:
+			m.breaker.Success()
+		case ctx.Err() != nil:
+			// Cancelled by our caller: that says nothing about Moodle.
+			m.breaker.Release()
+		default:
+			m.breaker.Failure()
+		}
+
+		if err == nil || reached || ctx.Err() != nil || attempt >= attempts {
+			return err
+		}
+
+		delay := backoff(attempt)
+		logger.WarnContext(ctx, "moodleHttpClient retrying request",
+			"err", err, "function", fn, "attempt", attempt, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// call makes a single attempt and returns the response status, or 0 when no
+// response was received.
+func (m *moodleHttpClient) call(ctx context.Context, fn string, body []byte, output any) (int, error) {
+	url := fmt.Sprintf("%s/webservice/restful/server.php/%s", m.baseURL, fn)
+	ctx, cancel := context.WithTimeout(ctx, m.timeoutFor(ctx, fn))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", m.token)
+
+	resp, err := m.client.Do(req)
 	if err != nil {
 		logger.ErrorContext(
 			ctx,
 			"moodleHttpClient request error",
 			"err",
 			err,
-			"request",
-			req,
 			"function",
 			fn,
 		)
-		return err
+		return 0, err
 	}
+	// Reading the body to the end lets the connection be reused.
 	defer resp.Body.Close()
 
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("moodle %s: read response: %w", fn, err)
+	}
+
+	// Moodle reports exceptions in the body, usually with status 200.
+	if moodleErr := decodeException(fn, b); moodleErr != nil {
+		logger.WarnContext(ctx, "moodleHttpClient moodle exception",
+			"function", fn, "errorcode", moodleErr.ErrorCode, "message", moodleErr.Message)
+		return resp.StatusCode, moodleErr
+	}
 	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("bad status: %s, body: %s", resp.Status, b)
+		return resp.StatusCode, fmt.Errorf("moodle %s: bad status: %s, body: %s", fn, resp.Status, b)
+	}
+
+	if err := json.Unmarshal(b, output); err != nil {
+		return resp.StatusCode, fmt.Errorf("moodle %s: decode response: %w", fn, err)
+	}
+	return resp.StatusCode, nil
+}
+
+func (m *moodleHttpClient) timeoutFor(ctx context.Context, fn string) time.Duration {
+	if d, ok := ctx.Value(timeoutKey{}).(time.Duration); ok && d > 0 {
+		return d
+	}
+	if d, ok := functionTimeouts[fn]; ok && d > m.timeout {
+		return d
 	}
+	return m.timeout
+}
+
+func decodeException(fn string, body []byte) *MoodleError {
+	var moodleErr MoodleError
+	if json.Unmarshal(body, &moodleErr) != nil || moodleErr.Exception == "" {
+		return nil
+	}
+	moodleErr.Function = fn
+	return &moodleErr
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
 
-	return json.NewDecoder(resp.Body).Decode(output)
+// backoff doubles the delay with every attempt, spread randomly over its
+// upper half so that clients do not retry in step.
+func backoff(attempt int) time.Duration {
+	d := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	return d/2 + rand.N(d/2)
 }
@@ -66,3 +66,14 @@ func (p *mdlApiExportProvider) DeleteTemplate(
 	}
 	return resp, nil
 }
+
+func (p *mdlApiExportProvider) GetTemplateContent(
+	ctx context.Context,
+	req *GetTemplateContentRequest,
+) (*GetTemplateContentResponse, error) {
+	resp := &GetTemplateContentResponse{}
+	if err := p.mdlApi.Do(ctx, GET_TEMPLATE_CONTENT, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
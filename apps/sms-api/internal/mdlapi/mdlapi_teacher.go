@@ -21,6 +21,7 @@ func (p *mdlApiTeacherProvider) GetCategories(
 	if err := p.mdlApi.Do(ctx, GET_CATEGORIES, req, resp); err != nil {
 		return nil, err
 	}
+	resp.Source = SourceWebService
 	return resp, nil
 }
 
@@ -33,6 +34,7 @@ func (p *mdlApiTeacherProvider) GetCategoryCourses(
 	if err := p.mdlApi.Do(ctx, GET_CATEGORY_COURSES, req, resp); err != nil {
 		return nil, err
 	}
+	resp.Source = SourceWebService
 	return resp, nil
 }
 
@@ -45,6 +47,7 @@ func (p *mdlApiTeacherProvider) GetAllCategories(
 	if err := p.mdlApi.Do(ctx, GET_ALL_CATEGORIES, req, resp); err != nil {
 		return nil, err
 	}
+	resp.Source = SourceWebService
 	return resp, nil
 }
 
@@ -57,5 +60,6 @@ func (p *mdlApiTeacherProvider) GetAllCategoryCoursesForAdmin(
 	if err := p.mdlApi.Do(ctx, GET_ALL_CATEGORY_COURSES, req, resp); err != nil {
 		return nil, err
 	}
+	resp.Source = SourceWebService
 	return resp, nil
 }
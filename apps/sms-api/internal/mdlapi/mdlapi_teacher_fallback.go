@@ -0,0 +1,89 @@
+package mdlapi
+
+import (
+	"context"
+	"errors"
+
+	"encore.app/internal/logger"
+	"encore.dev/beta/errs"
+)
+
+var _ LocalTeacherProvider = (*fallbackTeacherProvider)(nil)
+
+// fallbackTeacherProvider reads from primary and, when Moodle cannot be
+// reached, from fallback. Errors Moodle answers with, such as an unknown
+// category, are returned as they are.
+type fallbackTeacherProvider struct {
+	primary  LocalTeacherProvider
+	fallback LocalTeacherProvider
+}
+
+func NewFallbackTeacherProvider(primary, fallback LocalTeacherProvider) *fallbackTeacherProvider {
+	return &fallbackTeacherProvider{primary: primary, fallback: fallback}
+}
+
+// unreachable reports whether err means that Moodle gave no answer: it is
+// down, in maintenance, or its breaker is open. Calls cancelled by the
+// caller are not retried elsewhere.
+func unreachable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var moodleErr *MoodleError
+	if errors.As(err, &moodleErr) {
+		return moodleErr.ErrCode() == errs.Unavailable
+	}
+	return true
+}
+
+// withFallback calls primary and, if Moodle is unreachable, fallback. When
+// both fail the primary error is returned, as it says why the fallback was
+// needed.
+func withFallback[Req, Resp any](
+	ctx context.Context,
+	fn string,
+	req Req,
+	primary, fallback func(context.Context, Req) (Resp, error),
+) (Resp, error) {
+	resp, err := primary(ctx, req)
+	if !unreachable(ctx, err) {
+		return resp, err
+	}
+
+	logger.WarnContext(ctx, "Moodle unreachable, reading from its database", "fn", fn, "err", err)
+	fallbackResp, fallbackErr := fallback(ctx, req)
+	if fallbackErr != nil {
+		logger.ErrorContext(ctx, "Database fallback failed", "fn", fn, "err", fallbackErr)
+		return resp, err
+	}
+	return fallbackResp, nil
+}
+
+func (p *fallbackTeacherProvider) GetCategories(
+	ctx context.Context,
+	req *GetCategoriesRequest,
+) (*GetCategoriesResponse, error) {
+	return withFallback(ctx, GET_CATEGORIES, req, p.primary.GetCategories, p.fallback.GetCategories)
+}
+
+func (p *fallbackTeacherProvider) GetCategoryCourses(
+	ctx context.Context,
+	req *GetCategoryCoursesRequest,
+) (*GetCategoryCoursesResponse, error) {
+	return withFallback(ctx, GET_CATEGORY_COURSES, req, p.primary.GetCategoryCourses, p.fallback.GetCategoryCourses)
+}
+
+func (p *fallbackTeacherProvider) GetAllCategories(
+	ctx context.Context,
+	req *GetAllCategoriesRequest,
+) (*GetCategoriesResponse, error) {
+	return withFallback(ctx, GET_ALL_CATEGORIES, req, p.primary.GetAllCategories, p.fallback.GetAllCategories)
+}
+
+func (p *fallbackTeacherProvider) GetAllCategoryCoursesForAdmin(
+	ctx context.Context,
+	req *GetCategoryCoursesRequest,
+) (*GetCategoryCoursesResponse, error) {
+	return withFallback(ctx, GET_ALL_CATEGORY_COURSES, req,
+		p.primary.GetAllCategoryCoursesForAdmin, p.fallback.GetAllCategoryCoursesForAdmin)
+}
@@ -20,4 +20,5 @@ const (
 	GET_ALL_TEMPLATES    = "local_customgradeexport_get_all_templates"
 	UPLOAD_TEMPLATE      = "local_customgradeexport_upload_template"
 	DELETE_TEMPLATE      = "local_customgradeexport_delete_template"
+	GET_TEMPLATE_CONTENT = "local_customgradeexport_get_template_content"
 )
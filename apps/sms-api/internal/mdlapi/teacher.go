@@ -6,6 +6,17 @@ import (
 	"encore.app/internal/entities"
 )
 
+// Source tells where a list of categories or courses was read from.
+type Source string
+
+const (
+	SourceWebService Source = "webservice"
+	SourceDatabase   Source = "database"
+	// SourceCache marks a response served from the SMS cache. It was read
+	// from one of the other sources a few minutes ago at most.
+	SourceCache Source = "cache"
+)
+
 type GetCategoriesRequest struct {
 	UserID int `json:"userid"`
 }
@@ -45,8 +56,12 @@ type GetCategoriesResponse struct {
 	Email           string     `json:"email"`
 	Totalcategories int        `json:"totalcategories"`
 	Categories      []Category `json:"categories"`
+	// Source is set by the provider, never sent by Moodle.
+	Source Source `json:"source,omitempty"`
 }
 
+func (r *GetCategoriesResponse) SetSource(s Source) { r.Source = s }
+
 // GetAllCategoriesRequest has no parameters.
 type GetAllCategoriesRequest struct{}
 
@@ -99,8 +114,12 @@ type GetCategoryCoursesResponse struct {
 	Email        string           `json:"email"`
 	Totalcourses int              `json:"totalcourses"`
 	Courses      []CategoryCourse `json:"courses"`
+	// Source is set by the provider, never sent by Moodle.
+	Source Source `json:"source,omitempty"`
 }
 
+func (r *GetCategoryCoursesResponse) SetSource(s Source) { r.Source = s }
+
 type LocalTeacherProvider interface {
 	GetCategories(context.Context, *GetCategoriesRequest) (*GetCategoriesResponse, error)
 	GetCategoryCourses(
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"encore.app/internal/entities"
 	"encore.app/internal/logger"
 )
 
@@ -71,6 +72,8 @@ type GetUserGradesResponse struct {
 		Metadata   []CourseMetadata `json:"metadata"`
 		Teachers   []Teacher        `json:"teachers"`
 	} `json:"courses"`
+	// Averages is computed by the SMS, never sent by Moodle.
+	Averages []entities.CourseAverage `json:"averages,omitempty"`
 }
 
 type Teacher struct {
@@ -0,0 +1,275 @@
+// Package mdlcache caches Moodle reads in Redis. Its providers decorate the
+// mdlapi providers: reads are served from Redis while fresh, concurrent misses
+// for the same entry share one Moodle call, and grade writes invalidate the
+// course they change.
+package mdlcache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"encore.app/internal/helper"
+	"encore.app/internal/logger"
+	"encore.app/internal/mdlapi"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	keyPrefix        = "mdlcache:"
+	tagPrefix        = "mdlcache:tag:"
+	generationPrefix = "mdlcache:gen:"
+	// generationKey counts flushes. Each flush stamps its tag with the new
+	// count, see load.
+	generationKey = "mdlcache:generation"
+)
+
+// ttls is how long the response of each cached Moodle function stays fresh.
+// Grades change while teachers work, so course details expire first.
+var ttls = map[string]time.Duration{
+	mdlapi.GET_CUSTOM_COURSE_DETAILS: 2 * time.Minute,
+	mdlapi.GET_CATEGORIES:            10 * time.Minute,
+	mdlapi.GET_ALL_CATEGORIES:        10 * time.Minute,
+	mdlapi.GET_CATEGORY_COURSES:      5 * time.Minute,
+	mdlapi.GET_ALL_CATEGORY_COURSES:  5 * time.Minute,
+}
+
+// tagTTL keeps a tag's index of entries alive at least as long as the
+// entries in it.
+const tagTTL = 10 * time.Minute
+
+// generationTTL keeps a tag's flush stamp longer than any Moodle read can
+// take.
+const generationTTL = time.Hour
+
+// bumpScript stamps a tag (KEYS[2]) with the next flush count (KEYS[1]) for
+// ARGV[1] seconds.
+var bumpScript = redis.NewScript(`
+local generation = redis.call('INCR', KEYS[1])
+redis.call('SET', KEYS[2], generation, 'EX', ARGV[1])
+return generation
+`)
+
+// storeScript caches a response unless one of its tags was flushed after the
+// read began. KEYS[1] is the entry, followed by a tag and its flush stamp for
+// each tag. ARGV: response, entry TTL (ms), flush count when the read began,
+// tag TTL (s). Returns 1 when stored, 0 when skipped.
+var storeScript = redis.NewScript(`
+for i = 2, #KEYS, 2 do
+	local flushed = tonumber(redis.call('GET', KEYS[i + 1]) or '0')
+	if flushed > tonumber(ARGV[3]) then
+		return 0
+	end
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+for i = 2, #KEYS, 2 do
+	redis.call('SADD', KEYS[i], KEYS[1])
+	redis.call('EXPIRE', KEYS[i], ARGV[4])
+end
+return 1
+`)
+
+// Cache stores Moodle responses in Redis. Every entry is tagged with the
+// courses and categories it shows, and the user it was read for, so that it
+// can be flushed by any of them.
+type Cache struct {
+	rdb   *redis.Client
+	group singleflight.Group
+}
+
+func New(rdb *redis.Client) *Cache {
+	return &Cache{rdb: rdb}
+}
+
+// sourced is implemented by responses that tell where they were read from.
+// Cached ones report mdlapi.SourceCache, whatever source they were read from.
+type sourced interface {
+	SetSource(mdlapi.Source)
+}
+
+type refreshKey struct{}
+
+// Refresh returns a context whose reads skip the cache and store what Moodle
+// returns. Grade writes use it, as they validate against and record the
+// current grades.
+func Refresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey{}, true)
+}
+
+func refreshing(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshKey{}).(bool)
+	return refresh
+}
+
+func courseTag(id int64) string   { return tagPrefix + "course:" + strconv.FormatInt(id, 10) }
+func categoryTag(id int64) string { return tagPrefix + "category:" + strconv.FormatInt(id, 10) }
+func userTag(id int64) string     { return tagPrefix + "user:" + strconv.FormatInt(id, 10) }
+
+func tagGeneration(tag string) string { return generationPrefix + strings.TrimPrefix(tag, tagPrefix) }
+
+// FlushCourse drops every entry showing the course and returns how many
+// were dropped.
+func (c *Cache) FlushCourse(ctx context.Context, courseID int64) (int, error) {
+	return c.flush(ctx, courseTag(courseID))
+}
+
+// FlushCategory drops every entry showing the category and returns how many
+// were dropped.
+func (c *Cache) FlushCategory(ctx context.Context, categoryID int64) (int, error) {
+	return c.flush(ctx, categoryTag(categoryID))
+}
+
+// FlushUser drops every entry read for the user, such as the categories and
+// courses they teach, and returns how many were dropped.
+func (c *Cache) FlushUser(ctx context.Context, userID int64) (int, error) {
+	return c.flush(ctx, userTag(userID))
+}
+
+// flush stamps the tag before dropping its entries, so that reads still in
+// flight when it runs do not cache what they got, see load.
+func (c *Cache) flush(ctx context.Context, tag string) (int, error) {
+	err := bumpScript.Run(ctx, c.rdb, []string{generationKey, tagGeneration(tag)},
+		int64(generationTTL.Seconds())).Err()
+	if err != nil {
+		return 0, fmt.Errorf("mdlcache: flush: %w", err)
+	}
+
+	keys, err := c.rdb.SMembers(ctx, tag).Result()
+	if err != nil {
+		return 0, fmt.Errorf("mdlcache: flush: %w", err)
+	}
+
+	var deleted *redis.IntCmd
+	_, err = c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(keys) > 0 {
+			deleted = pipe.Del(ctx, keys...)
+		}
+		pipe.Del(ctx, tag)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("mdlcache: flush: %w", err)
+	}
+	if deleted == nil {
+		return 0, nil
+	}
+	return int(deleted.Val()), nil
+}
+
+// generation returns the current flush count.
+func (c *Cache) generation(ctx context.Context) (int64, error) {
+	generation, err := c.rdb.Get(ctx, generationKey).Int64()
+	if helper.IsKeyDoesNotExistErr(err) {
+		return 0, nil
+	}
+	return generation, err
+}
+
+// store caches value under key unless one of the tags was flushed after
+// generation. It reports whether the value was stored.
+func (c *Cache) store(
+	ctx context.Context,
+	key string,
+	value []byte,
+	ttl time.Duration,
+	tags []string,
+	generation int64,
+) (bool, error) {
+	keys := make([]string, 0, 1+2*len(tags))
+	keys = append(keys, key)
+	for _, tag := range tags {
+		keys = append(keys, tag, tagGeneration(tag))
+	}
+	stored, err := storeScript.Run(ctx, c.rdb, keys,
+		value, ttl.Milliseconds(), generation, int64(tagTTL.Seconds())).Int()
+	if err != nil {
+		return false, fmt.Errorf("mdlcache: store: %w", err)
+	}
+	return stored == 1, nil
+}
+
+// load returns the cached response of fn under key, or calls fetch and caches
+// its response under the tags returned by tagsOf. Concurrent misses for the
+// same key share one fetch, which is not cancelled when one of the callers
+// gives up. Every caller gets its own copy of the response. Under Refresh the
+// cached response is ignored.
+//
+// A fetch can return data from before a flush of one of its tags, e.g. the
+// grades of a course while they are being updated. The response is then not
+// cached: the flush count is read before fetching and compared with the
+// stamps of the tags when storing.
+//
+// Redis failures are logged and the response fetched from Moodle instead:
+// the cache must never make a read fail.
+func load[T any](
+	ctx context.Context,
+	c *Cache,
+	fn, key string,
+	tagsOf func(*T) []string,
+	fetch func(context.Context) (*T, error),
+) (*T, error) {
+	key = keyPrefix + key
+
+	if !refreshing(ctx) {
+		cached, err := c.rdb.Get(ctx, key).Bytes()
+		switch {
+		case err == nil:
+			resp := new(T)
+			if err := json.Unmarshal(cached, resp); err == nil {
+				if s, ok := any(resp).(sourced); ok {
+					s.SetSource(mdlapi.SourceCache)
+				}
+				return resp, nil
+			}
+			logger.WarnContext(ctx, "mdlcache: dropping undecodable entry", "key", key)
+		case !helper.IsKeyDoesNotExistErr(err):
+			logger.WarnContext(ctx, "mdlcache: read failed", "err", err, "key", key)
+		}
+	}
+
+	ch := c.group.DoChan(key, func() (any, error) {
+		fetchCtx := context.WithoutCancel(ctx)
+		generation, genErr := c.generation(fetchCtx)
+		if genErr != nil {
+			logger.WarnContext(fetchCtx, "mdlcache: read failed", "err", genErr, "key", generationKey)
+		}
+		resp, err := fetch(fetchCtx)
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(resp)
+		if err != nil {
+			return nil, fmt.Errorf("mdlcache: encode %s: %w", fn, err)
+		}
+		if genErr != nil {
+			// Without the flush count the response may be stale.
+			return b, nil
+		}
+		stored, err := c.store(fetchCtx, key, b, ttls[fn], tagsOf(resp), generation)
+		switch {
+		case err != nil:
+			logger.WarnContext(fetchCtx, "mdlcache: write failed", "err", err, "key", key)
+		case !stored:
+			logger.DebugContext(fetchCtx, "mdlcache: not caching a read that overlapped a flush", "key", key)
+		}
+		return b, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		resp := new(T)
+		if err := json.Unmarshal(res.Val.([]byte), resp); err != nil {
+			return nil, fmt.Errorf("mdlcache: decode %s: %w", fn, err)
+		}
+		return resp, nil
+	}
+}
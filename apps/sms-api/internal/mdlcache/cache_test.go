@@ -0,0 +1,141 @@
+package mdlcache
+
+import (
+	"context"
+	"testing"
+
+	"encore.app/internal/mdlapi"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestCache(t *testing.T) *Cache {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return New(rdb)
+}
+
+// fakeCourses serves course 1 in category 7. onFetch, if set, runs during
+// each fetch.
+type fakeCourses struct {
+	calls   int
+	onFetch func()
+}
+
+func (f *fakeCourses) GetCourseDetails(
+	_ context.Context,
+	req *mdlapi.GetCourseGradesRequest,
+) (*mdlapi.GetCourseGradesResponse, error) {
+	f.calls++
+	if f.onFetch != nil {
+		f.onFetch()
+	}
+	resp := &mdlapi.GetCourseGradesResponse{}
+	resp.Course.ID = int(req.CourseId)
+	resp.Course.Category = 7
+	return resp, nil
+}
+
+func readCourse(t *testing.T, p mdlapi.LocalCourseGrades) {
+	t.Helper()
+	if _, err := p.GetCourseDetails(context.Background(), &mdlapi.GetCourseGradesRequest{CourseId: 1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlushByTag(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t)
+	next := &fakeCourses{}
+	p := NewCourseGradesProvider(next, c)
+
+	for _, tc := range []struct {
+		name  string
+		flush func() (int, error)
+	}{
+		{"course", func() (int, error) { return c.FlushCourse(ctx, 1) }},
+		{"category", func() (int, error) { return c.FlushCategory(ctx, 7) }},
+	} {
+		next.calls = 0
+		readCourse(t, p)
+		readCourse(t, p)
+		if next.calls != 1 {
+			t.Fatalf("%s: fetched %d times for two reads, want 1", tc.name, next.calls)
+		}
+
+		n, err := tc.flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Errorf("%s: flushed %d entries, want 1", tc.name, n)
+		}
+		readCourse(t, p)
+		if next.calls != 2 {
+			t.Errorf("%s: fetched %d times after the flush, want 2", tc.name, next.calls)
+		}
+		if _, err := c.FlushCourse(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Other tags are left alone.
+	readCourse(t, p)
+	if n, err := c.FlushCategory(ctx, 8); err != nil || n != 0 {
+		t.Fatalf("flushing another category dropped %d entries (%v), want 0", n, err)
+	}
+}
+
+func TestFlushDuringFetchIsNotCached(t *testing.T) {
+	c := newTestCache(t)
+	next := &fakeCourses{}
+	next.onFetch = func() {
+		// The grades change while Moodle is being read.
+		if _, err := c.FlushCourse(context.Background(), 1); err != nil {
+			t.Error(err)
+		}
+		next.onFetch = nil
+	}
+	p := NewCourseGradesProvider(next, c)
+
+	readCourse(t, p)
+	readCourse(t, p)
+	if next.calls != 2 {
+		t.Fatalf("fetched %d times, want 2: the read that overlapped the flush was cached", next.calls)
+	}
+	readCourse(t, p)
+	if next.calls != 2 {
+		t.Fatalf("fetched %d times, want 2: the read after the flush was not cached", next.calls)
+	}
+}
+
+type fakeTeacher struct {
+	mdlapi.LocalTeacherProvider
+}
+
+func (fakeTeacher) GetAllCategories(
+	context.Context,
+	*mdlapi.GetAllCategoriesRequest,
+) (*mdlapi.GetCategoriesResponse, error) {
+	return &mdlapi.GetCategoriesResponse{
+		Categories: []mdlapi.Category{{ID: 7}},
+		Source:     mdlapi.SourceDatabase,
+	}, nil
+}
+
+func TestCachedResponsesReportTheCache(t *testing.T) {
+	ctx := context.Background()
+	p := NewTeacherProvider(fakeTeacher{}, newTestCache(t))
+
+	for _, want := range []mdlapi.Source{mdlapi.SourceDatabase, mdlapi.SourceCache} {
+		resp, err := p.GetAllCategories(ctx, &mdlapi.GetAllCategoriesRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Source != want {
+			t.Errorf("source = %q, want %q", resp.Source, want)
+		}
+	}
+}
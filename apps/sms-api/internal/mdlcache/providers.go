@@ -0,0 +1,167 @@
+package mdlcache
+
+import (
+	"context"
+	"fmt"
+
+	"encore.app/internal/logger"
+	"encore.app/internal/mdlapi"
+)
+
+// ── Course details ───────────────────────────────────────────────────────────
+
+type courseGradesProvider struct {
+	next  mdlapi.LocalCourseGrades
+	cache *Cache
+}
+
+var _ mdlapi.LocalCourseGrades = (*courseGradesProvider)(nil)
+
+func NewCourseGradesProvider(next mdlapi.LocalCourseGrades, cache *Cache) *courseGradesProvider {
+	return &courseGradesProvider{next: next, cache: cache}
+}
+
+func (p *courseGradesProvider) GetCourseDetails(
+	ctx context.Context,
+	req *mdlapi.GetCourseGradesRequest,
+) (*mdlapi.GetCourseGradesResponse, error) {
+	return load(ctx, p.cache, mdlapi.GET_CUSTOM_COURSE_DETAILS,
+		fmt.Sprintf("course:%d:details", req.CourseId),
+		func(resp *mdlapi.GetCourseGradesResponse) []string {
+			return []string{courseTag(req.CourseId), categoryTag(int64(resp.Course.Category))}
+		},
+		func(ctx context.Context) (*mdlapi.GetCourseGradesResponse, error) {
+			return p.next.GetCourseDetails(ctx, req)
+		},
+	)
+}
+
+// ── Categories and their courses ─────────────────────────────────────────────
+
+type teacherProvider struct {
+	next  mdlapi.LocalTeacherProvider
+	cache *Cache
+}
+
+var _ mdlapi.LocalTeacherProvider = (*teacherProvider)(nil)
+
+func NewTeacherProvider(next mdlapi.LocalTeacherProvider, cache *Cache) *teacherProvider {
+	return &teacherProvider{next: next, cache: cache}
+}
+
+func categoriesTags(resp *mdlapi.GetCategoriesResponse) []string {
+	tags := make([]string, len(resp.Categories))
+	for i, cat := range resp.Categories {
+		tags[i] = categoryTag(int64(cat.ID))
+	}
+	return tags
+}
+
+func categoryCoursesTags(categoryID int) func(*mdlapi.GetCategoryCoursesResponse) []string {
+	return func(resp *mdlapi.GetCategoryCoursesResponse) []string {
+		tags := []string{categoryTag(int64(categoryID))}
+		for _, course := range resp.Courses {
+			tags = append(tags, courseTag(int64(course.ID)))
+		}
+		return tags
+	}
+}
+
+// forUser adds the tag of the user a response was read for, so that it is
+// flushed when their roles or enrolments change.
+func forUser[T any](userID int, tagsOf func(*T) []string) func(*T) []string {
+	return func(resp *T) []string {
+		return append(tagsOf(resp), userTag(int64(userID)))
+	}
+}
+
+func (p *teacherProvider) GetCategories(
+	ctx context.Context,
+	req *mdlapi.GetCategoriesRequest,
+) (*mdlapi.GetCategoriesResponse, error) {
+	return load(ctx, p.cache, mdlapi.GET_CATEGORIES,
+		fmt.Sprintf("categories:user:%d", req.UserID),
+		forUser(req.UserID, categoriesTags),
+		func(ctx context.Context) (*mdlapi.GetCategoriesResponse, error) {
+			return p.next.GetCategories(ctx, req)
+		},
+	)
+}
+
+func (p *teacherProvider) GetCategoryCourses(
+	ctx context.Context,
+	req *mdlapi.GetCategoryCoursesRequest,
+) (*mdlapi.GetCategoryCoursesResponse, error) {
+	return load(ctx, p.cache, mdlapi.GET_CATEGORY_COURSES,
+		fmt.Sprintf("category:%d:courses:user:%d", req.CategoryID, req.UserID),
+		forUser(req.UserID, categoryCoursesTags(req.CategoryID)),
+		func(ctx context.Context) (*mdlapi.GetCategoryCoursesResponse, error) {
+			return p.next.GetCategoryCourses(ctx, req)
+		},
+	)
+}
+
+func (p *teacherProvider) GetAllCategories(
+	ctx context.Context,
+	req *mdlapi.GetAllCategoriesRequest,
+) (*mdlapi.GetCategoriesResponse, error) {
+	return load(ctx, p.cache, mdlapi.GET_ALL_CATEGORIES,
+		"categories:all",
+		categoriesTags,
+		func(ctx context.Context) (*mdlapi.GetCategoriesResponse, error) {
+			return p.next.GetAllCategories(ctx, req)
+		},
+	)
+}
+
+func (p *teacherProvider) GetAllCategoryCoursesForAdmin(
+	ctx context.Context,
+	req *mdlapi.GetCategoryCoursesRequest,
+) (*mdlapi.GetCategoryCoursesResponse, error) {
+	return load(ctx, p.cache, mdlapi.GET_ALL_CATEGORY_COURSES,
+		fmt.Sprintf("category:%d:courses:all", req.CategoryID),
+		categoryCoursesTags(req.CategoryID),
+		func(ctx context.Context) (*mdlapi.GetCategoryCoursesResponse, error) {
+			return p.next.GetAllCategoryCoursesForAdmin(ctx, req)
+		},
+	)
+}
+
+// ── Grade writes ─────────────────────────────────────────────────────────────
+
+// userGradeItemsProvider passes every call through and flushes the course of
+// each successful grade update, so the next read shows the new grades.
+type userGradeItemsProvider struct {
+	next  mdlapi.UserGradeItemsProvider
+	cache *Cache
+}
+
+var _ mdlapi.UserGradeItemsProvider = (*userGradeItemsProvider)(nil)
+
+func NewUserGradeItemsProvider(next mdlapi.UserGradeItemsProvider, cache *Cache) *userGradeItemsProvider {
+	return &userGradeItemsProvider{next: next, cache: cache}
+}
+
+func (p *userGradeItemsProvider) UpdateGrades(
+	ctx context.Context,
+	req *mdlapi.UpdateGradesRequest,
+) (mdlapi.UpdateGradesResponse, error) {
+	resp, err := p.next.UpdateGrades(ctx, req)
+	if err != nil || !resp {
+		return resp, err
+	}
+
+	if _, err := p.cache.FlushCourse(context.WithoutCancel(ctx), int64(req.CourseID)); err != nil {
+		// The entry expires with its TTL; until then reads show old grades.
+		logger.ErrorContext(ctx, "mdlcache: failed to flush course after grade update",
+			"err", err, "courseId", req.CourseID)
+	}
+	return resp, nil
+}
+
+func (p *userGradeItemsProvider) GetUserGrades(
+	ctx context.Context,
+	req *mdlapi.GetUserGradesRequest,
+) (*mdlapi.GetUserGradesResponse, error) {
+	return p.next.GetUserGrades(ctx, req)
+}
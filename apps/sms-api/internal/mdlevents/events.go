@@ -0,0 +1,95 @@
+// Package mdlevents describes the Moodle events the SMS is told about. Moodle's
+// local_smswebhook plugin observes them and posts each one, signed, to the
+// SMS webhook, which publishes it for the rest of the app.
+package mdlevents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Names of the events the plugin sends, as Moodle names them.
+const (
+	UserGraded     = `\core\event\user_graded`
+	UserEnrolled   = `\core\event\user_enrolment_created`
+	UserUnenrolled = `\core\event\user_enrolment_deleted`
+	CourseUpdated  = `\core\event\course_updated`
+	RoleAssigned   = `\core\event\role_assigned`
+	RoleUnassigned = `\core\event\role_unassigned`
+)
+
+var supported = map[string]bool{
+	UserGraded:     true,
+	UserEnrolled:   true,
+	UserUnenrolled: true,
+	CourseUpdated:  true,
+	RoleAssigned:   true,
+	RoleUnassigned: true,
+}
+
+// OriginWebService is the origin of events caused through Moodle's web
+// services, such as the grades the SMS writes itself.
+const OriginWebService = "ws"
+
+// maxIDLength is the length of the column the grade history keeps event IDs
+// in. The plugin sends UUIDs.
+const maxIDLength = 64
+
+// Event is a Moodle event as the plugin sends it: the standard event data,
+// plus what the SMS needs that Moodle leaves out.
+type Event struct {
+	// ID is unique per event; Moodle retries failed deliveries with the
+	// same ID.
+	ID        string `json:"id"`
+	EventName string `json:"eventname"`
+	CourseID  int64  `json:"courseid"`
+	// CategoryID is the category of the course, if any.
+	CategoryID        int64 `json:"categoryid"`
+	ContextLevel      int   `json:"contextlevel"`
+	ContextInstanceID int64 `json:"contextinstanceid"`
+	ObjectID          int64 `json:"objectid"`
+	// UserID is who caused the event, RelatedUserID who it happened to: the
+	// student graded, the user enrolled or given a role.
+	UserID        int64 `json:"userid"`
+	RelatedUserID int64 `json:"relateduserid"`
+	// Origin is how the event was caused: "web", "ws", "cli" or "restore".
+	Origin      string `json:"origin"`
+	TimeCreated int64  `json:"timecreated"`
+	// Grade is set on UserGraded events only.
+	Grade *Grade `json:"grade,omitempty"`
+}
+
+// Grade is the grade a UserGraded event is about.
+type Grade struct {
+	ItemID int64 `json:"itemid"`
+	// ActivityID is the course module graded, 0 for items of no activity
+	// such as the course total.
+	ActivityID int64 `json:"cmid"`
+	ItemNumber int   `json:"itemnumber"`
+	// FinalGrade is nil when the grade was removed. OldGrade is nil when
+	// there was none, or Moodle keeps no grade history.
+	FinalGrade *float64 `json:"finalgrade"`
+	OldGrade   *float64 `json:"oldgrade"`
+}
+
+// Validate checks that the event is one the SMS handles and carries what
+// handling it needs.
+func (e *Event) Validate() error {
+	switch {
+	case e.ID == "":
+		return errors.New("id is required")
+	case len(e.ID) > maxIDLength:
+		return fmt.Errorf("id must not be longer than %d characters", maxIDLength)
+	case !supported[e.EventName]:
+		return fmt.Errorf("unsupported event %q", e.EventName)
+	case e.EventName == UserGraded && e.Grade == nil:
+		return errors.New("grade is required on user_graded")
+	}
+	return nil
+}
+
+// Publisher publishes events; the webhook's Pub/Sub topic is one.
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) (id string, err error)
+}
@@ -0,0 +1,67 @@
+package mdlevents_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"encore.app/internal/mdlevents"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "s3cret"
+	body := []byte(`{"id":"1"}`)
+	now := time.Unix(1_700_000_000, 0)
+	ts := now.Unix()
+	sig := mdlevents.Sign(secret, ts, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		ok        bool
+	}{
+		{"valid", secret, strconv.FormatInt(ts, 10), sig, body, true},
+		{"slightly early clock", secret, strconv.FormatInt(ts+60, 10), mdlevents.Sign(secret, ts+60, body), body, true},
+		{"no secret", "", strconv.FormatInt(ts, 10), sig, body, false},
+		{"wrong secret", "other", strconv.FormatInt(ts, 10), sig, body, false},
+		{"altered body", secret, strconv.FormatInt(ts, 10), sig, []byte(`{"id":"2"}`), false},
+		{"altered timestamp", secret, strconv.FormatInt(ts+1, 10), sig, body, false},
+		{"malformed timestamp", secret, "yesterday", sig, body, false},
+		{"replayed", secret, strconv.FormatInt(ts-600, 10), mdlevents.Sign(secret, ts-600, body), body, false},
+		{"no signature", secret, strconv.FormatInt(ts, 10), "", body, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mdlevents.Verify(tt.secret, tt.timestamp, tt.signature, tt.body, now, 5*time.Minute)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Verify() = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		event mdlevents.Event
+		ok    bool
+	}{
+		{"enrolment", mdlevents.Event{ID: "1", EventName: mdlevents.UserEnrolled}, true},
+		{"grade", mdlevents.Event{ID: "1", EventName: mdlevents.UserGraded, Grade: &mdlevents.Grade{}}, true},
+		{"grade without grade", mdlevents.Event{ID: "1", EventName: mdlevents.UserGraded}, false},
+		{"no id", mdlevents.Event{EventName: mdlevents.CourseUpdated}, false},
+		{"long id", mdlevents.Event{ID: strings.Repeat("x", 65), EventName: mdlevents.CourseUpdated}, false},
+		{"unsupported", mdlevents.Event{ID: "1", EventName: `\core\event\course_viewed`}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.event.Validate(); (err == nil) != tt.ok {
+				t.Fatalf("Validate() = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
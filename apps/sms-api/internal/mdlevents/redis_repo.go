@@ -0,0 +1,40 @@
+package mdlevents
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix = "mdlevents:published:"
+	// ttl outlasts the retries of Moodle's ad hoc tasks for a day.
+	ttl = 24 * time.Hour
+)
+
+type redisRepository struct {
+	rdb *redis.Client
+}
+
+var _ Repository = (*redisRepository)(nil)
+
+func NewRedisRepository(rdb *redis.Client) *redisRepository {
+	return &redisRepository{rdb: rdb}
+}
+
+func (r *redisRepository) Claim(ctx context.Context, id string) (bool, error) {
+	ok, err := r.rdb.SetNX(ctx, keyPrefix+id, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("mdlevents: claim: %w", err)
+	}
+	return ok, nil
+}
+
+func (r *redisRepository) Release(ctx context.Context, id string) error {
+	if err := r.rdb.Del(ctx, keyPrefix+id).Err(); err != nil {
+		return fmt.Errorf("mdlevents: release: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package mdlevents
+
+import "context"
+
+// Repository remembers which events were published, so that deliveries
+// Moodle retries are published once.
+type Repository interface {
+	// Claim marks the event as published and reports whether it was not
+	// already.
+	Claim(ctx context.Context, id string) (bool, error)
+	// Release forgets a claim whose event could not be published.
+	Release(ctx context.Context, id string) error
+}
@@ -0,0 +1,47 @@
+package mdlevents
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	errNoSecret  = errors.New("webhook secret is not configured")
+	errTimestamp = errors.New("malformed timestamp")
+	errExpired   = errors.New("timestamp outside the tolerance")
+	errSignature = errors.New("signature mismatch")
+)
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" under secret. The
+// plugin sends it in the X-Moodle-Signature header, and the timestamp in
+// X-Moodle-Timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that body was signed with secret at timestamp, and that
+// timestamp is within tolerance of now.
+func Verify(secret, timestamp, signature string, body []byte, now time.Time, tolerance time.Duration) error {
+	if secret == "" {
+		return errNoSecret
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errTimestamp
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return errExpired
+	}
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature)) {
+		return errSignature
+	}
+	return nil
+}
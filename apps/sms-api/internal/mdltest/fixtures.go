@@ -0,0 +1,256 @@
+package mdltest
+
+import "slices"
+
+// Roles as computed by the local_oauth2userinfo plugin.
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+	RoleTeacher = "teacher"
+	RoleStudent = "student"
+)
+
+// User is a Moodle account.
+type User struct {
+	ID        int
+	Username  string
+	Firstname string
+	Lastname  string
+	Email     string
+	IDNumber  string
+	Role      string
+	Suspended bool
+}
+
+func (u *User) Fullname() string {
+	return u.Firstname + " " + u.Lastname
+}
+
+// Category is a course category.
+type Category struct {
+	ID       int
+	Name     string
+	IDNumber string
+	Parent   int
+}
+
+// Module is a graded activity of a course.
+type Module struct {
+	// GradeItemID identifies the module's grade item.
+	GradeItemID int
+	Cmid        int
+	Name        string
+	// Type is the activity module, "assign" or "quiz".
+	Type       string
+	ItemNumber int
+	// ExamType is the exam type custom field: "15P", "1T", "Thi" or empty.
+	ExamType string
+	Grademin float64
+	Grademax float64
+}
+
+// GradeKey identifies a grade of a student for one module.
+type GradeKey struct {
+	StudentID  int
+	Cmid       int
+	ItemNumber int
+}
+
+// Course is a course with its teachers, students and gradebook.
+type Course struct {
+	ID         int
+	CategoryID int
+	Fullname   string
+	Shortname  string
+	IDNumber   string
+	StartDate  int64
+	EndDate    int64
+	// Credits is the "credit" course custom field.
+	Credits  int
+	Teachers []int
+	Students []int
+	Modules  []Module
+	Grades   map[GradeKey]float64
+}
+
+// Template is an export template stored by local_customgradeexport.
+type Template struct {
+	ID string
+	// Type is "course" or "transcript".
+	Type     string
+	Name     string
+	Format   string
+	Data     []byte
+	Modified int64
+}
+
+// Fixtures is the data the server serves. The server works on a copy, so a
+// test can keep the Fixtures it started with as the expected state.
+type Fixtures struct {
+	Users      []User
+	Categories []Category
+	Courses    []Course
+	Templates  []Template
+}
+
+func (f *Fixtures) clone() *Fixtures {
+	c := &Fixtures{
+		Users:      slices.Clone(f.Users),
+		Categories: slices.Clone(f.Categories),
+		Courses:    slices.Clone(f.Courses),
+		Templates:  slices.Clone(f.Templates),
+	}
+	for i := range c.Courses {
+		course := &c.Courses[i]
+		course.Teachers = slices.Clone(course.Teachers)
+		course.Students = slices.Clone(course.Students)
+		course.Modules = slices.Clone(course.Modules)
+		grades := make(map[GradeKey]float64, len(course.Grades))
+		for k, v := range course.Grades {
+			grades[k] = v
+		}
+		course.Grades = grades
+	}
+	for i := range c.Templates {
+		c.Templates[i].Data = slices.Clone(c.Templates[i].Data)
+	}
+	return c
+}
+
+func (f *Fixtures) user(id int) *User {
+	for i := range f.Users {
+		if f.Users[i].ID == id {
+			return &f.Users[i]
+		}
+	}
+	return nil
+}
+
+func (f *Fixtures) category(id int) *Category {
+	for i := range f.Categories {
+		if f.Categories[i].ID == id {
+			return &f.Categories[i]
+		}
+	}
+	return nil
+}
+
+func (f *Fixtures) course(id int) *Course {
+	for i := range f.Courses {
+		if f.Courses[i].ID == id {
+			return &f.Courses[i]
+		}
+	}
+	return nil
+}
+
+// Fixture IDs of Seed, for tests to refer to.
+const (
+	AdminID    = 2
+	ManagerID  = 3
+	TeacherID  = 10
+	Teacher2ID = 11
+	Student1ID = 100
+	Student2ID = 101
+	Student3ID = 102
+
+	MathCategoryID    = 1
+	ScienceCategoryID = 2
+
+	MathCourseID      = 1001
+	PhysicsCourseID   = 1002
+	ChemistryCourseID = 1003
+
+	// Cmids of the math course's activities.
+	MathQuizCmid    = 5001
+	MathMidtermCmid = 5002
+	MathFinalCmid   = 5003
+
+	CourseTemplateID = "tpl-course-1"
+)
+
+// Seed returns a small school: an admin, a manager, two teachers, three
+// students and three courses in two categories. TeacherID teaches the math
+// and physics courses, Teacher2ID the chemistry course.
+func Seed() *Fixtures {
+	return &Fixtures{
+		Users: []User{
+			{ID: AdminID, Username: "admin", Firstname: "Admin", Lastname: "User", Email: "admin@example.com", Role: RoleAdmin},
+			{ID: ManagerID, Username: "manager", Firstname: "Mai", Lastname: "Tran", Email: "manager@example.com", Role: RoleManager},
+			{ID: TeacherID, Username: "teacher1", Firstname: "Lan", Lastname: "Nguyen", Email: "lan@example.com", Role: RoleTeacher},
+			{ID: Teacher2ID, Username: "teacher2", Firstname: "Hung", Lastname: "Pham", Email: "hung@example.com", Role: RoleTeacher},
+			{ID: Student1ID, Username: "student1", Firstname: "An", Lastname: "Le", Email: "an@example.com", IDNumber: "HS001", Role: RoleStudent},
+			{ID: Student2ID, Username: "student2", Firstname: "Binh", Lastname: "Vo", Email: "binh@example.com", IDNumber: "HS002", Role: RoleStudent},
+			{ID: Student3ID, Username: "student3", Firstname: "Chi", Lastname: "Do", Email: "chi@example.com", IDNumber: "HS003", Role: RoleStudent},
+		},
+		Categories: []Category{
+			{ID: MathCategoryID, Name: "Mathematics", IDNumber: "MATH"},
+			{ID: ScienceCategoryID, Name: "Science", IDNumber: "SCI"},
+		},
+		Courses: []Course{
+			{
+				ID:         MathCourseID,
+				CategoryID: MathCategoryID,
+				Fullname:   "Mathematics 10A",
+				Shortname:  "MATH10A",
+				IDNumber:   "MATH10A",
+				StartDate:  1725148800,
+				EndDate:    1748736000,
+				Credits:    2,
+				Teachers:   []int{TeacherID},
+				Students:   []int{Student1ID, Student2ID, Student3ID},
+				Modules: []Module{
+					{GradeItemID: 9001, Cmid: MathQuizCmid, Name: "Quiz 1", Type: "quiz", ExamType: "15P", Grademax: 10},
+					{GradeItemID: 9002, Cmid: MathMidtermCmid, Name: "Midterm", Type: "assign", ExamType: "1T", Grademax: 10},
+					{GradeItemID: 9003, Cmid: MathFinalCmid, Name: "Final exam", Type: "assign", ExamType: "Thi", Grademax: 10},
+				},
+				Grades: map[GradeKey]float64{
+					{Student1ID, MathQuizCmid, 0}:    8,
+					{Student1ID, MathMidtermCmid, 0}: 7.5,
+					{Student1ID, MathFinalCmid, 0}:   9,
+					{Student2ID, MathQuizCmid, 0}:    6,
+					{Student2ID, MathMidtermCmid, 0}: 5.5,
+				},
+			},
+			{
+				ID:         PhysicsCourseID,
+				CategoryID: ScienceCategoryID,
+				Fullname:   "Physics 10A",
+				Shortname:  "PHYS10A",
+				IDNumber:   "PHYS10A",
+				StartDate:  1725148800,
+				EndDate:    1748736000,
+				Credits:    1,
+				Teachers:   []int{TeacherID},
+				Students:   []int{Student1ID, Student2ID},
+				Modules: []Module{
+					{GradeItemID: 9101, Cmid: 5101, Name: "Lab report", Type: "assign", ExamType: "15P", Grademax: 10},
+					{GradeItemID: 9102, Cmid: 5102, Name: "Final exam", Type: "quiz", ExamType: "Thi", Grademax: 10},
+				},
+				Grades: map[GradeKey]float64{
+					{Student1ID, 5101, 0}: 9,
+					{Student2ID, 5101, 0}: 7,
+				},
+			},
+			{
+				ID:         ChemistryCourseID,
+				CategoryID: ScienceCategoryID,
+				Fullname:   "Chemistry 10A",
+				Shortname:  "CHEM10A",
+				IDNumber:   "CHEM10A",
+				StartDate:  1725148800,
+				EndDate:    1748736000,
+				Credits:    1,
+				Teachers:   []int{Teacher2ID},
+				Students:   []int{Student3ID},
+				Modules: []Module{
+					{GradeItemID: 9201, Cmid: 5201, Name: "Quiz 1", Type: "quiz", ExamType: "15P", Grademax: 10},
+				},
+				Grades: map[GradeKey]float64{},
+			},
+		},
+		Templates: []Template{
+			{ID: CourseTemplateID, Type: "course", Name: "Course sheet", Format: "xlsx", Data: []byte("xlsx template"), Modified: 1725148800},
+		},
+	}
+}
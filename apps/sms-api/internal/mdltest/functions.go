@@ -0,0 +1,757 @@
+package mdltest
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Names of the web service functions, as registered by Moodle and the
+// local plugins.
+const (
+	fnEnrolledUsers      = "core_enrol_get_enrolled_users"
+	fnUserGradeItems     = "gradereport_user_get_grade_items"
+	fnCourseData         = "local_coursegrades_get_course_data"
+	fnStudentGrades      = "local_coursegrades_get_student_grades"
+	fnTeacherCategories  = "local_teachercourses_get_teacher_categories"
+	fnTeacherCourses     = "local_teachercourses_get_teacher_courses"
+	fnUserInfo           = "local_oauth2userinfo_get_user_info"
+	fnUpdateGrades       = "core_grades_update_grades"
+	fnAllCategories      = "local_teachercourses_get_all_categories"
+	fnAllCategoryCourses = "local_teachercourses_get_all_category_courses"
+	fnCourseTemplates    = "local_customgradeexport_get_course_templates"
+	fnExportCourseGrades = "local_customgradeexport_export_course_grades"
+	fnAllTemplates       = "local_customgradeexport_get_all_templates"
+	fnUploadTemplate     = "local_customgradeexport_upload_template"
+	fnDeleteTemplate     = "local_customgradeexport_delete_template"
+	fnTemplateContent    = "local_customgradeexport_get_template_content"
+)
+
+// Moodle's role ids for enrolments.
+const (
+	roleIDEditingTeacher = 3
+	roleIDStudent        = 5
+)
+
+// A function handles one call with s.mu held and returns its response, or the
+// exception it raises.
+type function func(s *Server, body []byte) (any, *Exception)
+
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		fnEnrolledUsers:      getEnrolledUsers,
+		fnUserGradeItems:     getUserGradeItems,
+		fnCourseData:         getCourseData,
+		fnStudentGrades:      getStudentGrades,
+		fnTeacherCategories:  getTeacherCategories,
+		fnTeacherCourses:     getTeacherCourses,
+		fnUserInfo:           getUserInfo,
+		fnUpdateGrades:       updateGrades,
+		fnAllCategories:      getAllCategories,
+		fnAllCategoryCourses: getAllCategoryCourses,
+		fnCourseTemplates:    getCourseTemplates,
+		fnExportCourseGrades: exportCourseGrades,
+		fnAllTemplates:       getAllTemplates,
+		fnUploadTemplate:     uploadTemplate,
+		fnDeleteTemplate:     deleteTemplate,
+		fnTemplateContent:    getTemplateContent,
+	}
+}
+
+// ── Exceptions ───────────────────────────────────────────────────────────────
+
+func invalidParameter(err error) *Exception {
+	return &Exception{
+		Exception: "invalid_parameter_exception",
+		ErrorCode: "invalidparameter",
+		Message:   "Invalid parameter value detected",
+		DebugInfo: err.Error(),
+	}
+}
+
+func missingRecord(table string) *Exception {
+	return &Exception{
+		Exception: "dml_missing_record_exception",
+		ErrorCode: "invalidrecord",
+		Message:   fmt.Sprintf("Can't find data record in database table %s.", table),
+	}
+}
+
+func decode(body []byte, params any) *Exception {
+	if err := json.Unmarshal(body, params); err != nil {
+		return invalidParameter(err)
+	}
+	return nil
+}
+
+// ── Users ────────────────────────────────────────────────────────────────────
+
+func getUserInfo(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		AccessToken *string `json:"accesstoken"`
+		UserID      *int    `json:"userid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+
+	var user *User
+	switch {
+	case params.AccessToken != nil:
+		userID, ok := s.tokens[*params.AccessToken]
+		if !ok {
+			return nil, &Exception{Exception: "moodle_exception", ErrorCode: "invalidtoken", Message: "Invalid access token"}
+		}
+		user = s.fixtures.user(userID)
+	case params.UserID != nil:
+		user = s.fixtures.user(*params.UserID)
+	default:
+		return nil, invalidParameter(fmt.Errorf("accesstoken or userid is required"))
+	}
+	if user == nil {
+		return nil, missingRecord("user")
+	}
+
+	suspended := 0
+	if user.Suspended {
+		suspended = 1
+	}
+	roles := []map[string]any{}
+	for _, course := range s.fixtures.Courses {
+		if slices.Contains(course.Teachers, user.ID) {
+			roles = append(roles, map[string]any{
+				"roleid": roleIDEditingTeacher, "shortname": "editingteacher", "name": "Teacher",
+				"archetype": "editingteacher", "courseid": course.ID, "coursename": course.Fullname,
+			})
+		}
+		if slices.Contains(course.Students, user.ID) {
+			roles = append(roles, map[string]any{
+				"roleid": roleIDStudent, "shortname": "student", "name": "Student",
+				"archetype": "student", "courseid": course.ID, "coursename": course.Fullname,
+			})
+		}
+	}
+	return map[string]any{
+		"userid":      user.ID,
+		"username":    user.Username,
+		"firstname":   user.Firstname,
+		"lastname":    user.Lastname,
+		"email":       user.Email,
+		"idnumber":    user.IDNumber,
+		"auth":        "manual",
+		"suspended":   suspended,
+		"deleted":     0,
+		"roles":       roles,
+		"systemroles": []any{},
+		"isteacher":   user.Role == RoleTeacher,
+		"isstudent":   user.Role == RoleStudent,
+		"role":        user.Role,
+	}, nil
+}
+
+func (s *Server) userSummary(user *User) map[string]any {
+	return map[string]any{
+		"userid":    user.ID,
+		"username":  user.Username,
+		"firstname": user.Firstname,
+		"lastname":  user.Lastname,
+		"email":     user.Email,
+	}
+}
+
+func getEnrolledUsers(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		CourseID int `json:"courseid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	course := s.fixtures.course(params.CourseID)
+	if course == nil {
+		return nil, missingRecord("course")
+	}
+
+	users := []map[string]any{}
+	enrol := func(ids []int, roleID int, shortname, name string) {
+		for _, id := range ids {
+			user := s.fixtures.user(id)
+			if user == nil {
+				continue
+			}
+			users = append(users, map[string]any{
+				"id":        user.ID,
+				"username":  user.Username,
+				"firstname": user.Firstname,
+				"lastname":  user.Lastname,
+				"fullname":  user.Fullname(),
+				"email":     user.Email,
+				"roles": []map[string]any{
+					{"roleid": roleID, "name": name, "shortname": shortname, "sortorder": 0},
+				},
+			})
+		}
+	}
+	enrol(course.Teachers, roleIDEditingTeacher, "editingteacher", "Teacher")
+	enrol(course.Students, roleIDStudent, "student", "Student")
+	return users, nil
+}
+
+// ── Categories ───────────────────────────────────────────────────────────────
+
+func (s *Server) categoryJSON(cat *Category, courses []*Course) map[string]any {
+	courseIDs := make([]int, len(courses))
+	for i, c := range courses {
+		courseIDs[i] = c.ID
+	}
+	parentName := ""
+	if parent := s.fixtures.category(cat.Parent); parent != nil {
+		parentName = parent.Name
+	}
+	return map[string]any{
+		"id":          cat.ID,
+		"name":        cat.Name,
+		"description": "",
+		"parent":      cat.Parent,
+		"parentname":  parentName,
+		"path":        fmt.Sprintf("/%d", cat.ID),
+		"depth":       1,
+		"visible":     1,
+		"sortorder":   cat.ID,
+		"coursecount": len(courses),
+		"courseids":   courseIDs,
+		"idnumber":    cat.IDNumber,
+	}
+}
+
+// coursesIn returns the courses of a category, or of every category when
+// categoryID is 0, only those taught by teacherID unless it is 0.
+func (s *Server) coursesIn(categoryID, teacherID int) []*Course {
+	courses := []*Course{}
+	for i := range s.fixtures.Courses {
+		course := &s.fixtures.Courses[i]
+		if categoryID != 0 && course.CategoryID != categoryID {
+			continue
+		}
+		if teacherID != 0 && !slices.Contains(course.Teachers, teacherID) {
+			continue
+		}
+		courses = append(courses, course)
+	}
+	return courses
+}
+
+func (s *Server) categoriesResponse(user *User, teacherID int) map[string]any {
+	cats := []map[string]any{}
+	for i := range s.fixtures.Categories {
+		cat := &s.fixtures.Categories[i]
+		courses := s.coursesIn(cat.ID, teacherID)
+		if teacherID != 0 && len(courses) == 0 {
+			continue
+		}
+		cats = append(cats, s.categoryJSON(cat, courses))
+	}
+
+	resp := map[string]any{"userid": 0, "username": "", "firstname": "", "lastname": "", "email": ""}
+	if user != nil {
+		resp = s.userSummary(user)
+	}
+	resp["totalcategories"] = len(cats)
+	resp["categories"] = cats
+	return resp
+}
+
+func getTeacherCategories(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		UserID int `json:"userid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	user := s.fixtures.user(params.UserID)
+	if user == nil {
+		return nil, missingRecord("user")
+	}
+	return s.categoriesResponse(user, user.ID), nil
+}
+
+func getAllCategories(s *Server, body []byte) (any, *Exception) {
+	return s.categoriesResponse(nil, 0), nil
+}
+
+// categoryCoursesResponse lists the courses of a category, or of every
+// category when categoryID is 0, only those taught by teacherID unless it
+// is 0.
+func (s *Server) categoryCoursesResponse(user *User, categoryID, teacherID int) map[string]any {
+	courses := []map[string]any{}
+	for _, course := range s.coursesIn(categoryID, teacherID) {
+		cat := s.fixtures.category(course.CategoryID)
+		courses = append(courses, map[string]any{
+			"id":              course.ID,
+			"fullname":        course.Fullname,
+			"shortname":       course.Shortname,
+			"idnumber":        course.IDNumber,
+			"summary":         "",
+			"visible":         1,
+			"startdate":       course.StartDate,
+			"enddate":         course.EndDate,
+			"categoryid":      course.CategoryID,
+			"categoryname":    cat.Name,
+			"categorypath":    fmt.Sprintf("/%d", cat.ID),
+			"categoryvisible": 1,
+			"metadata":        courseMetadata(course),
+		})
+	}
+
+	resp := map[string]any{"userid": 0, "username": "", "firstname": "", "lastname": "", "email": ""}
+	if user != nil {
+		resp = s.userSummary(user)
+	}
+	resp["categoryid"] = categoryID
+	resp["totalcourses"] = len(courses)
+	resp["courses"] = courses
+	return resp
+}
+
+func courseMetadata(course *Course) []map[string]any {
+	if course.Credits == 0 {
+		return []map[string]any{}
+	}
+	return []map[string]any{{"name": "credit", "value": course.Credits}}
+}
+
+func getTeacherCourses(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		CategoryID int `json:"categoryid"`
+		UserID     int `json:"userid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	user := s.fixtures.user(params.UserID)
+	if user == nil {
+		return nil, missingRecord("user")
+	}
+	if params.CategoryID != 0 && s.fixtures.category(params.CategoryID) == nil {
+		return nil, &Exception{
+			Exception: "moodle_exception",
+			ErrorCode: "invalidcategoryid",
+			Message:   "Category not known!",
+		}
+	}
+	return s.categoryCoursesResponse(user, params.CategoryID, user.ID), nil
+}
+
+func getAllCategoryCourses(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		CategoryID int `json:"categoryid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	if s.fixtures.category(params.CategoryID) == nil {
+		return nil, missingRecord("course_categories")
+	}
+	return s.categoryCoursesResponse(nil, params.CategoryID, 0), nil
+}
+
+// ── Grades ───────────────────────────────────────────────────────────────────
+
+func examType(m *Module) any {
+	if m.ExamType == "" {
+		return nil
+	}
+	return m.ExamType
+}
+
+// studentGrades lists a student's grades in a course the way
+// local_coursegrades does: moduleid is the grade item, activityid the cmid.
+func studentGrades(course *Course, studentID int) []map[string]any {
+	grades := []map[string]any{}
+	for i := range course.Modules {
+		m := &course.Modules[i]
+		grade, ok := course.Grades[GradeKey{studentID, m.Cmid, m.ItemNumber}]
+		if !ok {
+			continue
+		}
+		grades = append(grades, map[string]any{
+			"moduleid":     m.GradeItemID,
+			"modulename":   m.Name,
+			"grade":        grade,
+			"examtype":     examType(m),
+			"itemmodule":   m.Type,
+			"iteminstance": m.Cmid,
+			"itemnumber":   m.ItemNumber,
+			"activityid":   m.Cmid,
+		})
+	}
+	return grades
+}
+
+func getCourseData(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		CourseID int `json:"courseid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	course := s.fixtures.course(params.CourseID)
+	if course == nil {
+		return nil, missingRecord("course")
+	}
+
+	modules := make([]map[string]any, len(course.Modules))
+	for i := range course.Modules {
+		m := &course.Modules[i]
+		modules[i] = map[string]any{
+			"id":         m.GradeItemID,
+			"cmid":       m.Cmid,
+			"name":       m.Name,
+			"type":       m.Type,
+			"idnumber":   "",
+			"examtype":   examType(m),
+			"itemnumber": m.ItemNumber,
+			"grademin":   m.Grademin,
+			"grademax":   m.Grademax,
+		}
+	}
+
+	students := []map[string]any{}
+	for _, id := range course.Students {
+		user := s.fixtures.user(id)
+		if user == nil {
+			continue
+		}
+		students = append(students, map[string]any{
+			"id":        user.ID,
+			"fullname":  user.Fullname(),
+			"username":  user.Username,
+			"firstname": user.Firstname,
+			"lastname":  user.Lastname,
+			"email":     user.Email,
+			"grades":    studentGrades(course, user.ID),
+		})
+	}
+
+	return map[string]any{
+		"course": map[string]any{
+			"id":           course.ID,
+			"fullname":     course.Fullname,
+			"shortname":    course.Shortname,
+			"idnumber":     course.IDNumber,
+			"visible":      1,
+			"summary":      "",
+			"category":     course.CategoryID,
+			"startdate":    course.StartDate,
+			"enddate":      course.EndDate,
+			"timemodified": course.StartDate,
+			"marker":       0,
+		},
+		"modules":  modules,
+		"students": students,
+	}, nil
+}
+
+func getStudentGrades(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		UserID int `json:"userid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	user := s.fixtures.user(params.UserID)
+	if user == nil {
+		return nil, missingRecord("user")
+	}
+
+	courses := []map[string]any{}
+	for i := range s.fixtures.Courses {
+		course := &s.fixtures.Courses[i]
+		if !slices.Contains(course.Students, user.ID) {
+			continue
+		}
+		teachers := []map[string]any{}
+		for _, id := range course.Teachers {
+			if t := s.fixtures.user(id); t != nil {
+				teachers = append(teachers, map[string]any{
+					"id": t.ID, "fullname": t.Fullname(), "email": t.Email, "picture": "",
+				})
+			}
+		}
+		courses = append(courses, map[string]any{
+			"courseid":   course.ID,
+			"coursename": course.Fullname,
+			"shortname":  course.Shortname,
+			"visible":    1,
+			"grades":     studentGrades(course, user.ID),
+			"metadata":   courseMetadata(course),
+			"teachers":   teachers,
+		})
+	}
+
+	resp := s.userSummary(user)
+	resp["courses"] = courses
+	return resp, nil
+}
+
+func getUserGradeItems(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		CourseID int `json:"courseid"`
+		UserID   int `json:"userid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	course := s.fixtures.course(params.CourseID)
+	if course == nil {
+		return nil, missingRecord("course")
+	}
+
+	usergrades := []map[string]any{}
+	for _, id := range course.Students {
+		user := s.fixtures.user(id)
+		if user == nil || (params.UserID != 0 && params.UserID != id) {
+			continue
+		}
+		items := make([]map[string]any, len(course.Modules))
+		for i := range course.Modules {
+			m := &course.Modules[i]
+			var raw any
+			if grade, ok := course.Grades[GradeKey{id, m.Cmid, m.ItemNumber}]; ok {
+				raw = grade
+			}
+			items[i] = map[string]any{
+				"id":         m.GradeItemID,
+				"itemname":   m.Name,
+				"itemtype":   "mod",
+				"itemmodule": m.Type,
+				"cmid":       m.Cmid,
+				"itemnumber": m.ItemNumber,
+				"graderaw":   raw,
+				"grademin":   m.Grademin,
+				"grademax":   m.Grademax,
+			}
+		}
+		usergrades = append(usergrades, map[string]any{
+			"courseid":     course.ID,
+			"userid":       user.ID,
+			"userfullname": user.Fullname(),
+			"gradeitems":   items,
+		})
+	}
+	return map[string]any{"usergrades": usergrades, "warnings": []any{}}, nil
+}
+
+// updateGrades returns 0 (GRADE_UPDATE_OK) or 1 (GRADE_UPDATE_FAILED), as
+// core_grades_update_grades does.
+func updateGrades(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		Source     string `json:"source"`
+		CourseID   int    `json:"courseid"`
+		Component  string `json:"component"`
+		ActivityID int    `json:"activityid"`
+		ItemNumber int    `json:"itemnumber"`
+		Grades     []struct {
+			StudentID int     `json:"studentid"`
+			Grade     float64 `json:"grade"`
+		} `json:"grades"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	course := s.fixtures.course(params.CourseID)
+	if course == nil {
+		return nil, missingRecord("course")
+	}
+	if params.Component != "mod_"+strings.TrimPrefix(params.Source, "mod/") {
+		return nil, invalidParameter(fmt.Errorf("component %q does not match source %q", params.Component, params.Source))
+	}
+
+	idx := slices.IndexFunc(course.Modules, func(m Module) bool {
+		return m.Cmid == params.ActivityID && m.ItemNumber == params.ItemNumber
+	})
+	if idx < 0 {
+		return 1, nil
+	}
+	for _, g := range params.Grades {
+		if !slices.Contains(course.Students, g.StudentID) {
+			return 1, nil
+		}
+	}
+	for _, g := range params.Grades {
+		course.Grades[GradeKey{g.StudentID, params.ActivityID, params.ItemNumber}] = g.Grade
+	}
+	return 0, nil
+}
+
+// ── Export templates ─────────────────────────────────────────────────────────
+
+func templateJSON(t *Template) map[string]any {
+	return map[string]any{
+		"id":       t.ID,
+		"name":     t.Name,
+		"format":   t.Format,
+		"size":     len(t.Data),
+		"modified": t.Modified,
+	}
+}
+
+func (s *Server) templatesOfType(templateType string) []map[string]any {
+	templates := []map[string]any{}
+	for i := range s.fixtures.Templates {
+		if s.fixtures.Templates[i].Type == templateType {
+			templates = append(templates, templateJSON(&s.fixtures.Templates[i]))
+		}
+	}
+	return templates
+}
+
+func (s *Server) template(templateType, id string) *Template {
+	for i := range s.fixtures.Templates {
+		t := &s.fixtures.Templates[i]
+		if t.Type == templateType && t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+func getCourseTemplates(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		CourseID int `json:"courseid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	if s.fixtures.course(params.CourseID) == nil {
+		return nil, missingRecord("course")
+	}
+	return s.templatesOfType("course"), nil
+}
+
+func getAllTemplates(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		Type string `json:"type"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	return s.templatesOfType(params.Type), nil
+}
+
+// exportCourseGrades renders the gradebook as CSV, whatever the template:
+// tests check what was exported, not how it looks.
+func exportCourseGrades(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		CourseID   int    `json:"courseid"`
+		TemplateID string `json:"templateid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	course := s.fixtures.course(params.CourseID)
+	if course == nil {
+		return nil, missingRecord("course")
+	}
+	if params.TemplateID != "" && s.template("course", params.TemplateID) == nil {
+		return nil, missingRecord("local_customgradeexport_templates")
+	}
+
+	var b strings.Builder
+	b.WriteString("student")
+	for _, m := range course.Modules {
+		b.WriteString("," + m.Name)
+	}
+	b.WriteString("\n")
+	for _, id := range course.Students {
+		user := s.fixtures.user(id)
+		if user == nil {
+			continue
+		}
+		b.WriteString(user.Fullname())
+		for _, m := range course.Modules {
+			b.WriteString(",")
+			if grade, ok := course.Grades[GradeKey{id, m.Cmid, m.ItemNumber}]; ok {
+				fmt.Fprintf(&b, "%g", grade)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	return map[string]any{
+		"filename": course.Shortname + ".csv",
+		"mimetype": "text/csv",
+		"filedata": base64.StdEncoding.EncodeToString([]byte(b.String())),
+	}, nil
+}
+
+func uploadTemplate(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		Type     string `json:"type"`
+		Name     string `json:"name"`
+		Filename string `json:"filename"`
+		Filedata string `json:"filedata"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	data, err := base64.StdEncoding.DecodeString(params.Filedata)
+	if err != nil {
+		return nil, invalidParameter(err)
+	}
+	format := strings.ToLower(params.Filename[strings.LastIndex(params.Filename, ".")+1:])
+	if format != "docx" && format != "xlsx" {
+		return nil, invalidParameter(fmt.Errorf("unsupported file type %q", format))
+	}
+
+	s.nextToken++
+	t := Template{
+		ID:       fmt.Sprintf("tpl-%s-%d", params.Type, s.nextToken),
+		Type:     params.Type,
+		Name:     params.Name,
+		Format:   format,
+		Data:     data,
+		Modified: 1725148800 + int64(s.nextToken),
+	}
+	s.fixtures.Templates = append(s.fixtures.Templates, t)
+	slices.SortFunc(s.fixtures.Templates, func(a, b Template) int { return cmp.Compare(a.ID, b.ID) })
+	return map[string]any{"id": t.ID, "success": true}, nil
+}
+
+func deleteTemplate(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		Type       string `json:"type"`
+		TemplateID string `json:"templateid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	n := len(s.fixtures.Templates)
+	s.fixtures.Templates = slices.DeleteFunc(s.fixtures.Templates, func(t Template) bool {
+		return t.Type == params.Type && t.ID == params.TemplateID
+	})
+	return map[string]any{"success": len(s.fixtures.Templates) < n}, nil
+}
+
+func getTemplateContent(s *Server, body []byte) (any, *Exception) {
+	var params struct {
+		Type       string `json:"type"`
+		TemplateID string `json:"templateid"`
+	}
+	if exc := decode(body, &params); exc != nil {
+		return nil, exc
+	}
+	t := s.template(params.Type, params.TemplateID)
+	if t == nil {
+		return nil, missingRecord("local_customgradeexport_templates")
+	}
+	return map[string]any{
+		"name":     t.Name,
+		"format":   t.Format,
+		"filedata": base64.StdEncoding.EncodeToString(t.Data),
+	}, nil
+}
@@ -0,0 +1,384 @@
+// Package mdltest runs an in-process stand-in for Moodle, so that the mdlapi
+// providers and the endpoints built on them can be tested without a Moodle
+// site and its plugins. It serves every web service function the SMS calls
+// from seeded Fixtures, the OAuth2 login of local_oauth2, and can be told to
+// fail in the ways Moodle does.
+package mdltest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Token is the web service token the server accepts.
+	Token = "mdltest-token"
+	// ClientID and ClientSecret identify the SMS to the OAuth2 endpoints.
+	ClientID     = "sms-oauth2"
+	ClientSecret = "mdltest-secret"
+
+	// Paths as configured in AUTH_URL and TOKEN_URL.
+	AuthPath  = "/local/oauth2/login.php"
+	TokenPath = "/local/oauth2/token.php"
+
+	webServicePath = "/webservice/restful/server.php/"
+)
+
+// Server is a running stand-in Moodle. It is safe for concurrent use.
+type Server struct {
+	// URL is the base URL to configure as MOODLE_URL and ORIGIN_URL.
+	URL string
+
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	fixtures  *Fixtures
+	faults    map[string]*Fault
+	calls     map[string]int
+	loginAs   int
+	codes     map[string]authCode
+	tokens    map[string]int
+	nextToken int
+}
+
+type authCode struct {
+	userID      int
+	challenge   string
+	redirectURI string
+}
+
+// New starts a server on a random local port.
+func New(fixtures *Fixtures) *Server {
+	s := newServer(fixtures)
+	s.srv = httptest.NewServer(s.handler())
+	s.URL = s.srv.URL
+	return s
+}
+
+// Listen starts a server on addr, e.g. the host of a MOODLE_URL that was
+// configured before the server could be started.
+func Listen(addr string, fixtures *Fixtures) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("mdltest: listen: %w", err)
+	}
+	s := newServer(fixtures)
+	s.srv = httptest.NewUnstartedServer(s.handler())
+	s.srv.Listener.Close()
+	s.srv.Listener = l
+	s.srv.Start()
+	s.URL = s.srv.URL
+	return s, nil
+}
+
+func newServer(fixtures *Fixtures) *Server {
+	if fixtures == nil {
+		fixtures = Seed()
+	}
+	return &Server{
+		fixtures: fixtures.clone(),
+		faults:   map[string]*Fault{},
+		calls:    map[string]int{},
+		codes:    map[string]authCode{},
+		tokens:   map[string]int{},
+	}
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Reset restores fixtures, forgets faults, calls and tokens, and logs nobody
+// in. Tests sharing a server call it between cases.
+func (s *Server) Reset(fixtures *Fixtures) {
+	if fixtures == nil {
+		fixtures = Seed()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures = fixtures.clone()
+	s.faults = map[string]*Fault{}
+	s.calls = map[string]int{}
+	s.loginAs = 0
+	s.codes = map[string]authCode{}
+	s.tokens = map[string]int{}
+}
+
+// Calls returns how often a web service function was called, failed calls
+// included.
+func (s *Server) Calls(fn string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[fn]
+}
+
+// Grade returns a student's grade in a course, as stored by the server.
+func (s *Server) Grade(courseID int, key GradeKey) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	course := s.fixtures.course(courseID)
+	if course == nil {
+		return 0, false
+	}
+	grade, ok := course.Grades[key]
+	return grade, ok
+}
+
+// LoginAs makes the OAuth2 authorize endpoint log in the given user, as if
+// they had entered their password.
+func (s *Server) LoginAs(userID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loginAs = userID
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+webServicePath+"{fn}", s.serveFunction)
+	mux.HandleFunc("GET "+AuthPath, s.serveAuthorize)
+	mux.HandleFunc("POST "+TokenPath, s.serveToken)
+	return mux
+}
+
+// ── Web service ──────────────────────────────────────────────────────────────
+
+func (s *Server) serveFunction(w http.ResponseWriter, r *http.Request) {
+	fn := r.PathValue("fn")
+
+	s.mu.Lock()
+	s.calls[fn]++
+	fault := s.takeFault(fn)
+	s.mu.Unlock()
+
+	if fault != nil {
+		if !fault.apply(w, r) {
+			return
+		}
+	}
+
+	if r.Header.Get("Authorization") != Token {
+		writeException(w, &Exception{
+			Exception: "moodle_exception",
+			ErrorCode: "invalidtoken",
+			Message:   "Invalid token - token not found",
+		})
+		return
+	}
+
+	handle, ok := functions[fn]
+	if !ok {
+		writeException(w, &Exception{
+			Exception: "dml_missing_record_exception",
+			ErrorCode: "invalidrecord",
+			Message:   fmt.Sprintf("Can't find data record in database table external_functions. (%s)", fn),
+		})
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	resp, exc := handle(s, body)
+	s.mu.Unlock()
+	if exc != nil {
+		writeException(w, exc)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeException answers like Moodle does: with status 200.
+func writeException(w http.ResponseWriter, exc *Exception) {
+	writeJSON(w, exc)
+}
+
+// ── OAuth2 ───────────────────────────────────────────────────────────────────
+
+// serveAuthorize logs in the user set with LoginAs and redirects back with
+// an authorization code bound to the PKCE challenge.
+func (s *Server) serveAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != ClientID || q.Get("response_type") != "code" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || q.Get("redirect_uri") == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	userID := s.loginAs
+	if s.fixtures.user(userID) == nil {
+		s.mu.Unlock()
+		http.Error(w, "no user logged in, call LoginAs", http.StatusUnauthorized)
+		return
+	}
+	s.nextToken++
+	code := fmt.Sprintf("code-%d", s.nextToken)
+	s.codes[code] = authCode{userID: userID, challenge: q.Get("code_challenge"), redirectURI: redirectURI.String()}
+	s.mu.Unlock()
+
+	back := redirectURI.Query()
+	back.Set("code", code)
+	back.Set("state", q.Get("state"))
+	redirectURI.RawQuery = back.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, "invalid_request")
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != ClientID || secret != ClientSecret {
+		oauthError(w, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		oauthError(w, "unsupported_grant_type")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	if !ok || !verifyChallenge(r.PostForm.Get("code_verifier"), code.challenge) {
+		oauthError(w, "invalid_grant")
+		return
+	}
+
+	s.nextToken++
+	access := fmt.Sprintf("access-%d", s.nextToken)
+	s.tokens[access] = code.userID
+	writeJSON(w, map[string]any{
+		"access_token":  access,
+		"refresh_token": fmt.Sprintf("refresh-%d", s.nextToken),
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+	})
+}
+
+func verifyChallenge(verifier, challenge string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier != "" && base64.RawURLEncoding.EncodeToString(sum[:]) == challenge
+}
+
+func oauthError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+// ── Faults ───────────────────────────────────────────────────────────────────
+
+// AnyFunction makes a fault apply to every web service function.
+const AnyFunction = "*"
+
+// Fault makes calls of a function misbehave. Latency is added first; then
+// the call fails with Status, or with Exception, if set. A Fault without
+// either only slows calls down.
+type Fault struct {
+	Latency   time.Duration
+	Status    int
+	Exception *Exception
+	// Times limits the fault to the next Times calls; 0 means every call
+	// until ClearFaults.
+	Times int
+}
+
+// Exception is the body Moodle returns when a function raises an exception.
+type Exception struct {
+	Exception string `json:"exception"`
+	ErrorCode string `json:"errorcode"`
+	Message   string `json:"message"`
+	DebugInfo string `json:"debuginfo,omitempty"`
+}
+
+// InjectFault makes calls of fn, or of every function for AnyFunction,
+// misbehave. A fault for fn takes precedence over one for AnyFunction.
+func (s *Server) InjectFault(fn string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[fn] = &fault
+}
+
+// ClearFaults makes every function behave again.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = map[string]*Fault{}
+}
+
+// takeFault returns the fault for a call of fn and uses up one of its Times.
+// s.mu must be held.
+func (s *Server) takeFault(fn string) *Fault {
+	key := fn
+	fault, ok := s.faults[key]
+	if !ok {
+		key = AnyFunction
+		fault, ok = s.faults[key]
+	}
+	if !ok {
+		return nil
+	}
+	if fault.Times > 0 {
+		fault.Times--
+		if fault.Times == 0 {
+			delete(s.faults, key)
+		}
+	}
+	f := *fault
+	return &f
+}
+
+// apply injects the fault and reports whether the call should go on.
+func (f *Fault) apply(w http.ResponseWriter, r *http.Request) bool {
+	if f.Latency > 0 {
+		select {
+		case <-time.After(f.Latency):
+		case <-r.Context().Done():
+			return false
+		}
+	}
+	switch {
+	case f.Status != 0:
+		http.Error(w, strings.ToLower(http.StatusText(f.Status)), f.Status)
+		return false
+	case f.Exception != nil:
+		writeException(w, f.Exception)
+		return false
+	default:
+		return true
+	}
+}
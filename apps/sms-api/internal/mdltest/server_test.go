@@ -0,0 +1,206 @@
+package mdltest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func call(t *testing.T, s *Server, token, fn string, params any, out any) int {
+	t.Helper()
+	body, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.URL+webServicePath+fn, strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestWebService(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+
+	var exc Exception
+	call(t, s, "wrong", fnCourseData, map[string]int{"courseid": MathCourseID}, &exc)
+	if exc.ErrorCode != "invalidtoken" {
+		t.Fatalf("errorcode = %q for a wrong token, want invalidtoken", exc.ErrorCode)
+	}
+
+	var data struct {
+		Course struct {
+			ID       int `json:"id"`
+			Category int `json:"category"`
+		} `json:"course"`
+		Modules  []struct{ Cmid int } `json:"modules"`
+		Students []struct {
+			ID     int `json:"id"`
+			Grades []struct {
+				ModuleID   int     `json:"moduleid"`
+				ActivityID int     `json:"activityid"`
+				Grade      float64 `json:"grade"`
+			} `json:"grades"`
+		} `json:"students"`
+	}
+	call(t, s, Token, fnCourseData, map[string]int{"courseid": MathCourseID}, &data)
+	if data.Course.ID != MathCourseID || data.Course.Category != MathCategoryID {
+		t.Fatalf("course = %+v", data.Course)
+	}
+	if len(data.Modules) != 3 || len(data.Students) != 3 || len(data.Students[0].Grades) != 3 {
+		t.Fatalf("got %d modules, %d students", len(data.Modules), len(data.Students))
+	}
+
+	var ok int
+	call(t, s, Token, fnUpdateGrades, map[string]any{
+		"source": "mod/assign", "courseid": MathCourseID, "component": "mod_assign",
+		"activityid": MathFinalCmid, "itemnumber": 0,
+		"grades": []map[string]any{{"studentid": Student2ID, "grade": 6.5}},
+	}, &ok)
+	if ok != 0 {
+		t.Fatalf("update returned %d, want 0", ok)
+	}
+	if grade, _ := s.Grade(MathCourseID, GradeKey{Student2ID, MathFinalCmid, 0}); grade != 6.5 {
+		t.Fatalf("stored grade = %v, want 6.5", grade)
+	}
+	if s.Calls(fnCourseData) != 2 || s.Calls(fnUpdateGrades) != 1 {
+		t.Fatal("calls were not counted")
+	}
+
+	s.Reset(nil)
+	if _, found := s.Grade(MathCourseID, GradeKey{Student2ID, MathFinalCmid, 0}); found {
+		t.Fatal("Reset kept a written grade")
+	}
+	if Seed().course(MathCourseID).Grades[GradeKey{Student2ID, MathFinalCmid, 0}] != 0 {
+		t.Fatal("the server wrote to the seed")
+	}
+}
+
+func TestFaults(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	params := map[string]int{"userid": TeacherID}
+
+	s.InjectFault(fnTeacherCategories, Fault{Status: http.StatusBadGateway, Times: 2})
+	for i := 0; i < 2; i++ {
+		if status := call(t, s, Token, fnTeacherCategories, params, nil); status != http.StatusBadGateway {
+			t.Fatalf("call %d: status = %d, want 502", i, status)
+		}
+	}
+	var cats struct {
+		Totalcategories int `json:"totalcategories"`
+	}
+	call(t, s, Token, fnTeacherCategories, params, &cats)
+	if cats.Totalcategories != 2 {
+		t.Fatalf("totalcategories = %d after the fault ran out, want 2", cats.Totalcategories)
+	}
+
+	s.InjectFault(AnyFunction, Fault{Exception: &Exception{Exception: "moodle_exception", ErrorCode: "sitemaintenance"}})
+	var exc Exception
+	call(t, s, Token, fnAllCategories, nil, &exc)
+	if exc.ErrorCode != "sitemaintenance" {
+		t.Fatalf("errorcode = %q, want sitemaintenance", exc.ErrorCode)
+	}
+
+	s.ClearFaults()
+	s.InjectFault(fnAllCategories, Fault{Latency: 50 * time.Millisecond})
+	start := time.Now()
+	if status := call(t, s, Token, fnAllCategories, nil, nil); status != http.StatusOK {
+		t.Fatalf("status = %d for a slow call, want 200", status)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Fatal("latency was not injected")
+	}
+}
+
+func TestOAuth2(t *testing.T) {
+	s := New(nil)
+	defer s.Close()
+	s.LoginAs(TeacherID)
+
+	verifier := "a-verifier-long-enough-for-pkce-0123456789"
+	sum := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"client_id":             {ClientID},
+		"response_type":         {"code"},
+		"redirect_uri":          {"http://sms.test/callback"},
+		"state":                 {"xyz"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := noRedirect.Get(s.URL + AuthPath + "?" + q.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || back.Query().Get("state") != "xyz" || back.Query().Get("code") == "" {
+		t.Fatalf("redirected to %q", resp.Header.Get("Location"))
+	}
+
+	exchange := func(code, verifier string) (*http.Response, error) {
+		return http.PostForm(s.URL+TokenPath, url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {ClientID},
+			"client_secret": {ClientSecret},
+			"code":          {code},
+			"code_verifier": {verifier},
+		})
+	}
+	resp, err = exchange(back.Query().Get("code"), "wrong-verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d for a wrong verifier, want 400", resp.StatusCode)
+	}
+
+	// A code is spent by its first exchange, right or wrong.
+	resp, err = noRedirect.Get(s.URL + AuthPath + "?" + q.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, _ = url.Parse(resp.Header.Get("Location"))
+	resp, err = exchange(back.Query().Get("code"), verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil || tokens.AccessToken == "" {
+		t.Fatalf("no access token: %v", err)
+	}
+
+	var info struct {
+		UserID int    `json:"userid"`
+		Role   string `json:"role"`
+	}
+	call(t, s, Token, fnUserInfo, map[string]string{"accesstoken": tokens.AccessToken}, &info)
+	if info.UserID != TeacherID || info.Role != RoleTeacher {
+		t.Fatalf("user info = %+v, want the teacher", info)
+	}
+}
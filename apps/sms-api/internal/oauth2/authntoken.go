@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"encore.app/internal/config"
@@ -15,51 +16,55 @@ import (
 
 type appTokenProvider struct {
 	authnConfig *config.AuthnConfig
+	keys        *KeySet
 }
 
 type AppClaims struct {
 	*entities.TokenPayload
+	// Type keeps refresh tokens from being used as access tokens and vice
+	// versa, now that both are signed with the same keys.
+	Type TokenType `json:"typ"`
 	jwt.RegisteredClaims
 }
 
 var _ TokenProvider = (*appTokenProvider)(nil)
 
-func (a *appTokenProvider) genToken(
-	claims *AppClaims,
-	jwtSecret []byte,
-) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
-	if err != nil {
-		return "", err
-	}
-	return tokenString, nil
+func (a *appTokenProvider) genToken(claims *AppClaims) (string, error) {
+	return a.keys.sign(claims)
 }
 
-func (a *appTokenProvider) genAccessToken(payload *entities.TokenPayload) (string, error) {
-	authConfig := a.authnConfig
+func (a *appTokenProvider) genAccessToken(
+	payload *entities.TokenPayload,
+	ttl time.Duration,
+) (string, error) {
 	accessTokenClaims := &AppClaims{
 		payload,
+		TokenTypeAccess,
 		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(
-				time.Now().Add(time.Duration(authConfig.TokenExpire) * time.Hour),
-			),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "sms-api",
-			Subject:   string(payload.UserID),
+			Subject:   strconv.FormatInt(payload.UserID, 10),
 			ID:        helper.UUIDStr(),
 			Audience:  []string{"sms-web"},
 		},
 	}
 
-	return a.genToken(accessTokenClaims, []byte(authConfig.JWTSecret))
+	return a.genToken(accessTokenClaims)
 }
 
-func (a *appTokenProvider) genRefreshToken(payload *entities.TokenPayload) (string, error) {
+// genRefreshToken also returns the jti of the token: payload.TokenID when the
+// caller chose it, a new one otherwise.
+func (a *appTokenProvider) genRefreshToken(payload *entities.TokenPayload) (string, string, error) {
 	authConfig := a.authnConfig
+	tokenID := payload.TokenID
+	if tokenID == "" {
+		tokenID = helper.UUIDStr()
+	}
 	refreshTokenClaims := &AppClaims{
 		payload,
+		TokenTypeRefresh,
 		jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(
 				time.Now().Add(time.Duration(authConfig.RefreshTokenExpire) * time.Hour),
@@ -67,55 +72,69 @@ func (a *appTokenProvider) genRefreshToken(payload *entities.TokenPayload) (stri
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "sms-api",
-			Subject:   string(payload.UserID),
-			ID:        helper.UUIDStr(),
+			Subject:   strconv.FormatInt(payload.UserID, 10),
+			ID:        tokenID,
 			Audience:  []string{"sms-web"},
 		},
 	}
 
-	return a.genToken(refreshTokenClaims, []byte(authConfig.JWTRefreshToken))
+	token, err := a.genToken(refreshTokenClaims)
+	return token, tokenID, err
 }
 
 func (a *appTokenProvider) GenTokens(
 	ctx context.Context,
 	req *entities.TokenPayload,
 ) (*entities.CallbackResponse, error) {
-	accessToken, err := a.genAccessToken(req)
+	accessToken, err := a.genAccessToken(req, time.Duration(a.authnConfig.TokenExpire)*time.Hour)
 	if err != nil {
 		logger.ErrorContext(ctx, "AppTokenProvider.genAccessToken error", "err", err)
 		return nil, err
 	}
 
-	refreshToken, err := a.genRefreshToken(req)
+	refreshToken, refreshTokenID, err := a.genRefreshToken(req)
 	if err != nil {
 		logger.ErrorContext(ctx, "AppTokenProvider.genRefreshToken error", "err", err)
 		return nil, err
 	}
 
 	return &entities.CallbackResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:    accessToken,
+		RefreshToken:   refreshToken,
+		RefreshTokenID: refreshTokenID,
 	}, nil
 }
 
+func (a *appTokenProvider) GenAccessToken(
+	ctx context.Context,
+	req *entities.TokenPayload,
+	ttl time.Duration,
+) (string, error) {
+	accessToken, err := a.genAccessToken(req, ttl)
+	if err != nil {
+		logger.ErrorContext(ctx, "AppTokenProvider.genAccessToken error", "err", err)
+		return "", err
+	}
+	return accessToken, nil
+}
+
 func (a *appTokenProvider) Verify(
 	ctx context.Context,
 	req *VerifyRequest,
 ) (*entities.TokenPayload, error) {
-	token, err := jwt.ParseWithClaims(
-		req.TokenStr,
-		&AppClaims{},
-		func(token *jwt.Token) (any, error) {
-			return []byte(req.Secret), nil
-		},
-	)
+	token, err := jwt.ParseWithClaims(req.TokenStr, &AppClaims{}, a.keys.keyFunc)
 	if err != nil {
 		logger.ErrorContext(ctx, "Verify token error", "err", err)
 	}
 
 	switch {
-	case token.Valid:
-		if claims, ok := token.Claims.(*AppClaims); ok {
+	// jwt returns no token for input it cannot parse at all.
+	case err == nil && token != nil && token.Valid:
+		if claims, ok := token.Claims.(*AppClaims); ok && claims.TokenPayload != nil {
+			if claims.Type != req.Type {
+				return nil, fmt.Errorf("Token is not a %s token", req.Type)
+			}
+			claims.TokenPayload.TokenID = claims.ID
 			return claims.TokenPayload, nil
 		}
 
@@ -131,6 +150,10 @@ func (a *appTokenProvider) Verify(
 	}
 }
 
-func NewAppTokenProvider(authnConfig *config.AuthnConfig) *appTokenProvider {
-	return &appTokenProvider{authnConfig: authnConfig}
+func (a *appTokenProvider) JWKS() *JWKS {
+	return a.keys.JWKS()
+}
+
+func NewAppTokenProvider(authnConfig *config.AuthnConfig, keys *KeySet) *appTokenProvider {
+	return &appTokenProvider{authnConfig: authnConfig, keys: keys}
 }
@@ -0,0 +1,16 @@
+package oauth2
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVerifyRejectsMalformedTokens(t *testing.T) {
+	p := NewAppTokenProvider(nil, &KeySet{})
+	for _, tokenStr := range []string{"", "garbage", "a.b", "a.b.c"} {
+		payload, err := p.Verify(context.Background(), &VerifyRequest{TokenStr: tokenStr, Type: TokenTypeAccess})
+		if err == nil || payload != nil {
+			t.Errorf("Verify(%q) = %v, %v, want an error", tokenStr, payload, err)
+		}
+	}
+}
@@ -32,6 +32,16 @@ func (r *redisTokenRepository) Get(ctx context.Context, key string) (string, err
 	return val, nil
 }
 
+func (r *redisTokenRepository) GetDel(ctx context.Context, key string) (string, error) {
+	val, err := r.rdb.GetDel(ctx, key).Result()
+	if err != nil {
+		logger.ErrorContext(ctx, "RedisTokenRepository.GetDel error", "err", err, "key", key)
+		return "", err
+	}
+
+	return val, nil
+}
+
 func NewOauth2Repository(rdb *redis.Client) *redisTokenRepository {
 	return &redisTokenRepository{rdb: rdb}
 }
@@ -56,6 +56,12 @@ func (p *DBUserInfoProvider) GetUserInfoByMdlToken(
 	if mdlUser.Description != nil {
 		userInfo.Description = *mdlUser.Description
 	}
+	if mdlUser.Suspended != nil {
+		userInfo.Suspended = *mdlUser.Suspended
+	}
+	if mdlUser.Deleted != nil {
+		userInfo.Deleted = *mdlUser.Deleted
+	}
 
 	return userInfo, nil
 }
@@ -86,6 +92,12 @@ func (p *DBUserInfoProvider) GetUserInfo(
 	if mdlUser.Description != nil {
 		userInfo.Description = *mdlUser.Description
 	}
+	if mdlUser.Suspended != nil {
+		userInfo.Suspended = *mdlUser.Suspended
+	}
+	if mdlUser.Deleted != nil {
+		userInfo.Deleted = *mdlUser.Deleted
+	}
 
 	return userInfo, nil
 }
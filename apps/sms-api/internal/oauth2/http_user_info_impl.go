@@ -32,6 +32,19 @@ func mapRole(resp *mdlapi.GetUserInfoResponse) entities.UserRole {
 	return entities.RoleStudent
 }
 
+func toUserInfo(resp *mdlapi.GetUserInfoResponse) *entities.UserInfo {
+	return &entities.UserInfo{
+		Id:        int64(resp.UserID),
+		Email:     resp.Email,
+		Firstname: resp.FirstName,
+		Idnumber:  resp.IdNumber,
+		Lastname:  resp.LastName,
+		Role:      mapRole(resp),
+		Suspended: resp.Suspended != 0,
+		Deleted:   resp.Deleted != 0,
+	}
+}
+
 // GetUserInfo implements UserInfoProvider.
 func (p *HTTPUserInfoProvider) GetUserInfo(
 	ctx context.Context,
@@ -46,14 +59,7 @@ func (p *HTTPUserInfoProvider) GetUserInfo(
 		return nil, err
 	}
 
-	return &entities.UserInfo{
-		Id:        int64(resp.UserID),
-		Email:     resp.Email,
-		Firstname: resp.FirstName,
-		Idnumber:  resp.IdNumber,
-		Lastname:  resp.LastName,
-		Role:      mapRole(resp),
-	}, nil
+	return toUserInfo(resp), nil
 }
 
 // GetUserInfoByMdlToken implements UserInfoProvider.
@@ -69,12 +75,5 @@ func (p *HTTPUserInfoProvider) GetUserInfoByMdlToken(
 		return nil, err
 	}
 
-	return &entities.UserInfo{
-		Id:        int64(resp.UserID),
-		Email:     resp.Email,
-		Firstname: resp.FirstName,
-		Idnumber:  resp.IdNumber,
-		Lastname:  resp.LastName,
-		Role:      mapRole(resp),
-	}, nil
+	return toUserInfo(resp), nil
 }
@@ -0,0 +1,194 @@
+package oauth2
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"encore.app/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// devKID names the ephemeral key generated when no keys are configured.
+const devKID = "dev"
+
+// KeySet holds the keys that verify our JWTs and the one that signs them.
+type KeySet struct {
+	signingKID string
+	signer     crypto.Signer
+	keys       map[string]crypto.PublicKey
+	// kids lists the keys in the order they are published.
+	kids []string
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet reads the keys of cfg.JWTKeysDir. Without a directory it
+// generates an ephemeral Ed25519 key, so tokens do not survive a restart;
+// config validation forbids this in prod.
+func LoadKeySet(cfg *config.AuthnConfig) (*KeySet, error) {
+	ks := &KeySet{keys: map[string]crypto.PublicKey{}}
+	if cfg.JWTKeysDir == "" {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		ks.add(devKID, key.Public())
+		ks.signingKID, ks.signer = devKID, key
+		return ks, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.JWTKeysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	signers := map[string]crypto.Signer{}
+	var lastSigner string
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		pub, signer, err := parseKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+		}
+		ks.add(kid, pub)
+		if signer != nil {
+			signers[kid] = signer
+			lastSigner = kid
+		}
+	}
+
+	ks.signingKID = cfg.JWTSigningKID
+	if ks.signingKID == "" {
+		ks.signingKID = lastSigner
+	}
+	ks.signer = signers[ks.signingKID]
+	if ks.signer == nil {
+		return nil, fmt.Errorf("no private jwt key %q in %s", ks.signingKID, cfg.JWTKeysDir)
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) add(kid string, pub crypto.PublicKey) {
+	ks.keys[kid] = pub
+	ks.kids = append(ks.kids, kid)
+}
+
+// parseKey decodes a PKCS#8 private key or a PKIX public key. signer is nil
+// for public keys.
+func parseKey(data []byte) (crypto.PublicKey, crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("no PEM block")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok || signingMethod(signer.Public()) == nil {
+			return nil, nil, fmt.Errorf("unsupported private key %T", key)
+		}
+		return signer.Public(), signer, nil
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if signingMethod(pub) == nil {
+			return nil, nil, fmt.Errorf("unsupported public key %T", pub)
+		}
+		return pub, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// signingMethod returns the JWT algorithm of a key, or nil if unsupported.
+func signingMethod(pub crypto.PublicKey) jwt.SigningMethod {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA
+	default:
+		return nil
+	}
+}
+
+// sign signs claims with the current signing key and names it in the kid
+// header.
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(signingMethod(ks.signer.Public()), claims)
+	token.Header["kid"] = ks.signingKID
+	return token.SignedString(ks.signer)
+}
+
+// keyFunc selects the verification key by the token's kid and makes sure the
+// token's algorithm is the key's.
+func (ks *KeySet) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	pub, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if token.Method != signingMethod(pub) {
+		return nil, fmt.Errorf("unexpected signing method %s for kid %q", token.Method.Alg(), kid)
+	}
+	return pub, nil
+}
+
+// JWKS returns the public keys for other services to verify our tokens.
+func (ks *KeySet) JWKS() *JWKS {
+	doc := &JWKS{Keys: make([]JWK, 0, len(ks.kids))}
+	for _, kid := range ks.kids {
+		jwk := JWK{Kid: kid, Use: "sig"}
+		switch pub := ks.keys[kid].(type) {
+		case *rsa.PublicKey:
+			jwk.Kty, jwk.Alg = "RSA", jwt.SigningMethodRS256.Alg()
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Alg, jwk.Crv = "OKP", jwt.SigningMethodEdDSA.Alg(), "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		doc.Keys = append(doc.Keys, jwk)
+	}
+	return doc
+}
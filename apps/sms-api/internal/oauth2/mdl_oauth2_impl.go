@@ -5,6 +5,7 @@ import (
 
 	"encore.app/internal/config"
 	"encore.app/internal/entities"
+	"golang.org/x/oauth2"
 )
 
 var _ OAuth2Provider = (*MoodleOauth2Provider)(nil)
@@ -17,17 +18,17 @@ func NewMoodleOauth2Provider(cfg *config.Config) *MoodleOauth2Provider {
 	return &MoodleOauth2Provider{cfg}
 }
 
-func (p *MoodleOauth2Provider) GetAuthURL(state string) string {
+func (p *MoodleOauth2Provider) GetAuthURL(state, verifier string) string {
 	oauth2Cfg := p.config.GetOauth2Config()
-	return oauth2Cfg.AuthCodeURL(state)
+	return oauth2Cfg.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
 }
 
 func (p *MoodleOauth2Provider) ExchangeCodeForToken(
 	ctx context.Context,
-	code string,
+	code, verifier string,
 ) (*entities.OAuth2Token, error) {
 	oauth2Cfg := p.config.GetOauth2Config()
-	token, err := oauth2Cfg.Exchange(ctx, code)
+	token, err := oauth2Cfg.Exchange(ctx, code, oauth2.VerifierOption(verifier))
 	if err != nil {
 		return nil, err
 	}
@@ -6,7 +6,9 @@ import (
 	"encore.app/internal/entities"
 )
 
+// OAuth2Provider runs the authorization code flow with PKCE: the verifier
+// passed to ExchangeCodeForToken must be the one GetAuthURL was called with.
 type OAuth2Provider interface {
-	GetAuthURL(state string) string
-	ExchangeCodeForToken(ctx context.Context, code string) (*entities.OAuth2Token, error)
+	GetAuthURL(state, verifier string) string
+	ExchangeCodeForToken(ctx context.Context, code, verifier string) (*entities.OAuth2Token, error)
 }
@@ -14,4 +14,7 @@ type SaveRequest struct {
 type Repository interface {
 	SetEx(context.Context, *SaveRequest) error
 	Get(context.Context, string) (string, error)
+	// GetDel returns the value of a key and deletes it, so that the value can
+	// be consumed only once.
+	GetDel(context.Context, string) (string, error)
 }
@@ -0,0 +1,49 @@
+package oauth2
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrInvalidState is returned by ParseState for a state value that was not
+// issued by NewState with the same secret.
+var ErrInvalidState = errors.New("invalid oauth2 state")
+
+// NewState returns a random state ID and the signed state value sent to the
+// authorization server. The ID is where the login attempt is stored; the
+// signature lets forged values be rejected without a lookup.
+func NewState(secret string) (id, state string) {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	id = base64.RawURLEncoding.EncodeToString(b)
+	return id, id + "." + stateSignature(secret, id)
+}
+
+// ParseState checks the signature of a state value and returns its ID.
+func ParseState(secret, state string) (string, error) {
+	id, sig, ok := strings.Cut(state, ".")
+	if !ok || id == "" {
+		return "", ErrInvalidState
+	}
+	if !hmac.Equal([]byte(sig), []byte(stateSignature(secret, id))) {
+		return "", ErrInvalidState
+	}
+	return id, nil
+}
+
+func stateSignature(secret, id string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewVerifier returns a random PKCE code verifier.
+func NewVerifier() string {
+	return oauth2.GenerateVerifier()
+}
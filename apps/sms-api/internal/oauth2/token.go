@@ -2,16 +2,32 @@ package oauth2
 
 import (
 	"context"
+	"time"
 
 	"encore.app/internal/entities"
 )
 
+// TokenType tells access and refresh tokens apart.
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+)
+
 type VerifyRequest struct {
 	TokenStr string
-	Secret   string
+	Type     TokenType
 }
 
 type TokenProvider interface {
+	// GenTokens issues an access and a refresh token. The refresh token's jti
+	// is the payload's TokenID when set, a new one otherwise.
 	GenTokens(context.Context, *entities.TokenPayload) (*entities.CallbackResponse, error)
+	// GenAccessToken issues an access token alone, valid for ttl, for
+	// sessions that cannot be refreshed.
+	GenAccessToken(context.Context, *entities.TokenPayload, time.Duration) (string, error)
 	Verify(context.Context, *VerifyRequest) (*entities.TokenPayload, error)
+	// JWKS returns the public keys that verify the tokens.
+	JWKS() *JWKS
 }
@@ -303,31 +303,81 @@ func (p *Pool) SubmitWithTimeout(task Task, timeout time.Duration) (<-chan TaskR
 		timeout: timeout,
 	}
 
-	if p.config.EnableMetrics {
-		atomic.AddInt64(&p.metrics.TasksSubmitted, 1)
-		atomic.AddInt64(&p.metrics.QueuedTasks, 1)
+	if err := p.tryEnqueue(wrapper); err != nil {
+		return nil, err
+	}
+	return wrapper.result, nil
+}
+
+// SubmitWait submits a task like Submit, but when the queue is full it waits
+// for room until ctx is done instead of failing with ErrPoolFull.
+func (p *Pool) SubmitWait(ctx context.Context, task Task) (<-chan TaskResult, error) {
+	if atomic.LoadInt64(&p.closed) == 1 {
+		return nil, ErrPoolClosed
+	}
+
+	if task == nil {
+		return nil, errors.New("task cannot be nil")
+	}
+
+	taskCtx, cancel := context.WithTimeout(p.ctx, p.config.TaskTimeout)
+	wrapper := taskWrapper{
+		id:      fmt.Sprintf("task-%d", time.Now().UnixNano()),
+		task:    task,
+		ctx:     taskCtx,
+		cancel:  cancel,
+		result:  make(chan TaskResult, 1),
+		timeout: p.config.TaskTimeout,
+	}
+
+	if err := p.enqueueWait(ctx, wrapper); err != nil {
+		return nil, err
 	}
+	return wrapper.result, nil
+}
 
+// tryEnqueue puts a task on the queue, or fails at once with ErrPoolFull when
+// the queue is full.
+func (p *Pool) tryEnqueue(wrapper taskWrapper) error {
+	p.countQueued(1)
 	select {
 	case p.tasks <- wrapper:
-		return wrapper.result, nil
+		return nil
 	case <-p.ctx.Done():
-		cancel()
-		if p.config.EnableMetrics {
-			atomic.AddInt64(&p.metrics.TasksSubmitted, -1)
-			atomic.AddInt64(&p.metrics.QueuedTasks, -1)
-		}
-		return nil, ErrPoolClosed
+		return p.dropQueued(wrapper, ErrPoolClosed)
 	default:
-		cancel()
-		if p.config.EnableMetrics {
-			atomic.AddInt64(&p.metrics.TasksSubmitted, -1)
-			atomic.AddInt64(&p.metrics.QueuedTasks, -1)
-		}
-		return nil, ErrPoolFull
+		return p.dropQueued(wrapper, ErrPoolFull)
+	}
+}
+
+// enqueueWait puts a task on the queue, waiting for room until ctx is done.
+func (p *Pool) enqueueWait(ctx context.Context, wrapper taskWrapper) error {
+	p.countQueued(1)
+	select {
+	case p.tasks <- wrapper:
+		return nil
+	case <-p.ctx.Done():
+		return p.dropQueued(wrapper, ErrPoolClosed)
+	case <-ctx.Done():
+		return p.dropQueued(wrapper, ctx.Err())
+	}
+}
+
+func (p *Pool) countQueued(delta int64) {
+	if p.config.EnableMetrics {
+		atomic.AddInt64(&p.metrics.TasksSubmitted, delta)
+		atomic.AddInt64(&p.metrics.QueuedTasks, delta)
 	}
 }
 
+// dropQueued undoes the accounting of a task that did not make it onto the
+// queue and returns err.
+func (p *Pool) dropQueued(wrapper taskWrapper, err error) error {
+	wrapper.cancel()
+	p.countQueued(-1)
+	return err
+}
+
 // SubmitFunc is a convenience method for submitting function tasks
 func (p *Pool) SubmitFunc(fn func(ctx context.Context) error) (<-chan TaskResult, error) {
 	return p.Submit(TaskFunc(fn))
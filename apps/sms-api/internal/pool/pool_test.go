@@ -103,6 +103,62 @@ func TestQueueFullError(t *testing.T) {
 	}
 }
 
+func TestSubmitWaitBlocksUntilQueueHasRoom(t *testing.T) {
+	config := &pool.PoolConfig{
+		MaxWorkers:        1,
+		QueueSize:         1,
+		WorkerIdleTimeout: 1 * time.Second,
+		TaskTimeout:       5 * time.Second,
+		EnableMetrics:     true,
+	}
+
+	p := pool.New(config)
+	p.Start()
+	defer p.Close()
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	blockingTask := pool.TaskFunc(func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+
+	// Occupy the worker, then fill the queue
+	if _, err := p.Submit(blockingTask); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-started
+	if _, err := p.Submit(blockingTask); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if _, err := p.Submit(blockingTask); err != pool.ErrPoolFull {
+		t.Fatalf("Expected ErrPoolFull, got %v", err)
+	}
+
+	// A context that ends before the queue drains gives up
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.SubmitWait(ctx, blockingTask); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// Otherwise the task is queued once a worker frees a slot
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+	resultCh, err := p.SubmitWait(context.Background(), &mockTask{id: "waiting-task"})
+	if err != nil {
+		t.Fatalf("SubmitWait failed: %v", err)
+	}
+	select {
+	case result := <-resultCh:
+		if result.Error != nil {
+			t.Errorf("Waiting task failed: %v", result.Error)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Waiting task timed out")
+	}
+}
+
 func TestMetrics(t *testing.T) {
 	config := pool.DefaultConfig()
 	config.EnableMetrics = true
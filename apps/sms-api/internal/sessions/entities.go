@@ -0,0 +1,41 @@
+package sessions
+
+import (
+	"errors"
+	"time"
+
+	"encore.app/internal/entities"
+)
+
+var (
+	// ErrNotFound is returned when a session does not exist, has expired or
+	// has been revoked.
+	ErrNotFound = errors.New("session not found")
+	// ErrTokenReused is returned by Rotate when the presented refresh token is
+	// not the latest one issued for the session.
+	ErrTokenReused = errors.New("refresh token reused")
+)
+
+// Session is one login of a user, from the OAuth2 callback until it expires or
+// is revoked. Every token issued for it carries its ID in the "sid" claim.
+type Session struct {
+	ID     string            `json:"id"`
+	UserID int64             `json:"user_id"`
+	Role   entities.UserRole `json:"role"`
+	// RefreshTokenID is the jti of the only refresh token that may be
+	// exchanged. It changes on every refresh.
+	RefreshTokenID string `json:"-"`
+	// Impersonator is the admin who started the session to act as UserID.
+	Impersonator int64     `json:"impersonator,omitempty"`
+	UserAgent    string    `json:"user_agent"`
+	IP           string    `json:"ip"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastSeenAt   time.Time `json:"last_seen_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Client describes the device a session was started from.
+type Client struct {
+	UserAgent string
+	IP        string
+}
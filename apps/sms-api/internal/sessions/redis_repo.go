@@ -0,0 +1,275 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"encore.app/internal/entities"
+	"encore.app/internal/helper"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix     = "sessions:"
+	userKeyPrefix = "sessions:user:"
+)
+
+// Hash fields of a session.
+const (
+	fieldUserID         = "user_id"
+	fieldRole           = "role"
+	fieldRefreshTokenID = "refresh_token_id"
+	fieldImpersonator   = "impersonator"
+	fieldUserAgent      = "user_agent"
+	fieldIP             = "ip"
+	fieldCreatedAt      = "created_at"
+	fieldLastSeenAt     = "last_seen_at"
+	fieldExpiresAt      = "expires_at"
+)
+
+// extendIndexScript pushes the expiry of a user's session index (KEYS[1]) to
+// ARGV[1] seconds from now unless it already lives longer, so the index never
+// expires before the sessions it lists.
+var extendIndexScript = redis.NewScript(`
+local ttl = redis.call('TTL', KEYS[1])
+if ttl < tonumber(ARGV[1]) then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// rotateScript swaps the refresh token ID atomically so that two requests
+// presenting the same refresh token cannot both succeed.
+//
+// KEYS[1] session key; ARGV old token ID, new token ID, role, now and expiry
+// (unix).
+// The user's session index is extended by the caller.
+// Returns 1 on success, 0 when the old token ID is stale, -1 when the session
+// does not exist.
+var rotateScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'refresh_token_id')
+if not current then
+	return -1
+end
+if current ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'refresh_token_id', ARGV[2], 'role', ARGV[3], 'last_seen_at', ARGV[4], 'expires_at', ARGV[5])
+redis.call('EXPIREAT', KEYS[1], ARGV[5])
+return 1
+`)
+
+// touchScript updates last_seen_at of an existing session (KEYS[1]) when it is
+// more than ARGV[2] seconds older than ARGV[1] (unix). Returns 1 when the
+// session exists, 0 otherwise.
+var touchScript = redis.NewScript(`
+local last = redis.call('HGET', KEYS[1], 'last_seen_at')
+if not last then
+	return 0
+end
+if tonumber(ARGV[1]) - tonumber(last) > tonumber(ARGV[2]) then
+	redis.call('HSET', KEYS[1], 'last_seen_at', ARGV[1])
+end
+return 1
+`)
+
+// touchInterval throttles last-seen writes to one per session and minute.
+const touchInterval = time.Minute
+
+type redisRepository struct {
+	rdb *redis.Client
+}
+
+var _ Repository = (*redisRepository)(nil)
+
+func NewRedisRepository(rdb *redis.Client) *redisRepository {
+	return &redisRepository{rdb: rdb}
+}
+
+func sessionKey(id string) string   { return keyPrefix + id }
+func userKey(userID int64) string   { return userKeyPrefix + strconv.FormatInt(userID, 10) }
+func unixString(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+
+func parseUnix(s string) time.Time {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return time.Unix(n, 0)
+}
+
+// extendIndex keeps the user's session index alive until expiresAt.
+func (r *redisRepository) extendIndex(ctx context.Context, userID int64, expiresAt time.Time) error {
+	seconds := int64(time.Until(expiresAt).Seconds()) + 1
+	return extendIndexScript.Run(ctx, r.rdb, []string{userKey(userID)}, seconds).Err()
+}
+
+func (r *redisRepository) Create(ctx context.Context, s *Session) error {
+	key := sessionKey(s.ID)
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key,
+			fieldUserID, s.UserID,
+			fieldRole, string(s.Role),
+			fieldRefreshTokenID, s.RefreshTokenID,
+			fieldImpersonator, s.Impersonator,
+			fieldUserAgent, s.UserAgent,
+			fieldIP, s.IP,
+			fieldCreatedAt, unixString(s.CreatedAt),
+			fieldLastSeenAt, unixString(s.LastSeenAt),
+			fieldExpiresAt, unixString(s.ExpiresAt),
+		)
+		pipe.ExpireAt(ctx, key, s.ExpiresAt)
+		pipe.SAdd(ctx, userKey(s.UserID), s.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("sessions: create: %w", err)
+	}
+	// Expired sessions stay listed in the index until it is read or expires.
+	if err := r.extendIndex(ctx, s.UserID, s.ExpiresAt); err != nil {
+		return fmt.Errorf("sessions: create: %w", err)
+	}
+	return nil
+}
+
+func (r *redisRepository) Get(ctx context.Context, id string) (*Session, error) {
+	fields, err := r.rdb.HGetAll(ctx, sessionKey(id)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("sessions: get: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, ErrNotFound
+	}
+
+	userID, _ := strconv.ParseInt(fields[fieldUserID], 10, 64)
+	// Sessions created before impersonation existed have no such field.
+	impersonator, _ := strconv.ParseInt(fields[fieldImpersonator], 10, 64)
+	return &Session{
+		ID:             id,
+		UserID:         userID,
+		Role:           entities.UserRole(fields[fieldRole]),
+		RefreshTokenID: fields[fieldRefreshTokenID],
+		Impersonator:   impersonator,
+		UserAgent:      fields[fieldUserAgent],
+		IP:             fields[fieldIP],
+		CreatedAt:      parseUnix(fields[fieldCreatedAt]),
+		LastSeenAt:     parseUnix(fields[fieldLastSeenAt]),
+		ExpiresAt:      parseUnix(fields[fieldExpiresAt]),
+	}, nil
+}
+
+func (r *redisRepository) Touch(ctx context.Context, id string) (bool, error) {
+	n, err := touchScript.Run(ctx, r.rdb, []string{sessionKey(id)},
+		unixString(time.Now()), int64(touchInterval.Seconds()),
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("sessions: touch: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (r *redisRepository) ListByUser(ctx context.Context, userID int64) ([]Session, error) {
+	ids, err := r.rdb.SMembers(ctx, userKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("sessions: list: %w", err)
+	}
+
+	list := make([]Session, 0, len(ids))
+	var stale []any
+	for _, id := range ids {
+		s, err := r.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			stale = append(stale, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *s)
+	}
+	if len(stale) > 0 {
+		if err := r.rdb.SRem(ctx, userKey(userID), stale...).Err(); err != nil {
+			return nil, fmt.Errorf("sessions: list: %w", err)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list, nil
+}
+
+func (r *redisRepository) Rotate(
+	ctx context.Context,
+	id, oldTokenID, newTokenID string,
+	role entities.UserRole,
+	expiresAt time.Time,
+) error {
+	res, err := rotateScript.Run(ctx, r.rdb, []string{sessionKey(id)},
+		oldTokenID, newTokenID, string(role), unixString(time.Now()), unixString(expiresAt),
+	).Int()
+	if err != nil {
+		return fmt.Errorf("sessions: rotate: %w", err)
+	}
+	switch res {
+	case 1:
+		userID, err := r.rdb.HGet(ctx, sessionKey(id), fieldUserID).Int64()
+		if err != nil {
+			return fmt.Errorf("sessions: rotate: %w", err)
+		}
+		if err := r.extendIndex(ctx, userID, expiresAt); err != nil {
+			return fmt.Errorf("sessions: rotate: %w", err)
+		}
+		return nil
+	case 0:
+		return ErrTokenReused
+	default:
+		return ErrNotFound
+	}
+}
+
+func (r *redisRepository) Delete(ctx context.Context, id string) error {
+	userID, err := r.rdb.HGet(ctx, sessionKey(id), fieldUserID).Int64()
+	if helper.IsKeyDoesNotExistErr(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("sessions: delete: %w", err)
+	}
+
+	_, err = r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionKey(id))
+		pipe.SRem(ctx, userKey(userID), id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("sessions: delete: %w", err)
+	}
+	return nil
+}
+
+func (r *redisRepository) DeleteByUser(ctx context.Context, userID int64) (int, error) {
+	ids, err := r.rdb.SMembers(ctx, userKey(userID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("sessions: delete by user: %w", err)
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = sessionKey(id)
+	}
+	var deleted *redis.IntCmd
+	_, err = r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(keys) > 0 {
+			deleted = pipe.Del(ctx, keys...)
+		}
+		pipe.Del(ctx, userKey(userID))
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("sessions: delete by user: %w", err)
+	}
+	if deleted == nil {
+		return 0, nil
+	}
+	return int(deleted.Val()), nil
+}
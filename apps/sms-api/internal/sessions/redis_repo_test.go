@@ -0,0 +1,97 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"encore.app/internal/entities"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRepo(t *testing.T) *redisRepository {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewRedisRepository(rdb)
+}
+
+func createSession(t *testing.T, r *redisRepository, id, tokenID string) {
+	t.Helper()
+	now := time.Now()
+	err := r.Create(context.Background(), &Session{
+		ID:             id,
+		UserID:         42,
+		Role:           entities.RoleTeacher,
+		RefreshTokenID: tokenID,
+		CreatedAt:      now,
+		LastSeenAt:     now,
+		ExpiresAt:      now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	createSession(t, r, "s1", "jti-1")
+	expiresAt := time.Now().Add(2 * time.Hour)
+
+	if err := r.Rotate(ctx, "s1", "jti-1", "jti-2", entities.RoleManager, expiresAt); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	s, err := r.Get(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.RefreshTokenID != "jti-2" || s.Role != entities.RoleManager || s.ExpiresAt.Unix() != expiresAt.Unix() {
+		t.Errorf("after rotation got token %q, role %q, expiry %v", s.RefreshTokenID, s.Role, s.ExpiresAt)
+	}
+
+	// The newest token can be rotated again.
+	if err := r.Rotate(ctx, "s1", "jti-2", "jti-3", entities.RoleManager, expiresAt); err != nil {
+		t.Fatalf("second Rotate: %v", err)
+	}
+}
+
+func TestRotateDetectsReuse(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo(t)
+	createSession(t, r, "s1", "jti-1")
+	expiresAt := time.Now().Add(time.Hour)
+
+	if err := r.Rotate(ctx, "s1", "jti-1", "jti-2", entities.RoleTeacher, expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	err := r.Rotate(ctx, "s1", "jti-1", "jti-3", entities.RoleTeacher, expiresAt)
+	if !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("Rotate with a spent token = %v, want ErrTokenReused", err)
+	}
+
+	// The reused token must not have replaced the current one.
+	s, err := r.Get(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.RefreshTokenID != "jti-2" {
+		t.Errorf("refresh token = %q, want jti-2", s.RefreshTokenID)
+	}
+}
+
+func TestRotateMissingSession(t *testing.T) {
+	r := newTestRepo(t)
+	createSession(t, r, "s1", "jti-1")
+	if err := r.Delete(context.Background(), "s1"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := r.Rotate(context.Background(), "s1", "jti-1", "jti-2", entities.RoleTeacher, time.Now().Add(time.Hour))
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Rotate on a revoked session = %v, want ErrNotFound", err)
+	}
+}
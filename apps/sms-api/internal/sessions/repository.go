@@ -0,0 +1,34 @@
+package sessions
+
+import (
+	"context"
+	"time"
+
+	"encore.app/internal/entities"
+)
+
+// Repository stores sessions until their ExpiresAt. Deleting a session revokes
+// every token issued for it.
+type Repository interface {
+	Create(ctx context.Context, s *Session) error
+	Get(ctx context.Context, id string) (*Session, error)
+	// Touch reports whether the session is still active and records it as
+	// seen now. LastSeenAt is only written once per minute.
+	Touch(ctx context.Context, id string) (bool, error)
+	// ListByUser returns the active sessions of a user, oldest first.
+	ListByUser(ctx context.Context, userID int64) ([]Session, error)
+	// Rotate replaces the session's refresh token ID with newTokenID if it is
+	// still oldTokenID, records the user's current role and extends the
+	// session to expiresAt. It returns ErrTokenReused when oldTokenID has
+	// already been rotated out.
+	Rotate(
+		ctx context.Context,
+		id, oldTokenID, newTokenID string,
+		role entities.UserRole,
+		expiresAt time.Time,
+	) error
+	Delete(ctx context.Context, id string) error
+	// DeleteByUser revokes every session of a user and returns how many were
+	// active.
+	DeleteByUser(ctx context.Context, userID int64) (int, error)
+}
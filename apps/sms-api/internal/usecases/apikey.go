@@ -0,0 +1,173 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"encore.app/internal/apikeys"
+	"encore.app/internal/authz"
+	"encore.app/internal/entities"
+	"encore.app/internal/logger"
+	"encore.dev/beta/errs"
+)
+
+// nonDelegableScopes are permissions an API key cannot hold: they act on
+// users, or on who may do what, and stay with people.
+var nonDelegableScopes = []authz.Permission{
+	authz.PermCoursesAccessOwn,
+	authz.PermSessionsManage,
+	authz.PermUsersImpersonate,
+	authz.PermPermissionsManage,
+	authz.PermAPIKeysManage,
+}
+
+// APIKeyUseCase manages the API keys of service accounts and authenticates
+// requests made with them.
+type APIKeyUseCase struct {
+	repo apikeys.Repository
+}
+
+func NewAPIKeyUseCase(repo apikeys.Repository) *APIKeyUseCase {
+	return &APIKeyUseCase{repo: repo}
+}
+
+// CreateKeyParams describes a new key. A nil ExpiresAt never expires.
+type CreateKeyParams struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		perm := authz.Permission(scope)
+		if !perm.Valid() {
+			return fmt.Errorf("invalid scope: %q", scope)
+		}
+		if slices.Contains(nonDelegableScopes, perm) {
+			return fmt.Errorf("scope %q cannot be granted to an API key", scope)
+		}
+	}
+	return nil
+}
+
+// Create stores a new key and returns it with its secret, which is not
+// stored and cannot be shown again.
+func (uc *APIKeyUseCase) Create(
+	ctx context.Context,
+	actorID int64,
+	params *CreateKeyParams,
+) (*apikeys.Key, string, error) {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return nil, "", &errs.Error{Code: errs.InvalidArgument, Message: "name is required"}
+	}
+	if err := validateScopes(params.Scopes); err != nil {
+		return nil, "", &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(time.Now()) {
+		return nil, "", &errs.Error{Code: errs.InvalidArgument, Message: "expires_at must be in the future"}
+	}
+
+	secret, prefix, hash := apikeys.Generate()
+	key := &apikeys.Key{
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(params.Scopes))),
+		CreatedBy: actorID,
+		CreatedAt: time.Now(),
+		ExpiresAt: params.ExpiresAt,
+	}
+	if err := uc.repo.Create(ctx, key); err != nil {
+		logger.ErrorContext(ctx, "Failed to create API key", "err", err, "name", name)
+		return nil, "", err
+	}
+
+	logger.InfoContext(ctx, "API key created", "keyId", key.ID, "prefix", prefix, "by", actorID)
+	return key, secret, nil
+}
+
+func (uc *APIKeyUseCase) List(ctx context.Context) ([]apikeys.Key, error) {
+	keys, err := uc.repo.List(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to list API keys", "err", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Rotate gives a key a new secret and returns it. The old secret stops
+// working at once.
+func (uc *APIKeyUseCase) Rotate(ctx context.Context, id int64) (*apikeys.Key, string, error) {
+	secret, prefix, hash := apikeys.Generate()
+	key, err := uc.repo.Rotate(ctx, id, prefix, hash)
+	if errors.Is(err, apikeys.ErrNotFound) {
+		return nil, "", &errs.Error{Code: errs.NotFound, Message: "api key not found or revoked"}
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to rotate API key", "err", err, "keyId", id)
+		return nil, "", err
+	}
+
+	logger.InfoContext(ctx, "API key rotated", "keyId", id, "prefix", prefix)
+	return key, secret, nil
+}
+
+func (uc *APIKeyUseCase) Revoke(ctx context.Context, actorID, id int64) (*apikeys.Key, error) {
+	key, err := uc.repo.Revoke(ctx, id, actorID)
+	if errors.Is(err, apikeys.ErrNotFound) {
+		return nil, &errs.Error{Code: errs.NotFound, Message: "api key not found or already revoked"}
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to revoke API key", "err", err, "keyId", id)
+		return nil, err
+	}
+
+	logger.InfoContext(ctx, "API key revoked", "keyId", id, "by", actorID)
+	return key, nil
+}
+
+// Authenticate maps a key to the payload of a service account: the synthetic
+// service role and the key's scopes. Unknown, revoked and expired keys are
+// rejected alike.
+func (uc *APIKeyUseCase) Authenticate(ctx context.Context, secret string) (*entities.TokenPayload, error) {
+	invalid := errors.New("invalid api key")
+
+	prefix, err := apikeys.Parse(secret)
+	if err != nil {
+		return nil, invalid
+	}
+	key, err := uc.repo.FindByPrefix(ctx, prefix)
+	if errors.Is(err, apikeys.ErrNotFound) {
+		return nil, invalid
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to look up API key", "err", err, "prefix", prefix)
+		return nil, err
+	}
+	now := time.Now()
+	if !apikeys.Matches(secret, key.Hash) || !key.Usable(now) {
+		logger.WarnContext(ctx, "Rejected API key", "keyId", key.ID, "prefix", prefix)
+		return nil, invalid
+	}
+
+	// Most requests find the last use recent enough and skip the write.
+	if key.TouchDue(now) {
+		if err := uc.repo.Touch(ctx, key.ID); err != nil {
+			logger.WarnContext(ctx, "Failed to record API key use", "err", err, "keyId", key.ID)
+		}
+	}
+	return &entities.TokenPayload{
+		Role:     entities.RoleService,
+		APIKeyID: key.ID,
+		Scopes:   key.Scopes,
+	}, nil
+}
@@ -2,13 +2,19 @@ package usecases
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"encore.app/internal/config"
 	"encore.app/internal/entities"
+	"encore.app/internal/helper"
 	"encore.app/internal/logger"
 	"encore.app/internal/oauth2"
+	"encore.app/internal/sessions"
+	"encore.dev/beta/errs"
 )
 
 type AuthnUseCase struct {
@@ -16,6 +22,7 @@ type AuthnUseCase struct {
 	userInfoProvider oauth2.UserInfoProvider
 	tokenProvider    oauth2.TokenProvider
 	tokenRepository  oauth2.Repository
+	sessionRepo      sessions.Repository
 	authnConfig      *config.AuthnConfig
 }
 
@@ -24,6 +31,7 @@ func NewAuthnUseCase(
 	userInfoProvider oauth2.UserInfoProvider,
 	tokenProvider oauth2.TokenProvider,
 	tokenRepository oauth2.Repository,
+	sessionRepo sessions.Repository,
 	authnConfig *config.AuthnConfig,
 ) *AuthnUseCase {
 	return &AuthnUseCase{
@@ -31,10 +39,22 @@ func NewAuthnUseCase(
 		userInfoProvider: userInfoProvider,
 		tokenProvider:    tokenProvider,
 		tokenRepository:  tokenRepository,
+		sessionRepo:      sessionRepo,
 		authnConfig:      authnConfig,
 	}
 }
 
+// loginStateTTL bounds how long a user may take to log in to Moodle.
+const loginStateTTL = 10 * time.Minute
+
+// exchangeCodeTTL bounds how long the web app has to redeem the code it
+// received from the OAuth2 callback redirect.
+const exchangeCodeTTL = 30 * time.Second
+
+func loginStateKey(id string) string     { return "oauth2:state:" + id }
+func exchangeCodeKey(code string) string { return "oauth2:code:" + code }
+func userInfoKey(userID int64) string    { return "oauth2:userinfo:" + strconv.FormatInt(userID, 10) }
+
 func (uc *AuthnUseCase) setToken(ctx context.Context, req *oauth2.SaveRequest) error {
 	return uc.tokenRepository.SetEx(ctx, req)
 }
@@ -78,13 +98,58 @@ func (uc *AuthnUseCase) setTokens(
 	return uc.setRefreshToken(ctx, appTokens.RefreshToken, mdlTokens.RefreshToken)
 }
 
+// refreshExpiry is when a refresh token issued now expires, and with it the
+// session unless the token is rotated.
+func (uc *AuthnUseCase) refreshExpiry(now time.Time) time.Time {
+	return now.Add(time.Duration(uc.authnConfig.RefreshTokenExpire) * time.Hour)
+}
+
+// LoginURL starts a Moodle login: it stores a single-use state with a PKCE
+// verifier and returns the authorization URL to redirect the browser to.
+func (uc *AuthnUseCase) LoginURL(ctx context.Context) (string, error) {
+	id, state := oauth2.NewState(uc.authnConfig.StateSecret)
+	verifier := oauth2.NewVerifier()
+	req := &oauth2.SaveRequest{Key: loginStateKey(id), Val: verifier, Expiration: loginStateTTL}
+	if err := uc.setToken(ctx, req); err != nil {
+		logger.ErrorContext(ctx, "Failed to save login state", "err", err)
+		return "", err
+	}
+	return uc.oauth2Provider.GetAuthURL(state, verifier), nil
+}
+
+// consumeState checks the state returned by Moodle and returns its PKCE
+// verifier. Each state can be used once, within loginStateTTL.
+func (uc *AuthnUseCase) consumeState(ctx context.Context, state string) (string, error) {
+	id, err := oauth2.ParseState(uc.authnConfig.StateSecret, state)
+	if err != nil {
+		logger.WarnContext(ctx, "OAuth2 callback with invalid state", "state", state)
+		return "", &errs.Error{Code: errs.Unauthenticated, Message: "invalid login state"}
+	}
+
+	verifier, err := uc.tokenRepository.GetDel(ctx, loginStateKey(id))
+	if helper.IsKeyDoesNotExistErr(err) {
+		logger.WarnContext(ctx, "OAuth2 callback with expired or replayed state", "state", state)
+		return "", &errs.Error{Code: errs.Unauthenticated, Message: "login state expired or already used"}
+	}
+	if err != nil {
+		return "", err
+	}
+	return verifier, nil
+}
+
 func (uc *AuthnUseCase) HandleCallback(
 	ctx context.Context,
 	state, code string,
+	client sessions.Client,
 ) (*entities.CallbackResponse, error) {
-	logger.InfoContext(ctx, "Processing OAuth2 callback", "state", state, "code", code)
+	logger.InfoContext(ctx, "Processing OAuth2 callback", "state", state)
 
-	token, err := uc.oauth2Provider.ExchangeCodeForToken(ctx, code)
+	verifier, err := uc.consumeState(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := uc.oauth2Provider.ExchangeCodeForToken(ctx, code, verifier)
 	if err != nil {
 		logger.Error("Failed to exchange code for token", "err", err)
 		return nil, fmt.Errorf("token exchange failed: %w", err)
@@ -98,7 +163,11 @@ func (uc *AuthnUseCase) HandleCallback(
 	}
 
 	// Embed the role in the JWT so handlers can authorize without extra lookups.
-	req := &entities.TokenPayload{UserID: userInfo.Id, Role: userInfo.Role}
+	req := &entities.TokenPayload{
+		UserID:    userInfo.Id,
+		Role:      userInfo.Role,
+		SessionID: helper.UUIDStr(),
+	}
 	logger.InfoContext(ctx, "GenTokens request", "request", req)
 	resp, err := uc.tokenProvider.GenTokens(ctx, req)
 	if err != nil {
@@ -106,6 +175,23 @@ func (uc *AuthnUseCase) HandleCallback(
 		return nil, err
 	}
 
+	now := time.Now()
+	session := &sessions.Session{
+		ID:             req.SessionID,
+		UserID:         req.UserID,
+		Role:           req.Role,
+		RefreshTokenID: resp.RefreshTokenID,
+		UserAgent:      client.UserAgent,
+		IP:             client.IP,
+		CreatedAt:      now,
+		LastSeenAt:     now,
+		ExpiresAt:      uc.refreshExpiry(now),
+	}
+	if err := uc.sessionRepo.Create(ctx, session); err != nil {
+		logger.ErrorContext(ctx, "Failed to create session", "err", err, "userId", req.UserID)
+		return nil, err
+	}
+
 	go func() {
 		ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -121,6 +207,57 @@ func (uc *AuthnUseCase) HandleCallback(
 	return resp, nil
 }
 
+// JWKS returns the public keys of our tokens for other services.
+func (uc *AuthnUseCase) JWKS() *oauth2.JWKS {
+	return uc.tokenProvider.JWKS()
+}
+
+// NewExchangeCode stores a token pair under a random single-use code. The
+// code, not the tokens, is put in the redirect to the web app so that tokens
+// never appear in URLs.
+func (uc *AuthnUseCase) NewExchangeCode(
+	ctx context.Context,
+	tokens *entities.CallbackResponse,
+) (string, error) {
+	val, err := json.Marshal(tokens)
+	if err != nil {
+		return "", err
+	}
+
+	code := helper.RandomToken()
+	req := &oauth2.SaveRequest{Key: exchangeCodeKey(code), Val: string(val), Expiration: exchangeCodeTTL}
+	if err := uc.setToken(ctx, req); err != nil {
+		logger.ErrorContext(ctx, "Failed to save exchange code", "err", err)
+		return "", err
+	}
+	return code, nil
+}
+
+// ExchangeCode redeems a code from NewExchangeCode for its token pair.
+func (uc *AuthnUseCase) ExchangeCode(
+	ctx context.Context,
+	code string,
+) (*entities.CallbackResponse, error) {
+	if code == "" {
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: "code is required"}
+	}
+
+	val, err := uc.tokenRepository.GetDel(ctx, exchangeCodeKey(code))
+	if helper.IsKeyDoesNotExistErr(err) {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: "code expired or already used"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := &entities.CallbackResponse{}
+	if err := json.Unmarshal([]byte(val), tokens); err != nil {
+		logger.ErrorContext(ctx, "Failed to decode exchange code", "err", err)
+		return nil, err
+	}
+	return tokens, nil
+}
+
 func (uc *AuthnUseCase) getUserInfoByMdlToken(
 	ctx context.Context,
 	accessToken string,
@@ -140,18 +277,67 @@ func (uc *AuthnUseCase) GetUserInfo(
 	return userInfo, nil
 }
 
+// resolveUser returns the current role and status of a user. Lookups are
+// cached for USER_INFO_CACHE_TTL seconds so that refreshes of several tabs or
+// devices do not each hit Moodle.
+func (uc *AuthnUseCase) resolveUser(ctx context.Context, userID int64) (*entities.UserInfo, error) {
+	key := userInfoKey(userID)
+	val, err := uc.tokenRepository.Get(ctx, key)
+	if err == nil {
+		userInfo := &entities.UserInfo{}
+		if err := json.Unmarshal([]byte(val), userInfo); err == nil {
+			return userInfo, nil
+		}
+		logger.WarnContext(ctx, "Ignoring undecodable cached user info", "userId", userID)
+	} else if !helper.IsKeyDoesNotExistErr(err) {
+		logger.WarnContext(ctx, "Failed to read cached user info", "err", err, "userId", userID)
+	}
+
+	userInfo, err := uc.GetUserInfo(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(userInfo); err == nil {
+		req := &oauth2.SaveRequest{
+			Key:        key,
+			Val:        string(data),
+			Expiration: time.Duration(uc.authnConfig.UserInfoCacheTTL) * time.Second,
+		}
+		if err := uc.setToken(ctx, req); err != nil {
+			logger.WarnContext(ctx, "Failed to cache user info", "err", err, "userId", userID)
+		}
+	}
+	return userInfo, nil
+}
+
+// VerifyAccessToken checks the token's signature and that its session has not
+// been revoked. Tokens issued before sessions existed carry no session and are
+// rejected.
 func (uc *AuthnUseCase) VerifyAccessToken(
 	ctx context.Context,
 	token string,
 ) (*entities.TokenPayload, error) {
 	payload, err := uc.tokenProvider.Verify(
 		ctx,
-		&oauth2.VerifyRequest{TokenStr: token, Secret: uc.authnConfig.JWTSecret},
+		&oauth2.VerifyRequest{TokenStr: token, Type: oauth2.TokenTypeAccess},
 	)
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to verify access token", "err", err)
 		return nil, err
 	}
+	if payload.SessionID == "" {
+		return nil, errors.New("token has no session")
+	}
+
+	ok, err := uc.sessionRepo.Touch(ctx, payload.SessionID)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to look up session", "err", err, "sessionId", payload.SessionID)
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("session revoked")
+	}
 	return payload, nil
 }
 
@@ -161,7 +347,7 @@ func (uc *AuthnUseCase) VerifyRefreshToken(
 ) (*entities.TokenPayload, error) {
 	payload, err := uc.tokenProvider.Verify(
 		ctx,
-		&oauth2.VerifyRequest{TokenStr: token, Secret: uc.authnConfig.JWTRefreshToken},
+		&oauth2.VerifyRequest{TokenStr: token, Type: oauth2.TokenTypeRefresh},
 	)
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to verify refresh token", "err", err)
@@ -170,25 +356,246 @@ func (uc *AuthnUseCase) VerifyRefreshToken(
 	return payload, nil
 }
 
+// RefreshResult is a new token pair, and the role change it carries if the
+// user's role in Moodle differs from the one of the old token.
+type RefreshResult struct {
+	Tokens    *entities.CallbackResponse
+	UserID    int64
+	SessionID string
+	// PreviousRole is empty when the role did not change.
+	PreviousRole entities.UserRole
+	Role         entities.UserRole
+}
+
+// RoleChanged reports whether the new tokens carry a different role.
+func (r *RefreshResult) RoleChanged() bool {
+	return r.PreviousRole != ""
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. The refresh
+// token is rotated: only the newest one of a session may be used. Presenting
+// an older one means it was copied, so the whole session is revoked. The
+// rotation comes first, so that a reused or revoked token costs no Moodle
+// call.
+//
+// The role is looked up again rather than copied from the old token, so a
+// demotion in Moodle takes effect on the next refresh. Suspended and deleted
+// users lose their session.
 func (uc *AuthnUseCase) RefreshToken(
 	ctx context.Context,
 	token string,
-) (*entities.CallbackResponse, error) {
+) (*RefreshResult, error) {
 	payload, err := uc.VerifyRefreshToken(ctx, token)
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to verify refresh token", "err", err)
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: "invalid refresh token"}
+	}
+	if payload.SessionID == "" {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: "session expired, please log in again"}
+	}
+
+	newTokenID := helper.UUIDStr()
+	expiresAt := uc.refreshExpiry(time.Now())
+	err = uc.sessionRepo.Rotate(ctx, payload.SessionID, payload.TokenID, newTokenID, payload.Role, expiresAt)
+	switch {
+	case errors.Is(err, sessions.ErrTokenReused):
+		logger.WarnContext(ctx, "Refresh token reused, revoking session",
+			"sessionId", payload.SessionID, "userId", payload.UserID)
+		if err := uc.sessionRepo.Delete(ctx, payload.SessionID); err != nil &&
+			!errors.Is(err, sessions.ErrNotFound) {
+			logger.ErrorContext(ctx, "Failed to revoke session", "err", err, "sessionId", payload.SessionID)
+		}
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: "session revoked"}
+	case errors.Is(err, sessions.ErrNotFound):
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: "session revoked"}
+	case err != nil:
+		logger.ErrorContext(ctx, "Failed to rotate refresh token", "err", err, "sessionId", payload.SessionID)
+		return nil, err
+	}
+
+	userInfo, err := uc.resolveUser(ctx, payload.UserID)
+	if err != nil {
+		uc.restoreRefreshToken(ctx, payload, newTokenID, expiresAt)
 		return nil, err
 	}
+	if userInfo.Suspended || userInfo.Deleted {
+		logger.WarnContext(ctx, "Refresh by disabled user, revoking session",
+			"userId", payload.UserID, "sessionId", payload.SessionID,
+			"suspended", userInfo.Suspended, "deleted", userInfo.Deleted)
+		if err := uc.Logout(ctx, payload.SessionID); err != nil {
+			return nil, err
+		}
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: "account is disabled"}
+	}
 
-	// Preserve the role from the existing payload so the new token still
-	// carries the correct role without an extra Moodle lookup.
-	req := &entities.TokenPayload{UserID: payload.UserID, Role: payload.Role}
+	req := &entities.TokenPayload{
+		UserID:    payload.UserID,
+		Role:      userInfo.Role,
+		SessionID: payload.SessionID,
+		TokenID:   newTokenID,
+	}
+	if req.Role != payload.Role {
+		// The session keeps the role of its newest tokens.
+		err := uc.sessionRepo.Rotate(ctx, payload.SessionID, newTokenID, newTokenID, req.Role, expiresAt)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to update session role", "err", err, "sessionId", payload.SessionID)
+			uc.restoreRefreshToken(ctx, payload, newTokenID, expiresAt)
+			return nil, err
+		}
+	}
 	resp, err := uc.tokenProvider.GenTokens(ctx, req)
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to generate tokens", "err", err, "request", req)
+		uc.restoreRefreshToken(ctx, payload, newTokenID, expiresAt)
 		return nil, err
 	}
-	resp.RefreshToken = token
 
-	return resp, nil
+	result := &RefreshResult{
+		Tokens:    resp,
+		UserID:    payload.UserID,
+		SessionID: payload.SessionID,
+		Role:      req.Role,
+	}
+	if payload.Role != req.Role {
+		logger.InfoContext(ctx, "User role changed on refresh",
+			"userId", payload.UserID, "from", payload.Role, "to", req.Role)
+		result.PreviousRole = payload.Role
+	}
+	return result, nil
+}
+
+// restoreRefreshToken makes the presented refresh token current again after
+// a refresh failed past its rotation, so that the client can retry it
+// instead of being taken for a thief.
+func (uc *AuthnUseCase) restoreRefreshToken(
+	ctx context.Context,
+	payload *entities.TokenPayload,
+	newTokenID string,
+	expiresAt time.Time,
+) {
+	err := uc.sessionRepo.Rotate(ctx, payload.SessionID, newTokenID, payload.TokenID, payload.Role, expiresAt)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to restore refresh token", "err", err, "sessionId", payload.SessionID)
+	}
+}
+
+// Logout revokes a single session. Its access tokens stop working at once.
+func (uc *AuthnUseCase) Logout(ctx context.Context, sessionID string) error {
+	err := uc.sessionRepo.Delete(ctx, sessionID)
+	if err != nil && !errors.Is(err, sessions.ErrNotFound) {
+		logger.ErrorContext(ctx, "Failed to delete session", "err", err, "sessionId", sessionID)
+		return err
+	}
+	return nil
+}
+
+// LogoutAll revokes every session of a user and returns how many there were.
+func (uc *AuthnUseCase) LogoutAll(ctx context.Context, userID int64) (int, error) {
+	n, err := uc.sessionRepo.DeleteByUser(ctx, userID)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to delete user sessions", "err", err, "userId", userID)
+		return 0, err
+	}
+	return n, nil
+}
+
+func (uc *AuthnUseCase) ListSessions(ctx context.Context, userID int64) ([]sessions.Session, error) {
+	list, err := uc.sessionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to list sessions", "err", err, "userId", userID)
+		return nil, err
+	}
+	return list, nil
+}
+
+// RevokeSession is Logout for administrators: unknown sessions are reported.
+func (uc *AuthnUseCase) RevokeSession(ctx context.Context, sessionID string) (*sessions.Session, error) {
+	session, err := uc.sessionRepo.Get(ctx, sessionID)
+	if errors.Is(err, sessions.ErrNotFound) {
+		return nil, &errs.Error{Code: errs.NotFound, Message: "session not found"}
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to get session", "err", err, "sessionId", sessionID)
+		return nil, err
+	}
+	if err := uc.Logout(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ImpersonationResult is the access token of an impersonated session.
+type ImpersonationResult struct {
+	AccessToken string
+	SessionID   string
+	User        *entities.UserInfo
+	ExpiresAt   time.Time
+}
+
+// Impersonate starts a session in which actor acts as another user, to see
+// the application as they do. The session cannot be refreshed and expires
+// after IMPERSONATION_TTL minutes; its token names actor as impersonator,
+// which makes it read-only. Admins cannot be impersonated.
+func (uc *AuthnUseCase) Impersonate(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	userID int64,
+	client sessions.Client,
+) (*ImpersonationResult, error) {
+	if actor.Impersonator != 0 {
+		return nil, &errs.Error{Code: errs.PermissionDenied, Message: "already impersonating a user"}
+	}
+	if userID == actor.UserID {
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: "cannot impersonate yourself"}
+	}
+
+	userInfo, err := uc.GetUserInfo(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if userInfo.Role == entities.RoleAdmin {
+		return nil, &errs.Error{Code: errs.PermissionDenied, Message: "cannot impersonate an admin"}
+	}
+	if userInfo.Suspended || userInfo.Deleted {
+		return nil, &errs.Error{Code: errs.FailedPrecondition, Message: "account is disabled"}
+	}
+
+	req := &entities.TokenPayload{
+		UserID:       userInfo.Id,
+		Role:         userInfo.Role,
+		SessionID:    helper.UUIDStr(),
+		Impersonator: actor.UserID,
+	}
+	ttl := time.Duration(uc.authnConfig.ImpersonationTTL) * time.Minute
+	token, err := uc.tokenProvider.GenAccessToken(ctx, req, ttl)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to generate impersonation token", "err", err, "request", req)
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &sessions.Session{
+		ID:           req.SessionID,
+		UserID:       req.UserID,
+		Role:         req.Role,
+		Impersonator: actor.UserID,
+		UserAgent:    client.UserAgent,
+		IP:           client.IP,
+		CreatedAt:    now,
+		LastSeenAt:   now,
+		ExpiresAt:    now.Add(ttl),
+	}
+	if err := uc.sessionRepo.Create(ctx, session); err != nil {
+		logger.ErrorContext(ctx, "Failed to create session", "err", err, "userId", req.UserID)
+		return nil, err
+	}
+
+	logger.InfoContext(ctx, "Impersonation started",
+		"impersonator", actor.UserID, "userId", req.UserID, "sessionId", req.SessionID)
+	return &ImpersonationResult{
+		AccessToken: token,
+		SessionID:   req.SessionID,
+		User:        userInfo,
+		ExpiresAt:   session.ExpiresAt,
+	}, nil
 }
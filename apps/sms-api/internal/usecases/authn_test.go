@@ -0,0 +1,141 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"encore.app/internal/config"
+	"encore.app/internal/entities"
+	"encore.app/internal/oauth2"
+	"encore.app/internal/sessions"
+	"encore.dev/beta/errs"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// noUserCache makes every refresh look the user up in Moodle.
+type noUserCache struct{ oauth2.Repository }
+
+func (noUserCache) Get(context.Context, string) (string, error)      { return "", redis.Nil }
+func (noUserCache) SetEx(context.Context, *oauth2.SaveRequest) error { return nil }
+
+// fakeUsers stands in for Moodle's user lookup.
+type fakeUsers struct {
+	oauth2.UserInfoProvider
+	role  entities.UserRole
+	err   error
+	calls int
+}
+
+func (f *fakeUsers) GetUserInfo(_ context.Context, id int64) (*entities.UserInfo, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &entities.UserInfo{Id: id, Role: f.role}, nil
+}
+
+// newRefreshTest returns a use case and the refresh token of a teacher's
+// session "s1".
+func newRefreshTest(t *testing.T) (*AuthnUseCase, *fakeUsers, sessions.Repository, string) {
+	t.Helper()
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	cfg := &config.AuthnConfig{TokenExpire: 1, RefreshTokenExpire: 1}
+	keys, err := oauth2.LoadKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := oauth2.NewAppTokenProvider(cfg, keys)
+	sessionRepo := sessions.NewRedisRepository(rdb)
+	users := &fakeUsers{role: entities.RoleTeacher}
+	uc := NewAuthnUseCase(nil, users, tokens, noUserCache{}, sessionRepo, cfg)
+
+	resp, err := tokens.GenTokens(ctx, &entities.TokenPayload{UserID: 42, Role: entities.RoleTeacher, SessionID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	err = sessionRepo.Create(ctx, &sessions.Session{
+		ID:             "s1",
+		UserID:         42,
+		Role:           entities.RoleTeacher,
+		RefreshTokenID: resp.RefreshTokenID,
+		CreatedAt:      now,
+		LastSeenAt:     now,
+		ExpiresAt:      now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uc, users, sessionRepo, resp.RefreshToken
+}
+
+func wantUnauthenticated(t *testing.T, err error) {
+	t.Helper()
+	// errs.Code needs the Encore runtime.
+	var e *errs.Error
+	if !errors.As(err, &e) || e.Code != errs.Unauthenticated {
+		t.Fatalf("err = %v, want Unauthenticated", err)
+	}
+}
+
+func TestRefreshTokenReuseRevokesWithoutMoodle(t *testing.T) {
+	ctx := context.Background()
+	uc, users, sessionRepo, token := newRefreshTest(t)
+	users.role = entities.RoleManager
+
+	result, err := uc.RefreshToken(ctx, token)
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if result.Role != entities.RoleManager || !result.RoleChanged() {
+		t.Errorf("role = %q (changed %t), want manager", result.Role, result.RoleChanged())
+	}
+	s, err := sessionRepo.Get(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Role != entities.RoleManager {
+		t.Errorf("session role = %q, want manager", s.Role)
+	}
+
+	// The new refresh token is the one the session expects.
+	payload, err := uc.VerifyRefreshToken(ctx, result.Tokens.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.TokenID != s.RefreshTokenID {
+		t.Errorf("new refresh token %q, session expects %q", payload.TokenID, s.RefreshTokenID)
+	}
+
+	_, err = uc.RefreshToken(ctx, token)
+	wantUnauthenticated(t, err)
+	if users.calls != 1 {
+		t.Errorf("Moodle asked %d times, want 1: the reused token was looked up", users.calls)
+	}
+	if _, err := sessionRepo.Get(ctx, "s1"); !errors.Is(err, sessions.ErrNotFound) {
+		t.Errorf("session after reuse: %v, want it revoked", err)
+	}
+}
+
+func TestRefreshTokenSurvivesMoodleFailure(t *testing.T) {
+	ctx := context.Background()
+	uc, users, _, token := newRefreshTest(t)
+
+	users.err = errors.New("moodle is down")
+	if _, err := uc.RefreshToken(ctx, token); err == nil {
+		t.Fatal("RefreshToken succeeded although Moodle failed")
+	}
+
+	// The client retries with the same token once Moodle answers.
+	users.err = nil
+	if _, err := uc.RefreshToken(ctx, token); err != nil {
+		t.Fatalf("retry after the Moodle failure: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package usecases
+
+import (
+	"context"
+
+	"encore.app/internal/authz"
+	"encore.app/internal/entities"
+	"encore.app/internal/logger"
+	"encore.dev/beta/errs"
+)
+
+// AuthzUseCase lets admins change which roles hold which permissions.
+type AuthzUseCase struct {
+	authorizer *authz.Authorizer
+}
+
+func NewAuthzUseCase(authorizer *authz.Authorizer) *AuthzUseCase {
+	return &AuthzUseCase{authorizer: authorizer}
+}
+
+// GetPolicy returns the policy in effect.
+func (uc *AuthzUseCase) GetPolicy(ctx context.Context) (authz.Policy, error) {
+	policy, err := uc.authorizer.Policy(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "GetPolicy error", "err", err)
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (uc *AuthzUseCase) SetPolicy(ctx context.Context, policy authz.Policy) error {
+	logger.InfoContext(ctx, "Processing SetPolicy", "policy", policy)
+
+	if err := policy.Validate(); err != nil {
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+	if err := uc.authorizer.SetPolicy(ctx, policy); err != nil {
+		logger.ErrorContext(ctx, "SetPolicy error", "err", err)
+		return err
+	}
+	return nil
+}
+
+// ResetPolicy reverts to the default policy.
+func (uc *AuthzUseCase) ResetPolicy(ctx context.Context) error {
+	logger.InfoContext(ctx, "Processing ResetPolicy")
+
+	if err := uc.authorizer.ResetPolicy(ctx); err != nil {
+		logger.ErrorContext(ctx, "ResetPolicy error", "err", err)
+		return err
+	}
+	return nil
+}
+
+// courseScope reports whether the actor may access every course, or only the
+// ones they teach. Use cases that list courses pick their Moodle query by it.
+func courseScope(
+	ctx context.Context,
+	authorizer *authz.Authorizer,
+	actor *entities.TokenPayload,
+) (all, own bool, err error) {
+	all, err = authorizer.Permits(ctx, actor, authz.PermCoursesAccessAll)
+	if err != nil || all {
+		return all, false, err
+	}
+	own, err = authorizer.Permits(ctx, actor, authz.PermCoursesAccessOwn)
+	return false, own, err
+}
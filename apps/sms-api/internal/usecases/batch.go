@@ -0,0 +1,57 @@
+package usecases
+
+import (
+	"context"
+
+	"encore.app/internal/pool"
+)
+
+// batchChunkSize bounds how many tasks one fan-out keeps queued at once, so
+// that it leaves room on the shared pool for other requests.
+const batchChunkSize = 20
+
+// runTasks runs tasks on the pool in chunks and returns the error of each
+// task, whether it could not be queued or failed while running. When other
+// requests have filled the queue, it waits for room rather than failing, for
+// as long as ctx allows. The tasks run with ctx, see withRequest.
+func runTasks(ctx context.Context, p *pool.Pool, tasks []pool.Task) []error {
+	taskErrs := make([]error, len(tasks))
+	for start := 0; start < len(tasks); start += batchChunkSize {
+		end := min(start+batchChunkSize, len(tasks))
+
+		results := make([]<-chan pool.TaskResult, end-start)
+		for i, task := range tasks[start:end] {
+			results[i], taskErrs[start+i] = p.SubmitWait(ctx, withRequest(ctx, task))
+		}
+		for i, resultCh := range results {
+			if resultCh == nil {
+				continue
+			}
+			select {
+			case result := <-resultCh:
+				taskErrs[start+i] = result.Error
+			case <-ctx.Done():
+				taskErrs[start+i] = ctx.Err()
+			}
+		}
+	}
+	return taskErrs
+}
+
+// withRequest runs a task with the request context instead of the pool's, so
+// that it keeps the request's trace and values and stops when the request is
+// canceled. The pool's task timeout still applies.
+func withRequest(reqCtx context.Context, task pool.Task) pool.Task {
+	return pool.TaskFunc(func(taskCtx context.Context) error {
+		ctx, cancel := context.WithCancelCause(reqCtx)
+		defer cancel(nil)
+		if deadline, ok := taskCtx.Deadline(); ok {
+			var cancelDeadline context.CancelFunc
+			ctx, cancelDeadline = context.WithDeadline(ctx, deadline)
+			defer cancelDeadline()
+		}
+		stop := context.AfterFunc(taskCtx, func() { cancel(context.Cause(taskCtx)) })
+		defer stop()
+		return task.Execute(ctx)
+	})
+}
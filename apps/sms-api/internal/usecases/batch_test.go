@@ -0,0 +1,75 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"encore.app/internal/pool"
+)
+
+type ctxKey struct{}
+
+func startPool(t *testing.T, workers, queue int) *pool.Pool {
+	t.Helper()
+	cfg := pool.DefaultConfig()
+	cfg.MaxWorkers = workers
+	cfg.QueueSize = queue
+	p := pool.New(cfg)
+	p.Start()
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestRunTasksWaitsForAFullQueue(t *testing.T) {
+	p := startPool(t, 1, 1)
+
+	tasks := make([]pool.Task, batchChunkSize+5)
+	for i := range tasks {
+		tasks[i] = pool.TaskFunc(func(context.Context) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+	}
+	for i, err := range runTasks(context.Background(), p, tasks) {
+		if err != nil {
+			t.Fatalf("task %d: %v", i, err)
+		}
+	}
+}
+
+func TestRunTasksUseTheRequestContext(t *testing.T) {
+	p := startPool(t, 2, 10)
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "request"))
+
+	checked, started := make(chan struct{}), make(chan struct{})
+	tasks := []pool.Task{
+		pool.TaskFunc(func(ctx context.Context) error {
+			defer close(checked)
+			if ctx.Value(ctxKey{}) != "request" {
+				return errors.New("request value missing")
+			}
+			return nil
+		}),
+		pool.TaskFunc(func(ctx context.Context) error {
+			// Cancel only once the first task is done with the context.
+			<-checked
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	}
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	taskErrs := runTasks(ctx, p, tasks)
+	if taskErrs[0] != nil {
+		t.Errorf("first task: %v", taskErrs[0])
+	}
+	if !errors.Is(taskErrs[1], context.Canceled) {
+		t.Errorf("second task: got %v, want context.Canceled", taskErrs[1])
+	}
+}
@@ -0,0 +1,48 @@
+package usecases
+
+import (
+	"context"
+
+	"encore.app/internal/logger"
+	"encore.app/internal/mdlcache"
+	"encore.dev/beta/errs"
+)
+
+// CacheUseCase lets admins drop cached Moodle data, e.g. after changing a
+// course in Moodle, instead of waiting for it to expire.
+type CacheUseCase struct {
+	cache *mdlcache.Cache
+}
+
+func NewCacheUseCase(cache *mdlcache.Cache) *CacheUseCase {
+	return &CacheUseCase{cache: cache}
+}
+
+// Flush drops the cached data of a course, a category, or both, and returns
+// how many entries were dropped.
+func (uc *CacheUseCase) Flush(ctx context.Context, courseID, categoryID *int64) (int, error) {
+	if courseID == nil && categoryID == nil {
+		return 0, &errs.Error{Code: errs.InvalidArgument, Message: "courseId or categoryId is required"}
+	}
+
+	flushed := 0
+	if courseID != nil {
+		n, err := uc.cache.FlushCourse(ctx, *courseID)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to flush course cache", "err", err, "courseId", *courseID)
+			return 0, err
+		}
+		logger.InfoContext(ctx, "Course cache flushed", "courseId", *courseID, "entries", n)
+		flushed += n
+	}
+	if categoryID != nil {
+		n, err := uc.cache.FlushCategory(ctx, *categoryID)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to flush category cache", "err", err, "categoryId", *categoryID)
+			return 0, err
+		}
+		logger.InfoContext(ctx, "Category cache flushed", "categoryId", *categoryID, "entries", n)
+		flushed += n
+	}
+	return flushed, nil
+}
@@ -0,0 +1,223 @@
+package usecases
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"encore.app/internal/authz"
+	"encore.app/internal/entities"
+	"encore.app/internal/gradecalc"
+	"encore.app/internal/gradeexport"
+	"encore.app/internal/logger"
+	"encore.app/internal/mdlapi"
+	"encore.app/internal/pool"
+)
+
+// CategoryReportUseCase builds the consolidated grade report of a category.
+type CategoryReportUseCase struct {
+	teacherProvider      mdlapi.LocalTeacherProvider
+	courseGradesProvider mdlapi.LocalCourseGrades
+	coefRepo             gradecalc.Repository
+	authorizer           *authz.Authorizer
+	pool                 *pool.Pool
+}
+
+func NewCategoryReportUseCase(
+	teacherProvider mdlapi.LocalTeacherProvider,
+	courseGradesProvider mdlapi.LocalCourseGrades,
+	coefRepo gradecalc.Repository,
+	authorizer *authz.Authorizer,
+	p *pool.Pool,
+) *CategoryReportUseCase {
+	return &CategoryReportUseCase{
+		teacherProvider:      teacherProvider,
+		courseGradesProvider: courseGradesProvider,
+		coefRepo:             coefRepo,
+		authorizer:           authorizer,
+		pool:                 p,
+	}
+}
+
+// GetReport aggregates every course of the category and its child
+// categories. Callers with courses.access_own only get the courses they
+// teach.
+func (uc *CategoryReportUseCase) GetReport(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	categoryID int,
+) (*entities.CategoryReport, error) {
+	logger.InfoContext(ctx, "Processing GetCategoryReport", "categoryId", categoryID, "userId", actor.UserID)
+
+	courses, err := uc.reportCourses(ctx, actor, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*gradecalc.CourseResult, len(courses))
+	tasks := make([]pool.Task, len(courses))
+	for i, c := range courses {
+		tasks[i] = pool.TaskFunc(func(taskCtx context.Context) error {
+			details, err := uc.courseGradesProvider.GetCourseDetails(
+				taskCtx,
+				&mdlapi.GetCourseGradesRequest{CourseId: int64(c.ID)},
+			)
+			if err != nil {
+				return err
+			}
+			averages, err := courseAverages(taskCtx, uc.coefRepo, details)
+			if err != nil {
+				return err
+			}
+			results[i] = &gradecalc.CourseResult{
+				Course:   reportCourse(c),
+				Details:  details,
+				Averages: averages,
+			}
+			return nil
+		})
+	}
+
+	loaded := make([]gradecalc.CourseResult, 0, len(courses))
+	var failures []entities.ReportCourseFailure
+	for i, err := range runTasks(ctx, uc.pool, tasks) {
+		if err != nil {
+			logger.ErrorContext(ctx, "GetCategoryReport course error", "err", err, "courseId", courses[i].ID)
+			failures = append(failures, entities.ReportCourseFailure{CourseID: courses[i].ID, Error: err.Error()})
+			continue
+		}
+		loaded = append(loaded, *results[i])
+	}
+
+	report := gradecalc.Consolidate(categoryID, loaded)
+	report.Failures = failures
+	return report, nil
+}
+
+// ExportReport renders the category report as an XLSX workbook.
+func (uc *CategoryReportUseCase) ExportReport(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	categoryID int,
+) (*mdlapi.ExportCourseGradesResponse, error) {
+	report, err := uc.GetReport(ctx, actor, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := gradeexport.BuildCategoryReportXLSX(report)
+	if err != nil {
+		logger.ErrorContext(ctx, "ExportCategoryReport BuildCategoryReportXLSX error", "err", err, "categoryId", categoryID)
+		return nil, err
+	}
+	return &mdlapi.ExportCourseGradesResponse{
+		Filename: fmt.Sprintf("report-category-%d.xlsx", categoryID),
+		Mimetype: gradeexport.MimetypeXLSX,
+		Filedata: base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// reportCourses lists the courses of the category and its descendants that
+// the actor may see.
+func (uc *CategoryReportUseCase) reportCourses(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	categoryID int,
+) ([]mdlapi.CategoryCourse, error) {
+	all, own, err := courseScope(ctx, uc.authorizer, actor)
+	if err != nil {
+		logger.ErrorContext(ctx, "GetCategoryReport policy error", "err", err)
+		return nil, err
+	}
+	if !all {
+		if !own {
+			return nil, nil
+		}
+		// A teacher's courses across all categories carry their category
+		// path, so a single call is enough.
+		resp, err := uc.teacherProvider.GetCategoryCourses(ctx, &mdlapi.GetCategoryCoursesRequest{
+			UserID: int(actor.UserID),
+		})
+		if err != nil {
+			logger.ErrorContext(ctx, "GetCategoryReport GetCategoryCourses error", "err", err)
+			return nil, err
+		}
+		courses := make([]mdlapi.CategoryCourse, 0, len(resp.Courses))
+		for _, c := range resp.Courses {
+			if c.Categoryid == categoryID || inCategory(c.Categorypath, categoryID) {
+				courses = append(courses, c)
+			}
+		}
+		return courses, nil
+	}
+
+	categories, err := uc.teacherProvider.GetAllCategories(ctx, &mdlapi.GetAllCategoriesRequest{})
+	if err != nil {
+		logger.ErrorContext(ctx, "GetCategoryReport GetAllCategories error", "err", err)
+		return nil, err
+	}
+	categoryIDs := []int{categoryID}
+	for _, c := range categories.Categories {
+		if c.ID != categoryID && inCategory(c.Path, categoryID) {
+			categoryIDs = append(categoryIDs, c.ID)
+		}
+	}
+
+	found := make([][]mdlapi.CategoryCourse, len(categoryIDs))
+	tasks := make([]pool.Task, len(categoryIDs))
+	for i, id := range categoryIDs {
+		tasks[i] = pool.TaskFunc(func(taskCtx context.Context) error {
+			resp, err := uc.teacherProvider.GetAllCategoryCoursesForAdmin(
+				taskCtx,
+				&mdlapi.GetCategoryCoursesRequest{CategoryID: id},
+			)
+			if err != nil {
+				return err
+			}
+			found[i] = resp.Courses
+			return nil
+		})
+	}
+
+	var courses []mdlapi.CategoryCourse
+	for i, err := range runTasks(ctx, uc.pool, tasks) {
+		if err != nil {
+			logger.ErrorContext(ctx, "GetCategoryReport GetAllCategoryCoursesForAdmin error",
+				"err", err, "categoryId", categoryIDs[i])
+			return nil, err
+		}
+		courses = append(courses, found[i]...)
+	}
+	return courses, nil
+}
+
+// inCategory reports whether a category path ("/1/5/9") goes through the
+// given category.
+func inCategory(path string, categoryID int) bool {
+	id := strconv.Itoa(categoryID)
+	for _, segment := range strings.Split(path, "/") {
+		if segment == id {
+			return true
+		}
+	}
+	return false
+}
+
+func reportCourse(c mdlapi.CategoryCourse) entities.ReportCourse {
+	course := entities.ReportCourse{
+		ID:           c.ID,
+		Shortname:    c.Shortname,
+		Fullname:     c.Fullname,
+		CategoryID:   c.Categoryid,
+		CategoryName: c.Categoryname,
+		Credits:      1,
+	}
+	for _, m := range c.Metadata {
+		if m.Name == "credit" && m.Value > 0 {
+			course.Credits = float64(m.Value)
+		}
+	}
+	return course
+}
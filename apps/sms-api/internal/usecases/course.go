@@ -2,30 +2,139 @@ package usecases
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 
+	"encore.app/internal/authz"
 	"encore.app/internal/entities"
+	"encore.app/internal/gradecalc"
+	"encore.app/internal/gradehistory"
+	"encore.app/internal/gradelocks"
 	"encore.app/internal/logger"
 	"encore.app/internal/mdlapi"
+	"encore.app/internal/mdlcache"
+	"encore.app/internal/pool"
+	"encore.dev/beta/errs"
 )
 
+var errGradeUpdateRejected = errors.New("moodle rejected the grade update")
+
 type CourseUseCase struct {
 	courseGradesProvider mdlapi.LocalCourseGrades
 	userGradesProvider   mdlapi.UserGradeItemsProvider
 	teacherProvider      mdlapi.LocalTeacherProvider
+	historyRepo          gradehistory.Repository
+	lockRepo             gradelocks.Repository
+	coefRepo             gradecalc.Repository
+	authorizer           *authz.Authorizer
+	courseCache          authz.CourseCache
+	pool                 *pool.Pool
 }
 
 func NewCourseUseCase(
 	courseGradesProvider mdlapi.LocalCourseGrades,
 	UserGradeItemsProvider mdlapi.UserGradeItemsProvider,
 	teacherProvider mdlapi.LocalTeacherProvider,
+	historyRepo gradehistory.Repository,
+	lockRepo gradelocks.Repository,
+	coefRepo gradecalc.Repository,
+	authorizer *authz.Authorizer,
+	courseCache authz.CourseCache,
+	p *pool.Pool,
 ) *CourseUseCase {
 	return &CourseUseCase{
 		courseGradesProvider: courseGradesProvider,
 		userGradesProvider:   UserGradeItemsProvider,
 		teacherProvider:      teacherProvider,
+		historyRepo:          historyRepo,
+		lockRepo:             lockRepo,
+		coefRepo:             coefRepo,
+		authorizer:           authorizer,
+		courseCache:          courseCache,
+		pool:                 p,
+	}
+}
+
+// teacherCoursesTTL bounds how long a change of course assignments in Moodle
+// takes to reach course access checks.
+const teacherCoursesTTL = 5 * time.Minute
+
+// CheckCourseAccess allows actors with courses.access_all into any course and
+// actors with courses.access_own into the courses they teach. Everyone else,
+// students included, is denied.
+func (uc *CourseUseCase) CheckCourseAccess(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	courseID int64,
+) error {
+	if actor == nil {
+		return &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+
+	all, err := uc.authorizer.Permits(ctx, actor, authz.PermCoursesAccessAll)
+	if err != nil {
+		logger.ErrorContext(ctx, "CheckCourseAccess policy error", "err", err)
+		return err
+	}
+	if all {
+		return nil
+	}
+
+	own, err := uc.authorizer.Permits(ctx, actor, authz.PermCoursesAccessOwn)
+	if err != nil {
+		logger.ErrorContext(ctx, "CheckCourseAccess policy error", "err", err)
+		return err
+	}
+	if own {
+		courseIDs, err := uc.teacherCourseIDs(ctx, actor.UserID)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(courseIDs, courseID) {
+			return nil
+		}
+	}
+
+	logger.WarnContext(ctx, "Course access denied",
+		"userId", actor.UserID, "role", actor.Role, "courseId", courseID)
+	return &errs.Error{
+		Code:    errs.PermissionDenied,
+		Message: fmt.Sprintf("no access to course %d", courseID),
 	}
 }
 
+// teacherCourseIDs returns the courses a user teaches, cached for
+// teacherCoursesTTL.
+func (uc *CourseUseCase) teacherCourseIDs(ctx context.Context, userID int64) ([]int64, error) {
+	courseIDs, err := uc.courseCache.GetCourses(ctx, userID)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to read cached courses", "err", err, "userId", userID)
+	}
+	if courseIDs != nil {
+		return courseIDs, nil
+	}
+
+	resp, err := uc.teacherProvider.GetCategoryCourses(
+		ctx,
+		&mdlapi.GetCategoryCoursesRequest{UserID: int(userID)},
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "CheckCourseAccess GetCategoryCourses error", "err", err, "userId", userID)
+		return nil, err
+	}
+
+	courseIDs = make([]int64, len(resp.Courses))
+	for i, c := range resp.Courses {
+		courseIDs[i] = int64(c.ID)
+	}
+	if err := uc.courseCache.SetCourses(ctx, userID, courseIDs, teacherCoursesTTL); err != nil {
+		logger.WarnContext(ctx, "Failed to cache courses", "err", err, "userId", userID)
+	}
+	return courseIDs, nil
+}
+
 func (uc *CourseUseCase) GetUserCourses(
 	ctx context.Context,
 	req *entities.GetUsersCoursesParams,
@@ -50,7 +159,7 @@ func (uc *CourseUseCase) GetUserCourses(
 		data[i] = *c.ToAppCourse()
 	}
 
-	return &entities.GetUsersCoursesResponse{Data: data}, nil
+	return &entities.GetUsersCoursesResponse{Data: data, Source: string(mdlApiResp.Source)}, nil
 }
 
 func (uc *CourseUseCase) GetUserCourseDetails(
@@ -68,20 +177,328 @@ func (uc *CourseUseCase) GetUserCourseDetails(
 		return nil, err
 	}
 
+	// Averages are an add-on; the grades are still useful without them.
+	if averages, err := courseAverages(ctx, uc.coefRepo, resp); err == nil {
+		resp.Averages = averages
+	}
+
 	return resp, nil
 }
 
+// UpdateCourseGrades writes one activity's grades and records every changed
+// value in the grade history. Current values are read before the write so the
+// history can hold the before/after pair. The grades are checked like those of
+// a batch update, but one invalid grade rejects the whole request.
 func (uc *CourseUseCase) UpdateCourseGrades(
 	ctx context.Context,
+	actor *entities.TokenPayload,
 	req *mdlapi.UpdateGradesRequest,
 ) (mdlapi.UpdateGradesResponse, error) {
 	logger.InfoContext(ctx, "Processing UpdateCourseGrades", "request", req)
 
+	// Validate against, and record history from, the grades in Moodle now.
+	details, err := uc.courseGradesProvider.GetCourseDetails(
+		mdlcache.Refresh(ctx),
+		&mdlapi.GetCourseGradesRequest{CourseId: int64(req.CourseID)},
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "UpdateCourseGrades GetCourseDetails error",
+			"err", err, "courseId", req.CourseID)
+		return false, err
+	}
+
+	locks, err := uc.lockRepo.FindActive(ctx, int64(req.CourseID))
+	if err != nil {
+		logger.ErrorContext(ctx, "UpdateCourseGrades FindActive locks error",
+			"err", err, "courseId", req.CourseID)
+		return false, err
+	}
+	// Apply the rules of a batch update; a single update is all or nothing.
+	result, valid := validateActivityGrades(details, locks, req)
+	if result.Error != "" {
+		code := errs.FailedPrecondition
+		if details.FindModule(req.ActivityID, req.ItemNumber) == nil {
+			code = errs.InvalidArgument
+		}
+		return false, &errs.Error{Code: code, Message: result.Error}
+	}
+	if len(valid) < len(req.Grades) {
+		for _, s := range result.Students {
+			if s.Error != "" {
+				return false, &errs.Error{
+					Code:    errs.InvalidArgument,
+					Message: fmt.Sprintf("student %d: %s", s.StudentID, s.Error),
+				}
+			}
+		}
+	}
+
 	resp, err := uc.userGradesProvider.UpdateGrades(ctx, req)
 	if err != nil {
 		logger.ErrorContext(ctx, "UpdateCourseGrades error", "err", err, "request", req)
 		return resp, err
 	}
 
+	if resp {
+		uc.recordHistory(ctx, gradeChanges(details, actor, req.ActivityID, req.ItemNumber, req.Grades))
+	}
+
 	return resp, nil
 }
+
+// GetGradeHistory lists the recorded grade changes of a course.
+func (uc *CourseUseCase) GetGradeHistory(
+	ctx context.Context,
+	courseID int64,
+	req *gradehistory.ListRequest,
+) (*gradehistory.ListResponse, error) {
+	logger.InfoContext(ctx, "Processing GetGradeHistory", "courseId", courseID, "request", req)
+
+	resp, err := uc.historyRepo.List(ctx, courseID, req)
+	if err != nil {
+		logger.ErrorContext(ctx, "GetGradeHistory error", "err", err, "courseId", courseID)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// recordHistory persists grade changes. The grades are already written in
+// Moodle at this point, so a failure is logged rather than returned.
+func (uc *CourseUseCase) recordHistory(ctx context.Context, entries []gradehistory.Entry) {
+	if err := uc.historyRepo.SaveAll(ctx, entries); err != nil {
+		logger.ErrorContext(ctx, "Failed to record grade history", "err", err, "entries", len(entries))
+	}
+}
+
+// gradeChanges compares the grades about to be written with the values in
+// details and returns one history entry per value that actually changes.
+func gradeChanges(
+	details *mdlapi.GetCourseGradesResponse,
+	actor *entities.TokenPayload,
+	activityID, itemNumber int,
+	grades []mdlapi.UpdateGrade,
+) []gradehistory.Entry {
+	now := time.Now().UTC()
+	entries := make([]gradehistory.Entry, 0, len(grades))
+	for _, g := range grades {
+		var old *float64
+		if s := details.FindStudent(g.StudentID); s != nil {
+			if cur := s.FindGrade(activityID, itemNumber); cur != nil {
+				if cur.Grade == g.Grade {
+					continue
+				}
+				v := cur.Grade
+				old = &v
+			}
+		}
+
+		entry := gradehistory.Entry{
+			CourseID:   int64(details.Course.ID),
+			ActivityID: int64(activityID),
+			ItemNumber: itemNumber,
+			StudentID:  int64(g.StudentID),
+			OldGrade:   old,
+			NewGrade:   g.Grade,
+			ChangedAt:  now,
+		}
+		if actor != nil {
+			entry.ActorID = actor.UserID
+			entry.ActorRole = actor.Role
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// BatchUpdateCourseGrades writes grades for several activities of one course.
+// Every value is checked against the module's Grademin/Grademax first; the
+// valid grades of each activity are then sent to Moodle as one
+// core_grades_update_grades call, fanned out through the worker pool.
+// A failure in one activity never aborts the others — the returned report
+// tells the caller exactly which student grades were written.
+func (uc *CourseUseCase) BatchUpdateCourseGrades(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	courseID int,
+	activities []mdlapi.UpdateGradesRequest,
+) (*entities.BatchUpdateGradesResponse, error) {
+	logger.InfoContext(ctx, "Processing BatchUpdateCourseGrades",
+		"courseId", courseID, "activities", len(activities))
+
+	details, err := uc.courseGradesProvider.GetCourseDetails(
+		mdlcache.Refresh(ctx),
+		&mdlapi.GetCourseGradesRequest{CourseId: int64(courseID)},
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "BatchUpdateCourseGrades GetCourseDetails error",
+			"err", err, "courseId", courseID)
+		return nil, err
+	}
+
+	locks, err := uc.lockRepo.FindActive(ctx, int64(courseID))
+	if err != nil {
+		logger.ErrorContext(ctx, "BatchUpdateCourseGrades FindActive locks error",
+			"err", err, "courseId", courseID)
+		return nil, err
+	}
+
+	results := make([]entities.ActivityGradesResult, len(activities))
+	tasks := make([]pool.Task, 0, len(activities))
+	taskActivity := make([]int, 0, len(activities))
+
+	for i := range activities {
+		req := activities[i]
+		req.CourseID = courseID
+
+		result, valid := validateActivityGrades(details, locks, &req)
+		results[i] = result
+		if len(valid) == 0 {
+			continue
+		}
+
+		req.Grades = valid
+		tasks = append(tasks, uc.updateGradesTask(&req))
+		taskActivity = append(taskActivity, i)
+	}
+
+	if len(tasks) > 0 {
+		taskErrs := runTasks(ctx, uc.pool, tasks)
+		for j, i := range taskActivity {
+			err := taskErrs[j]
+			if err != nil {
+				logger.ErrorContext(ctx, "BatchUpdateCourseGrades activity error",
+					"err", err, "courseId", courseID, "activityId", results[i].ActivityID)
+			}
+			applyUpdateOutcome(&results[i], err)
+		}
+	}
+
+	resp := &entities.BatchUpdateGradesResponse{CourseID: courseID, Activities: results}
+	var history []gradehistory.Entry
+	for _, a := range results {
+		written := make([]mdlapi.UpdateGrade, 0, len(a.Students))
+		for _, s := range a.Students {
+			if s.Success {
+				resp.Succeeded++
+				written = append(written, mdlapi.UpdateGrade{StudentID: s.StudentID, Grade: s.Grade})
+			} else {
+				resp.Failed++
+			}
+		}
+		history = append(history, gradeChanges(details, actor, a.ActivityID, a.ItemNumber, written)...)
+	}
+	uc.recordHistory(ctx, history)
+
+	return resp, nil
+}
+
+func (uc *CourseUseCase) updateGradesTask(req *mdlapi.UpdateGradesRequest) pool.Task {
+	return pool.TaskFunc(func(ctx context.Context) error {
+		ok, err := uc.userGradesProvider.UpdateGrades(ctx, req)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errGradeUpdateRejected
+		}
+		return nil
+	})
+}
+
+// validateActivityGrades checks one activity of a batch against the course
+// details and its active locks. It returns the per-student report — with invalid entries already
+// marked as failed — and the grades that may be sent to Moodle.
+func validateActivityGrades(
+	details *mdlapi.GetCourseGradesResponse,
+	locks []gradelocks.Lock,
+	req *mdlapi.UpdateGradesRequest,
+) (entities.ActivityGradesResult, []mdlapi.UpdateGrade) {
+	result := entities.ActivityGradesResult{
+		ActivityID: req.ActivityID,
+		ItemNumber: req.ItemNumber,
+		Students:   make([]entities.StudentGradeResult, len(req.Grades)),
+	}
+	for i, g := range req.Grades {
+		result.Students[i] = entities.StudentGradeResult{StudentID: g.StudentID, Grade: g.Grade}
+	}
+
+	module := details.FindModule(req.ActivityID, req.ItemNumber)
+	if module == nil {
+		result.Error = fmt.Sprintf(
+			"activity %d (item %d) is not graded in this course", req.ActivityID, req.ItemNumber,
+		)
+	} else if lock := coveringLock(locks, moduleExamType(module)); lock != nil {
+		result.Error = lockMessage(lock)
+	}
+	if result.Error != "" {
+		for i := range result.Students {
+			result.Students[i].Error = result.Error
+		}
+		return result, nil
+	}
+
+	valid := make([]mdlapi.UpdateGrade, 0, len(req.Grades))
+	for i, g := range req.Grades {
+		switch {
+		case details.FindStudent(g.StudentID) == nil:
+			result.Students[i].Error = "student is not enrolled in this course"
+		case !module.InRange(g.Grade):
+			result.Students[i].Error = fmt.Sprintf(
+				"grade must be between %g and %g", module.Grademin, module.Grademax,
+			)
+		default:
+			valid = append(valid, g)
+		}
+	}
+
+	return result, valid
+}
+
+// applyUpdateOutcome marks every still-pending student of an activity with
+// the result of its Moodle call.
+func applyUpdateOutcome(result *entities.ActivityGradesResult, err error) {
+	result.Success = err == nil
+	for i := range result.Students {
+		s := &result.Students[i]
+		if s.Error != "" {
+			result.Success = false
+			continue
+		}
+		if err != nil {
+			s.Error = err.Error()
+			continue
+		}
+		s.Success = true
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+}
+
+// moduleExamType returns the exam type label ("15P", "1T", "Thi") of a module,
+// or "" when the module is unknown or has no exam type.
+func moduleExamType(m *mdlapi.Module) string {
+	if m == nil || m.ExamType == nil {
+		return ""
+	}
+	return m.ExamType.String()
+}
+
+// coveringLock returns the first active lock that freezes activities of the
+// given exam type, or nil.
+func coveringLock(locks []gradelocks.Lock, examType string) *gradelocks.Lock {
+	for i := range locks {
+		if locks[i].Covers(examType) {
+			return &locks[i]
+		}
+	}
+	return nil
+}
+
+func lockMessage(lock *gradelocks.Lock) string {
+	if lock.ExamType == "" {
+		return "grades of this course are locked"
+	}
+	return fmt.Sprintf("%s grades of this course are locked", lock.ExamType)
+}
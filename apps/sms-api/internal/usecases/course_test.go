@@ -0,0 +1,44 @@
+package usecases
+
+import (
+	"testing"
+	"time"
+
+	"encore.app/internal/gradelocks"
+	"encore.app/internal/mdlapi"
+)
+
+func TestCoveringLock(t *testing.T) {
+	lifted := time.Now()
+	course := gradelocks.Lock{ID: 1}
+	final := gradelocks.Lock{ID: 2, ExamType: mdlapi.ExamFinal.String()}
+	liftedCourse := gradelocks.Lock{ID: 3, UnlockedAt: &lifted}
+	liftedFinal := gradelocks.Lock{ID: 4, ExamType: mdlapi.ExamFinal.String(), UnlockedAt: &lifted}
+
+	tests := []struct {
+		name     string
+		locks    []gradelocks.Lock
+		examType string
+		want     int64 // ID of the covering lock, 0 for none
+	}{
+		{"no locks", nil, mdlapi.ExamFinal.String(), 0},
+		{"course-wide lock", []gradelocks.Lock{course}, mdlapi.Exam15M.String(), 1},
+		{"course-wide lock, no exam type", []gradelocks.Lock{course}, "", 1},
+		{"same exam type", []gradelocks.Lock{final}, mdlapi.ExamFinal.String(), 2},
+		{"other exam type", []gradelocks.Lock{final}, mdlapi.Exam45M.String(), 0},
+		{"exam lock, no exam type", []gradelocks.Lock{final}, "", 0},
+		{"lifted locks", []gradelocks.Lock{liftedCourse, liftedFinal}, mdlapi.ExamFinal.String(), 0},
+		{"active lock after a lifted one", []gradelocks.Lock{liftedCourse, final}, mdlapi.ExamFinal.String(), 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got int64
+			if lock := coveringLock(tc.locks, tc.examType); lock != nil {
+				got = lock.ID
+			}
+			if got != tc.want {
+				t.Errorf("coveringLock = lock %d, want lock %d", got, tc.want)
+			}
+		})
+	}
+}
@@ -2,18 +2,33 @@ package usecases
 
 import (
 	"context"
+	"encoding/base64"
 
+	"encore.app/internal/entities"
+	"encore.app/internal/gradecalc"
+	"encore.app/internal/gradeexport"
 	"encore.app/internal/logger"
 	"encore.app/internal/mdlapi"
+	"encore.dev/beta/errs"
 )
 
 // ExportUseCase orchestrates export and template-management operations.
 type ExportUseCase struct {
-	provider mdlapi.ExportProvider
+	provider             mdlapi.ExportProvider
+	courseGradesProvider mdlapi.LocalCourseGrades
+	coefRepo             gradecalc.Repository
 }
 
-func NewExportUseCase(provider mdlapi.ExportProvider) *ExportUseCase {
-	return &ExportUseCase{provider: provider}
+func NewExportUseCase(
+	provider mdlapi.ExportProvider,
+	courseGradesProvider mdlapi.LocalCourseGrades,
+	coefRepo gradecalc.Repository,
+) *ExportUseCase {
+	return &ExportUseCase{
+		provider:             provider,
+		courseGradesProvider: courseGradesProvider,
+		coefRepo:             coefRepo,
+	}
 }
 
 func (uc *ExportUseCase) GetCourseTemplates(
@@ -21,7 +36,15 @@ func (uc *ExportUseCase) GetCourseTemplates(
 	courseID int,
 ) (*mdlapi.GetCourseTemplatesResponse, error) {
 	logger.InfoContext(ctx, "GetCourseTemplates", "courseID", courseID)
-	return uc.provider.GetCourseTemplates(ctx, &mdlapi.GetCourseTemplatesRequest{CourseID: courseID})
+	resp, err := uc.provider.GetCourseTemplates(ctx, &mdlapi.GetCourseTemplatesRequest{CourseID: courseID})
+	if err != nil {
+		return nil, err
+	}
+
+	// Native exporters are always available, even without the Moodle plugin's
+	// templates.
+	templates := append(nativeTemplates(), *resp...)
+	return &templates, nil
 }
 
 func (uc *ExportUseCase) ExportCourseGrades(
@@ -30,6 +53,15 @@ func (uc *ExportUseCase) ExportCourseGrades(
 	templateID string,
 ) (*mdlapi.ExportCourseGradesResponse, error) {
 	logger.InfoContext(ctx, "ExportCourseGrades", "courseID", courseID, "templateID", templateID)
+	if templateID == gradeexport.NativeXLSXTemplateID {
+		return uc.exportNativeXLSX(ctx, courseID)
+	}
+	if templateID != "" {
+		tpl := uc.nativeDOCXTemplate(ctx, templateID)
+		if tpl != nil {
+			return uc.exportNativeDOCX(ctx, courseID, tpl)
+		}
+	}
 	return uc.provider.ExportCourseGrades(ctx, &mdlapi.ExportCourseGradesRequest{
 		CourseID:   courseID,
 		TemplateID: templateID,
@@ -49,9 +81,49 @@ func (uc *ExportUseCase) UploadTemplate(
 	req *mdlapi.UploadTemplateRequest,
 ) (*mdlapi.UploadTemplateResponse, error) {
 	logger.InfoContext(ctx, "UploadTemplate", "type", req.Type, "name", req.Name)
+
+	data, err := base64.StdEncoding.DecodeString(req.Filedata)
+	if err != nil {
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: "filedata is not valid base64"}
+	}
+	if _, err := gradeexport.ValidateTemplate(req.Type, req.Filename, data); err != nil {
+		logger.WarnContext(ctx, "UploadTemplate rejected", "type", req.Type, "filename", req.Filename, "err", err)
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
 	return uc.provider.UploadTemplate(ctx, req)
 }
 
+// PreviewTemplate renders a course export without storing anything. It renders
+// either a stored template (templateID) or an uploaded file (filename and
+// base64 filedata); uploaded files are validated like UploadTemplate.
+func (uc *ExportUseCase) PreviewTemplate(
+	ctx context.Context,
+	courseID int,
+	templateID, filename, filedata string,
+) (*mdlapi.ExportCourseGradesResponse, error) {
+	logger.InfoContext(ctx, "PreviewTemplate", "courseID", courseID, "templateID", templateID, "filename", filename)
+
+	if filedata == "" {
+		return uc.ExportCourseGrades(ctx, courseID, templateID)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(filedata)
+	if err != nil {
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: "filedata is not valid base64"}
+	}
+	format, err := gradeexport.ValidateTemplate(gradeexport.CourseTemplateType, filename, data)
+	if err != nil {
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+	if format != gradeexport.FormatDOCX || !gradeexport.IsNativeDOCX(data) {
+		return nil, &errs.Error{
+			Code:    errs.FailedPrecondition,
+			Message: "only DOCX templates with {{placeholders}} can be previewed before upload",
+		}
+	}
+	return uc.exportNativeDOCX(ctx, courseID, data)
+}
+
 func (uc *ExportUseCase) DeleteTemplate(
 	ctx context.Context,
 	templateType, templateID string,
@@ -62,3 +134,105 @@ func (uc *ExportUseCase) DeleteTemplate(
 		TemplateID: templateID,
 	})
 }
+
+// exportNativeXLSX builds the course gradebook in Go, independently of the
+// Moodle export plugin.
+func (uc *ExportUseCase) exportNativeXLSX(
+	ctx context.Context,
+	courseID int,
+) (*mdlapi.ExportCourseGradesResponse, error) {
+	details, averages, err := uc.courseExportData(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := gradeexport.BuildXLSX(details, averages)
+	if err != nil {
+		logger.ErrorContext(ctx, "exportNativeXLSX BuildXLSX error", "err", err, "courseID", courseID)
+		return nil, err
+	}
+
+	return &mdlapi.ExportCourseGradesResponse{
+		Filename: gradeexport.Filename(details, "xlsx"),
+		Mimetype: gradeexport.MimetypeXLSX,
+		Filedata: base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// nativeDOCXTemplate downloads a stored course template and returns it when it
+// is a DOCX written for the SMS renderer. Other templates, or any failure to
+// download them, leave the export to the Moodle plugin.
+func (uc *ExportUseCase) nativeDOCXTemplate(ctx context.Context, templateID string) []byte {
+	resp, err := uc.provider.GetTemplateContent(ctx, &mdlapi.GetTemplateContentRequest{
+		Type:       gradeexport.CourseTemplateType,
+		TemplateID: templateID,
+	})
+	if err != nil {
+		logger.WarnContext(ctx, "GetTemplateContent error, falling back to Moodle export",
+			"err", err, "templateID", templateID)
+		return nil
+	}
+	if resp.Format != "docx" {
+		return nil
+	}
+
+	tpl, err := base64.StdEncoding.DecodeString(resp.Filedata)
+	if err != nil {
+		logger.WarnContext(ctx, "GetTemplateContent returned invalid base64", "err", err, "templateID", templateID)
+		return nil
+	}
+	if !gradeexport.IsNativeDOCX(tpl) {
+		return nil
+	}
+	return tpl
+}
+
+func (uc *ExportUseCase) exportNativeDOCX(
+	ctx context.Context,
+	courseID int,
+	tpl []byte,
+) (*mdlapi.ExportCourseGradesResponse, error) {
+	details, averages, err := uc.courseExportData(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := gradeexport.RenderCourseDOCX(tpl, details, averages)
+	if err != nil {
+		logger.ErrorContext(ctx, "exportNativeDOCX RenderCourseDOCX error", "err", err, "courseID", courseID)
+		return nil, &errs.Error{Code: errs.FailedPrecondition, Message: "invalid export template: " + err.Error()}
+	}
+
+	return &mdlapi.ExportCourseGradesResponse{
+		Filename: gradeexport.Filename(details, "docx"),
+		Mimetype: gradeexport.MimetypeDOCX,
+		Filedata: base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// courseExportData fetches the gradebook of a course with its averages.
+func (uc *ExportUseCase) courseExportData(
+	ctx context.Context,
+	courseID int,
+) (*mdlapi.GetCourseGradesResponse, []entities.StudentAverage, error) {
+	details, err := uc.courseGradesProvider.GetCourseDetails(
+		ctx,
+		&mdlapi.GetCourseGradesRequest{CourseId: int64(courseID)},
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "Export GetCourseDetails error", "err", err, "courseID", courseID)
+		return nil, nil, err
+	}
+
+	averages, err := courseAverages(ctx, uc.coefRepo, details)
+	if err != nil {
+		return nil, nil, err
+	}
+	return details, averages, nil
+}
+
+func nativeTemplates() mdlapi.GetCourseTemplatesResponse {
+	return mdlapi.GetCourseTemplatesResponse{
+		{ID: gradeexport.NativeXLSXTemplateID, Name: "Gradebook (XLSX)", Format: "xlsx"},
+	}
+}
@@ -0,0 +1,334 @@
+package usecases
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.app/internal/authz"
+	"encore.app/internal/entities"
+	"encore.app/internal/exportjobs"
+	"encore.app/internal/logger"
+	"encore.app/internal/mdlapi"
+	"encore.app/internal/pool"
+	"encore.dev/beta/errs"
+	"github.com/google/uuid"
+)
+
+const (
+	// exportJobTimeout bounds a whole export job, however many courses it has.
+	exportJobTimeout = 30 * time.Minute
+	// exportMoodleTimeout replaces the default Moodle timeout inside jobs,
+	// since the plugin can take minutes to export a large course.
+	exportMoodleTimeout = 5 * time.Minute
+	mimetypeZIP         = "application/zip"
+)
+
+// ExportJobUseCase runs course and category exports in the background on a
+// pool of their own and keeps their state in the job repository.
+type ExportJobUseCase struct {
+	repo            exportjobs.Repository
+	exportUseCase   *ExportUseCase
+	teacherProvider mdlapi.LocalTeacherProvider
+	authorizer      *authz.Authorizer
+	pool            *pool.Pool
+}
+
+func NewExportJobUseCase(
+	repo exportjobs.Repository,
+	exportUseCase *ExportUseCase,
+	teacherProvider mdlapi.LocalTeacherProvider,
+	authorizer *authz.Authorizer,
+	p *pool.Pool,
+) *ExportJobUseCase {
+	return &ExportJobUseCase{
+		repo:            repo,
+		exportUseCase:   exportUseCase,
+		teacherProvider: teacherProvider,
+		authorizer:      authorizer,
+		pool:            p,
+	}
+}
+
+// CreateJob queues the export of one course (courseID) or of every course in
+// a category (categoryID) the actor can see, and returns immediately.
+func (uc *ExportJobUseCase) CreateJob(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	courseID, categoryID int64,
+	templateID string,
+) (*exportjobs.Job, error) {
+	logger.InfoContext(ctx, "Processing CreateExportJob",
+		"courseID", courseID, "categoryID", categoryID, "templateID", templateID)
+
+	now := time.Now().UTC()
+	job := &exportjobs.Job{
+		ID:           uuid.NewString(),
+		CourseID:     courseID,
+		CategoryID:   categoryID,
+		TemplateID:   templateID,
+		Status:       exportjobs.StatusQueued,
+		CreatedBy:    actor.UserID,
+		CreatedByKey: actor.APIKeyID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := uc.repo.Save(ctx, job); err != nil {
+		logger.ErrorContext(ctx, "CreateExportJob save error", "err", err)
+		return nil, err
+	}
+
+	// Copy the job so the worker never shares memory with the response.
+	queued := *job
+	_, err := uc.pool.SubmitWithTimeout(pool.TaskFunc(func(taskCtx context.Context) error {
+		return uc.run(taskCtx, actor, &queued)
+	}), exportJobTimeout)
+	if err != nil {
+		logger.ErrorContext(ctx, "CreateExportJob submit error", "err", err, "jobID", job.ID)
+		uc.fail(ctx, job, "could not queue the export")
+		if errors.Is(err, pool.ErrPoolFull) {
+			return nil, &errs.Error{Code: errs.ResourceExhausted, Message: "too many exports in progress, try again later"}
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetJob returns a job created by the actor. Callers with
+// courses.access_all can read any job.
+func (uc *ExportJobUseCase) GetJob(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	id string,
+) (*exportjobs.Job, error) {
+	job, err := uc.repo.Get(ctx, id)
+	if errors.Is(err, exportjobs.ErrNotFound) {
+		return nil, &errs.Error{Code: errs.NotFound, Message: "export job not found"}
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "GetExportJob error", "err", err, "jobID", id)
+		return nil, err
+	}
+	if createdBy(job, actor) {
+		return job, nil
+	}
+	all, err := uc.authorizer.Permits(ctx, actor, authz.PermCoursesAccessAll)
+	if err != nil {
+		logger.ErrorContext(ctx, "GetExportJob policy error", "err", err, "jobID", id)
+		return nil, err
+	}
+	if !all {
+		return nil, &errs.Error{Code: errs.NotFound, Message: "export job not found"}
+	}
+	return job, nil
+}
+
+// DownloadJob returns the file of a finished job.
+func (uc *ExportJobUseCase) DownloadJob(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	id string,
+) (*mdlapi.ExportCourseGradesResponse, error) {
+	job, err := uc.GetJob(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != exportjobs.StatusSucceeded {
+		return nil, &errs.Error{
+			Code:    errs.FailedPrecondition,
+			Message: fmt.Sprintf("export job is %s", job.Status),
+		}
+	}
+
+	data, err := uc.repo.GetFile(ctx, id)
+	if errors.Is(err, exportjobs.ErrNotFound) {
+		return nil, &errs.Error{Code: errs.NotFound, Message: "export file has expired"}
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "DownloadExportJob error", "err", err, "jobID", id)
+		return nil, err
+	}
+
+	return &mdlapi.ExportCourseGradesResponse{
+		Filename: job.Filename,
+		Mimetype: job.Mimetype,
+		Filedata: base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// createdBy reports whether the actor created the job. Services all have
+// user ID 0, so they are told apart by their API key.
+func createdBy(job *exportjobs.Job, actor *entities.TokenPayload) bool {
+	if job.CreatedByKey != 0 || actor.APIKeyID != 0 {
+		return job.CreatedByKey == actor.APIKeyID
+	}
+	return job.CreatedBy == actor.UserID
+}
+
+func (uc *ExportJobUseCase) run(ctx context.Context, actor *entities.TokenPayload, job *exportjobs.Job) error {
+	ctx = mdlapi.WithTimeout(ctx, exportMoodleTimeout)
+	logger.InfoContext(ctx, "Running export job", "jobID", job.ID)
+
+	job.Status = exportjobs.StatusRunning
+	uc.save(ctx, job)
+
+	var err error
+	if job.CategoryID != 0 {
+		err = uc.runCategory(ctx, actor, job)
+	} else {
+		err = uc.runCourse(ctx, job)
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Export job failed", "err", err, "jobID", job.ID)
+		uc.fail(ctx, job, err.Error())
+		return err
+	}
+
+	finished := time.Now().UTC()
+	job.Status = exportjobs.StatusSucceeded
+	job.FinishedAt = &finished
+	uc.save(ctx, job)
+	logger.InfoContext(ctx, "Export job finished", "jobID", job.ID)
+	return nil
+}
+
+func (uc *ExportJobUseCase) runCourse(ctx context.Context, job *exportjobs.Job) error {
+	job.Total = 1
+	uc.save(ctx, job)
+
+	filename, mimetype, data, err := uc.exportCourse(ctx, int(job.CourseID), job.TemplateID)
+	if err != nil {
+		return err
+	}
+	if err := uc.repo.SaveFile(ctx, job.ID, data); err != nil {
+		return err
+	}
+
+	job.Done = 1
+	job.Filename = filename
+	job.Mimetype = mimetype
+	return nil
+}
+
+// runCategory exports every course of the category into one ZIP archive.
+// Courses that fail are listed on the job; the job only fails when none
+// could be exported.
+func (uc *ExportJobUseCase) runCategory(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	job *exportjobs.Job,
+) error {
+	courses, err := uc.categoryCourses(ctx, actor, int(job.CategoryID))
+	if err != nil {
+		return err
+	}
+	if len(courses) == 0 {
+		return errors.New("the category has no courses to export")
+	}
+
+	job.Total = len(courses)
+	uc.save(ctx, job)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, c := range courses {
+		filename, _, data, err := uc.exportCourse(ctx, c.ID, job.TemplateID)
+		if err == nil {
+			err = addZipFile(zw, fmt.Sprintf("%d-%s", c.ID, filename), data)
+		}
+		if err != nil {
+			logger.WarnContext(ctx, "Export job course failed", "err", err, "jobID", job.ID, "courseID", c.ID)
+			job.Failures = append(job.Failures, exportjobs.CourseFailure{CourseID: c.ID, Error: err.Error()})
+		}
+		job.Done++
+		uc.save(ctx, job)
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if len(job.Failures) == len(courses) {
+		return errors.New("no course of the category could be exported")
+	}
+
+	if err := uc.repo.SaveFile(ctx, job.ID, buf.Bytes()); err != nil {
+		return err
+	}
+	job.Filename = fmt.Sprintf("grades-category-%d.zip", job.CategoryID)
+	job.Mimetype = mimetypeZIP
+	return nil
+}
+
+// categoryCourses lists the courses of a category: all of them for callers
+// with courses.access_all, only the ones they teach for the others.
+func (uc *ExportJobUseCase) categoryCourses(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	categoryID int,
+) ([]mdlapi.CategoryCourse, error) {
+	all, own, err := courseScope(ctx, uc.authorizer, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &mdlapi.GetCategoryCoursesRequest{CategoryID: categoryID}
+	var resp *mdlapi.GetCategoryCoursesResponse
+	switch {
+	case all:
+		resp, err = uc.teacherProvider.GetAllCategoryCoursesForAdmin(ctx, req)
+	case own:
+		req.UserID = int(actor.UserID)
+		resp, err = uc.teacherProvider.GetCategoryCourses(ctx, req)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Courses, nil
+}
+
+func (uc *ExportJobUseCase) exportCourse(
+	ctx context.Context,
+	courseID int,
+	templateID string,
+) (filename, mimetype string, data []byte, err error) {
+	resp, err := uc.exportUseCase.ExportCourseGrades(ctx, courseID, templateID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	data, err = base64.StdEncoding.DecodeString(resp.Filedata)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("decode export of course %d: %w", courseID, err)
+	}
+	return resp.Filename, resp.Mimetype, data, nil
+}
+
+func addZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// save persists job progress. Failures are logged only: the export itself
+// continues and the next save may succeed.
+func (uc *ExportJobUseCase) save(ctx context.Context, job *exportjobs.Job) {
+	job.UpdatedAt = time.Now().UTC()
+	if err := uc.repo.Save(ctx, job); err != nil {
+		logger.ErrorContext(ctx, "Save export job error", "err", err, "jobID", job.ID)
+	}
+}
+
+func (uc *ExportJobUseCase) fail(ctx context.Context, job *exportjobs.Job, reason string) {
+	finished := time.Now().UTC()
+	job.Status = exportjobs.StatusFailed
+	job.Error = reason
+	job.FinishedAt = &finished
+	uc.save(ctx, job)
+}
@@ -0,0 +1,78 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"encore.app/internal/authz"
+	"encore.app/internal/entities"
+	"encore.app/internal/exportjobs"
+	"encore.dev/beta/errs"
+)
+
+// defaultPolicy serves the default policy.
+type defaultPolicy struct{ authz.Repository }
+
+func (defaultPolicy) Get(context.Context) (authz.Policy, error) { return nil, nil }
+
+type memJobs struct {
+	exportjobs.Repository
+	jobs map[string]*exportjobs.Job
+}
+
+func (r *memJobs) Get(_ context.Context, id string) (*exportjobs.Job, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, exportjobs.ErrNotFound
+	}
+	return job, nil
+}
+
+func TestGetJobAccess(t *testing.T) {
+	repo := &memJobs{jobs: map[string]*exportjobs.Job{
+		"teacher": {ID: "teacher", CreatedBy: 10},
+		"service": {ID: "service", CreatedByKey: 7},
+	}}
+	uc := NewExportJobUseCase(repo, nil, nil, authz.NewAuthorizer(defaultPolicy{}), nil)
+
+	teacher := &entities.TokenPayload{UserID: 10, Role: entities.RoleTeacher}
+	otherTeacher := &entities.TokenPayload{UserID: 11, Role: entities.RoleTeacher}
+	manager := &entities.TokenPayload{UserID: 3, Role: entities.RoleManager}
+	service := &entities.TokenPayload{Role: entities.RoleService, APIKeyID: 7}
+	otherService := &entities.TokenPayload{Role: entities.RoleService, APIKeyID: 8}
+	allCourses := &entities.TokenPayload{
+		Role:     entities.RoleService,
+		APIKeyID: 9,
+		Scopes:   []string{string(authz.PermCoursesAccessAll)},
+	}
+
+	tests := []struct {
+		name   string
+		actor  *entities.TokenPayload
+		job    string
+		readOK bool
+	}{
+		{"creator", teacher, "teacher", true},
+		{"other teacher", otherTeacher, "teacher", false},
+		{"courses.access_all", manager, "teacher", true},
+		{"creating key", service, "service", true},
+		{"other key", otherService, "service", false},
+		{"key of a user job", service, "teacher", false},
+		{"user of a key job", &entities.TokenPayload{Role: entities.RoleTeacher}, "service", false},
+		{"key with courses.access_all", allCourses, "service", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := uc.GetJob(context.Background(), tc.actor, tc.job)
+			// errs.Code needs the Encore runtime.
+			var e *errs.Error
+			switch {
+			case tc.readOK && err != nil:
+				t.Fatalf("GetJob: %v", err)
+			case !tc.readOK && (!errors.As(err, &e) || e.Code != errs.NotFound):
+				t.Fatalf("GetJob = %v, want NotFound", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package usecases
+
+import (
+	"context"
+
+	"encore.app/internal/entities"
+	"encore.app/internal/gradecalc"
+	"encore.app/internal/logger"
+	"encore.app/internal/mdlapi"
+	"encore.dev/beta/errs"
+)
+
+// GradeCalcUseCase manages the per-category coefficients used to compute
+// weighted course averages.
+type GradeCalcUseCase struct {
+	coefRepo gradecalc.Repository
+}
+
+func NewGradeCalcUseCase(coefRepo gradecalc.Repository) *GradeCalcUseCase {
+	return &GradeCalcUseCase{coefRepo: coefRepo}
+}
+
+// GetCoefficients returns the category's coefficients, or the defaults when
+// the category has none configured.
+func (uc *GradeCalcUseCase) GetCoefficients(
+	ctx context.Context,
+	categoryID int64,
+) (*gradecalc.Coefficients, error) {
+	return coefficientsFor(ctx, uc.coefRepo, categoryID)
+}
+
+func (uc *GradeCalcUseCase) SetCoefficients(
+	ctx context.Context,
+	categoryID int64,
+	coef *gradecalc.Coefficients,
+) error {
+	logger.InfoContext(ctx, "Processing SetCoefficients", "categoryId", categoryID, "coefficients", coef)
+
+	if err := coef.Validate(); err != nil {
+		return &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+	if err := uc.coefRepo.Set(ctx, categoryID, coef); err != nil {
+		logger.ErrorContext(ctx, "SetCoefficients error", "err", err, "categoryId", categoryID)
+		return err
+	}
+	return nil
+}
+
+// DeleteCoefficients reverts a category to the default coefficients.
+func (uc *GradeCalcUseCase) DeleteCoefficients(ctx context.Context, categoryID int64) error {
+	logger.InfoContext(ctx, "Processing DeleteCoefficients", "categoryId", categoryID)
+
+	if err := uc.coefRepo.Delete(ctx, categoryID); err != nil {
+		logger.ErrorContext(ctx, "DeleteCoefficients error", "err", err, "categoryId", categoryID)
+		return err
+	}
+	return nil
+}
+
+func coefficientsFor(
+	ctx context.Context,
+	repo gradecalc.Repository,
+	categoryID int64,
+) (*gradecalc.Coefficients, error) {
+	coef, err := repo.Get(ctx, categoryID)
+	if err != nil {
+		logger.ErrorContext(ctx, "Get coefficients error", "err", err, "categoryId", categoryID)
+		return nil, err
+	}
+	if coef == nil {
+		return gradecalc.DefaultCoefficients(), nil
+	}
+	return coef, nil
+}
+
+// courseAverages computes the averages of every student in a course using
+// the coefficients of the course's category.
+func courseAverages(
+	ctx context.Context,
+	repo gradecalc.Repository,
+	details *mdlapi.GetCourseGradesResponse,
+) ([]entities.StudentAverage, error) {
+	coef, err := coefficientsFor(ctx, repo, int64(details.Course.Category))
+	if err != nil {
+		return nil, err
+	}
+	return gradecalc.ComputeAverages(details, coef), nil
+}
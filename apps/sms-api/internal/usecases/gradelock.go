@@ -0,0 +1,98 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"encore.app/internal/entities"
+	"encore.app/internal/gradelocks"
+	"encore.app/internal/logger"
+	"encore.app/internal/mdlapi"
+	"encore.dev/beta/errs"
+)
+
+// GradeLockUseCase manages the finalisation locks that freeze course grades.
+type GradeLockUseCase struct {
+	lockRepo gradelocks.Repository
+}
+
+func NewGradeLockUseCase(lockRepo gradelocks.Repository) *GradeLockUseCase {
+	return &GradeLockUseCase{lockRepo: lockRepo}
+}
+
+// LockGrades freezes a whole course (empty examType) or one exam type in it.
+// Locking a scope that is already covered by an active lock is rejected.
+func (uc *GradeLockUseCase) LockGrades(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	courseID int64,
+	examType, reason string,
+) (*gradelocks.Lock, error) {
+	logger.InfoContext(ctx, "Processing LockGrades", "courseId", courseID, "examType", examType)
+
+	if _, ok := mdlapi.ReverseExamTypeMap[examType]; examType != "" && !ok {
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: "invalid exam type: " + examType}
+	}
+
+	lock := &gradelocks.Lock{
+		CourseID: courseID,
+		ExamType: examType,
+		Reason:   reason,
+		LockedBy: actor.UserID,
+		LockedAt: time.Now().UTC(),
+	}
+	err := uc.lockRepo.Create(ctx, lock)
+	var conflict *gradelocks.ConflictError
+	if errors.As(err, &conflict) {
+		return nil, &errs.Error{
+			Code:    errs.AlreadyExists,
+			Message: lockMessage(&gradelocks.Lock{ExamType: conflict.ExamType}),
+		}
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "LockGrades Create error", "err", err, "courseId", courseID)
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// UnlockGrades lifts an active lock. The reason is mandatory and kept with
+// the lock row.
+func (uc *GradeLockUseCase) UnlockGrades(
+	ctx context.Context,
+	actor *entities.TokenPayload,
+	courseID, lockID int64,
+	reason string,
+) (*gradelocks.Lock, error) {
+	logger.InfoContext(ctx, "Processing UnlockGrades", "courseId", courseID, "lockId", lockID)
+
+	if reason == "" {
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: "reason is required"}
+	}
+
+	lock, err := uc.lockRepo.Unlock(ctx, courseID, lockID, actor.UserID, reason)
+	if errors.Is(err, gradelocks.ErrNotFound) {
+		return nil, &errs.Error{Code: errs.NotFound, Message: "active lock not found"}
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "UnlockGrades error", "err", err, "lockId", lockID)
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// GetLocks returns every lock of a course, active and lifted.
+func (uc *GradeLockUseCase) GetLocks(
+	ctx context.Context,
+	courseID int64,
+) (*gradelocks.ListResponse, error) {
+	locks, err := uc.lockRepo.FindAll(ctx, courseID)
+	if err != nil {
+		logger.ErrorContext(ctx, "GetLocks error", "err", err, "courseId", courseID)
+		return nil, err
+	}
+	return &gradelocks.ListResponse{Data: locks}, nil
+}
@@ -0,0 +1,151 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"encore.app/internal/config"
+	"encore.app/internal/gradehistory"
+	"encore.app/internal/logger"
+	"encore.app/internal/mdlcache"
+	"encore.app/internal/mdlevents"
+	"encore.dev/beta/errs"
+)
+
+// MoodleEventUseCase takes in the events Moodle posts to the webhook and
+// keeps the SMS in step with them.
+type MoodleEventUseCase struct {
+	cfg         *config.WebhookConfig
+	repo        mdlevents.Repository
+	cache       *mdlcache.Cache
+	historyRepo gradehistory.Repository
+}
+
+func NewMoodleEventUseCase(
+	cfg *config.WebhookConfig,
+	repo mdlevents.Repository,
+	cache *mdlcache.Cache,
+	historyRepo gradehistory.Repository,
+) *MoodleEventUseCase {
+	return &MoodleEventUseCase{cfg: cfg, repo: repo, cache: cache, historyRepo: historyRepo}
+}
+
+// Accept checks the signature of an event posted to the webhook and
+// publishes it, unless it was already. It reports whether it published it.
+func (uc *MoodleEventUseCase) Accept(
+	ctx context.Context,
+	timestamp, signature string,
+	body []byte,
+	publisher mdlevents.Publisher,
+) (*mdlevents.Event, bool, error) {
+	tolerance := time.Duration(uc.cfg.Tolerance) * time.Second
+	if err := mdlevents.Verify(uc.cfg.Secret, timestamp, signature, body, time.Now(), tolerance); err != nil {
+		logger.WarnContext(ctx, "Refused Moodle event", "err", err)
+		return nil, false, &errs.Error{Code: errs.Unauthenticated, Message: "invalid webhook signature"}
+	}
+
+	event := &mdlevents.Event{}
+	if err := json.Unmarshal(body, event); err != nil {
+		return nil, false, &errs.Error{Code: errs.InvalidArgument, Message: "malformed event"}
+	}
+	if err := event.Validate(); err != nil {
+		return nil, false, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()}
+	}
+
+	first, err := uc.repo.Claim(ctx, event.ID)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to claim Moodle event", "err", err, "eventId", event.ID)
+		return nil, false, err
+	}
+	if !first {
+		logger.InfoContext(ctx, "Moodle event already published", "eventId", event.ID)
+		return event, false, nil
+	}
+
+	if _, err := publisher.Publish(ctx, event); err != nil {
+		logger.ErrorContext(ctx, "Failed to publish Moodle event", "err", err, "eventId", event.ID)
+		// Let Moodle's retry publish it.
+		if err := uc.repo.Release(ctx, event.ID); err != nil {
+			logger.ErrorContext(ctx, "Failed to release Moodle event", "err", err, "eventId", event.ID)
+		}
+		return nil, false, err
+	}
+	return event, true, nil
+}
+
+// Invalidate drops the cached Moodle data an event changes: the course, the
+// category a course was updated in, and what the user enrolled or given a
+// role sees.
+func (uc *MoodleEventUseCase) Invalidate(ctx context.Context, event *mdlevents.Event) error {
+	flushed := 0
+	if event.CourseID != 0 {
+		n, err := uc.cache.FlushCourse(ctx, event.CourseID)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to flush course cache", "err", err, "courseId", event.CourseID)
+			return err
+		}
+		flushed += n
+	}
+
+	switch event.EventName {
+	case mdlevents.CourseUpdated:
+		if event.CategoryID != 0 {
+			n, err := uc.cache.FlushCategory(ctx, event.CategoryID)
+			if err != nil {
+				logger.ErrorContext(ctx, "Failed to flush category cache", "err", err, "categoryId", event.CategoryID)
+				return err
+			}
+			flushed += n
+		}
+	case mdlevents.UserEnrolled, mdlevents.UserUnenrolled, mdlevents.RoleAssigned, mdlevents.RoleUnassigned:
+		if event.RelatedUserID != 0 {
+			n, err := uc.cache.FlushUser(ctx, event.RelatedUserID)
+			if err != nil {
+				logger.ErrorContext(ctx, "Failed to flush user cache", "err", err, "userId", event.RelatedUserID)
+				return err
+			}
+			flushed += n
+		}
+	}
+
+	logger.InfoContext(ctx, "Cache flushed for Moodle event",
+		"event", event.EventName, "eventId", event.ID, "entries", flushed)
+	return nil
+}
+
+// RecordGrade adds a grade changed in Moodle to the grade history. Grades
+// written through Moodle's web services are left out: the SMS writes its own
+// that way, and records them as it does. Redeliveries of an event are
+// recorded once.
+func (uc *MoodleEventUseCase) RecordGrade(ctx context.Context, event *mdlevents.Event) error {
+	if event.EventName != mdlevents.UserGraded || event.Origin == mdlevents.OriginWebService {
+		return nil
+	}
+	g := event.Grade
+	// The history tracks activity grades; removed grades have no new value.
+	if g == nil || g.ActivityID == 0 || g.FinalGrade == nil {
+		return nil
+	}
+	if g.OldGrade != nil && *g.OldGrade == *g.FinalGrade {
+		return nil
+	}
+
+	entry := gradehistory.Entry{
+		CourseID:   event.CourseID,
+		ActivityID: g.ActivityID,
+		ItemNumber: g.ItemNumber,
+		StudentID:  event.RelatedUserID,
+		OldGrade:   g.OldGrade,
+		NewGrade:   *g.FinalGrade,
+		ActorID:    event.UserID,
+		ActorRole:  gradehistory.ActorRoleMoodle,
+		EventID:    event.ID,
+		ChangedAt:  time.Unix(event.TimeCreated, 0).UTC(),
+	}
+	if err := uc.historyRepo.SaveAll(ctx, []gradehistory.Entry{entry}); err != nil {
+		logger.ErrorContext(ctx, "Failed to record Moodle grade", "err", err, "eventId", event.ID)
+		return err
+	}
+	return nil
+}
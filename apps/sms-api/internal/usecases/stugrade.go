@@ -2,17 +2,41 @@ package usecases
 
 import (
 	"context"
+	"encoding/base64"
+	"time"
 
+	"encore.app/internal/config"
+	"encore.app/internal/entities"
+	"encore.app/internal/gradecalc"
+	"encore.app/internal/gradeexport"
 	"encore.app/internal/logger"
 	"encore.app/internal/mdlapi"
+	"encore.app/internal/pool"
+	"encore.dev/beta/errs"
 )
 
 type StudentGradeUseCase struct {
-	userGradesProvider mdlapi.UserGradeItemsProvider
+	userGradesProvider   mdlapi.UserGradeItemsProvider
+	courseGradesProvider mdlapi.LocalCourseGrades
+	coefRepo             gradecalc.Repository
+	pool                 *pool.Pool
+	school               *config.SchoolConfig
 }
 
-func NewStudentGradeUseCase(userGradesProvider mdlapi.UserGradeItemsProvider) *StudentGradeUseCase {
-	return &StudentGradeUseCase{userGradesProvider: userGradesProvider}
+func NewStudentGradeUseCase(
+	userGradesProvider mdlapi.UserGradeItemsProvider,
+	courseGradesProvider mdlapi.LocalCourseGrades,
+	coefRepo gradecalc.Repository,
+	p *pool.Pool,
+	school *config.SchoolConfig,
+) *StudentGradeUseCase {
+	return &StudentGradeUseCase{
+		userGradesProvider:   userGradesProvider,
+		courseGradesProvider: courseGradesProvider,
+		coefRepo:             coefRepo,
+		pool:                 p,
+		school:               school,
+	}
 }
 
 func (uc *StudentGradeUseCase) GetStudentGrades(
@@ -27,5 +51,87 @@ func (uc *StudentGradeUseCase) GetStudentGrades(
 		return nil, err
 	}
 
+	resp.Averages = uc.studentAverages(ctx, req.UserID, resp)
 	return resp, nil
 }
+
+// GetTranscript renders the grades of a student across all of their courses
+// as a printable PDF or DOCX transcript.
+func (uc *StudentGradeUseCase) GetTranscript(
+	ctx context.Context,
+	userID int,
+	format string,
+) (*mdlapi.ExportCourseGradesResponse, error) {
+	if format == "" {
+		format = gradeexport.FormatPDF
+	}
+	if format != gradeexport.FormatPDF && format != gradeexport.FormatDOCX {
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: "format must be pdf or docx"}
+	}
+
+	grades, err := uc.GetStudentGrades(ctx, &mdlapi.GetUserGradesRequest{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	transcript := gradeexport.NewTranscript(*uc.school, grades, time.Now())
+	data, filename, mimetype, err := gradeexport.BuildTranscript(transcript, format)
+	if err != nil {
+		logger.ErrorContext(ctx, "GetTranscript BuildTranscript error", "err", err, "userId", userID)
+		return nil, err
+	}
+	return &mdlapi.ExportCourseGradesResponse{
+		Filename: filename,
+		Mimetype: mimetype,
+		Filedata: base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// studentAverages computes the student's average in each of their courses.
+// The rank needs the whole class, so every course's details are fetched in
+// parallel through the worker pool. Courses whose details cannot be loaded
+// are left out rather than failing the whole response.
+func (uc *StudentGradeUseCase) studentAverages(
+	ctx context.Context,
+	userID int,
+	resp *mdlapi.GetUserGradesResponse,
+) []entities.CourseAverage {
+	// Each task writes only its own slot, so no locking is needed.
+	found := make([]*entities.CourseAverage, len(resp.Courses))
+	tasks := make([]pool.Task, len(resp.Courses))
+	for i, c := range resp.Courses {
+		courseID := c.CourseID
+		tasks[i] = pool.TaskFunc(func(taskCtx context.Context) error {
+			details, err := uc.courseGradesProvider.GetCourseDetails(
+				taskCtx,
+				&mdlapi.GetCourseGradesRequest{CourseId: int64(courseID)},
+			)
+			if err != nil {
+				return err
+			}
+			all, err := courseAverages(taskCtx, uc.coefRepo, details)
+			if err != nil {
+				return err
+			}
+			if avg := gradecalc.FindStudent(all, userID); avg != nil {
+				found[i] = &entities.CourseAverage{CourseID: courseID, StudentAverage: *avg}
+			}
+			return nil
+		})
+	}
+
+	taskErrs := runTasks(ctx, uc.pool, tasks)
+	averages := make([]entities.CourseAverage, 0, len(found))
+	for i, err := range taskErrs {
+		if err != nil {
+			logger.ErrorContext(ctx, "GetStudentGrades course average error",
+				"err", err, "courseId", resp.Courses[i].CourseID)
+			continue
+		}
+		if found[i] != nil {
+			averages = append(averages, *found[i])
+		}
+	}
+
+	return averages
+}
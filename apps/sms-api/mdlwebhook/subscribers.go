@@ -0,0 +1,29 @@
+package mdlwebhook
+
+import (
+	"context"
+
+	"encore.app/authn"
+	"encore.app/internal/mdlevents"
+	"encore.dev/pubsub"
+)
+
+var _ = pubsub.NewSubscription(MoodleEvents, "invalidate-moodle-cache", pubsub.SubscriptionConfig[*mdlevents.Event]{
+	Handler: invalidateCache,
+})
+
+var _ = pubsub.NewSubscription(MoodleEvents, "record-moodle-grades", pubsub.SubscriptionConfig[*mdlevents.Event]{
+	Handler: recordGrade,
+})
+
+// invalidateCache drops the cached Moodle data an event changes, so that the
+// SMS shows changes made in Moodle without waiting for the cache to expire.
+func invalidateCache(ctx context.Context, event *mdlevents.Event) error {
+	return authn.GetContainer().GetMoodleEventController().Invalidate(ctx, event)
+}
+
+// recordGrade records grades changed in Moodle in the grade history, next to
+// those changed through the SMS.
+func recordGrade(ctx context.Context, event *mdlevents.Event) error {
+	return authn.GetContainer().GetMoodleEventController().RecordGrade(ctx, event)
+}
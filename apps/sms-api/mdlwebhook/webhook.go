@@ -0,0 +1,63 @@
+// Package mdlwebhook receives the events Moodle's local_smswebhook plugin
+// posts, and publishes them on the MoodleEvents topic for the rest of the app.
+package mdlwebhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"encore.app/authn"
+	"encore.app/internal/logger"
+	"encore.app/internal/mdlevents"
+	"encore.dev/beta/errs"
+	"encore.dev/pubsub"
+)
+
+// MoodleEvents carries every event Moodle reports. Moodle retries failed
+// deliveries, so subscribers must cope with seeing an event twice.
+var MoodleEvents = pubsub.NewTopic[*mdlevents.Event]("moodle-events", pubsub.TopicConfig{
+	DeliveryGuarantee: pubsub.AtLeastOnce,
+})
+
+// maxBodySize is far above any event the plugin sends.
+const maxBodySize = 64 << 10
+
+// ReceiveResponse acknowledges an event. Published is false for an event
+// that was already published by an earlier delivery.
+type ReceiveResponse struct {
+	ID        string `json:"id"`
+	Published bool   `json:"published"`
+}
+
+// Moodle event webhook. Requests are signed by the plugin with the shared
+// MOODLE_WEBHOOK_SECRET rather than authenticated.
+//
+//encore:api public raw method=POST path=/webhooks/moodle
+func Receive(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxBodySize))
+	if err != nil {
+		errs.HTTPError(w, &errs.Error{Code: errs.InvalidArgument, Message: "unreadable or oversized body"})
+		return
+	}
+
+	event, published, err := authn.GetContainer().GetMoodleEventController().Accept(
+		ctx,
+		req.Header.Get("X-Moodle-Timestamp"),
+		req.Header.Get("X-Moodle-Signature"),
+		body,
+		MoodleEvents,
+	)
+	if err != nil {
+		errs.HTTPError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(&ReceiveResponse{ID: event.ID, Published: published}); err != nil {
+		logger.ErrorContext(ctx, "Failed to write webhook response", "err", err)
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"encore.app/audit"
+	"encore.app/internal/entities"
+	"encore.dev"
+	"encore.dev/beta/auth"
+	"encore.dev/middleware"
+)
+
+// APIKeyMiddleware audits every request made with an API key as
+// EventAPIKeyRequest, so that the use of each key can be traced. The audit
+// logger adds the key to the entry. Which routes a key may call is decided
+// by AuthzMiddleware.
+//
+//encore:middleware global target=all
+func APIKeyMiddleware(req middleware.Request, next middleware.Next) middleware.Response {
+	encoreReq := encore.CurrentRequest()
+	if encoreReq.Type != encore.APICall {
+		return next(req)
+	}
+	payload, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || payload == nil || payload.APIKeyID == 0 {
+		return next(req)
+	}
+
+	resp := next(req)
+	routeKey := encoreReq.Service + "." + encoreReq.Endpoint
+	logCallerRequest(req.Context(), routeKey, encoreReq, payload, audit.EventAPIKeyRequest, resp)
+	return resp
+}
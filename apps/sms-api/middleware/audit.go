@@ -1,12 +1,14 @@
 package middleware
 
 import (
+	"context"
 	"strings"
 
 	"encore.app/audit"
 	"encore.app/internal/entities"
 	"encore.dev"
 	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
 	"encore.dev/middleware"
 )
 
@@ -27,15 +29,43 @@ var auditWhitelist = map[string]audit.EventType{
 	// OAuth2 callback completes the login flow and issues JWT tokens.
 	"authn.OAuth2Callback": audit.EventLogin,
 	// Refresh-token exchange — user effectively re-authenticates silently.
+	// Recorded as audit.EventRoleChange when the user's role changed in Moodle.
 	"authn.RefreshToken": audit.EventTokenRefresh,
+	// User logs out of the current device, or of every device.
+	"authn.Logout":    audit.EventLogout,
+	"authn.LogoutAll": audit.EventLogoutAll,
+	// Admin force-ends a user's session.
+	"authn.RevokeSession": audit.EventSessionRevoke,
+	// Admin starts viewing the application as another user.
+	"authn.Impersonate": audit.EventImpersonate,
+
+	// ── API keys ──────────────────────────────────────────────────────────
+	// Admin creates, rotates or revokes the API key of a service account.
+	"authn.CreateAPIKey": audit.EventAPIKeyCreate,
+	"authn.RotateAPIKey": audit.EventAPIKeyRotate,
+	"authn.RevokeAPIKey": audit.EventAPIKeyRevoke,
 
 	// ── Grade mutations ───────────────────────────────────────────────────
 	// Teacher writes updated scores for one or more students.
 	"usrcourses.UpdateCourseGrades": audit.EventUpdateGrades,
+	// Teacher writes scores for several activities in one batch.
+	"usrcourses.BatchUpdateCourseGrades": audit.EventUpdateGrades,
+	// Manager / admin freezes grades once the exam board signs off.
+	"usrcourses.LockCourseGrades": audit.EventLockGrades,
+	// Admin lifts a grade lock, giving a reason.
+	"usrcourses.UnlockCourseGrades": audit.EventUnlockGrades,
 
 	// ── Grade export ──────────────────────────────────────────────────────
 	// Teacher / manager generates and downloads a grade sheet.
 	"usrexport.ExportCourseGrades": audit.EventExportGrades,
+	// Same event for background exports, recorded when the job is queued.
+	"usrexport.CreateExportJob": audit.EventExportGrades,
+	// Manager downloads the consolidated report of a category.
+	"usrcategories.ExportCategoryReport": audit.EventExportGrades,
+	// Student prints their own transcript.
+	"usrgrades.GetUserTranscript": audit.EventExportGrades,
+	// Admin / manager prints the transcript of any student.
+	"usrgrades.GetStudentTranscript": audit.EventExportGrades,
 
 	// ── Export template management ────────────────────────────────────────
 	// Admin / manager uploads a DOCX/XLSX template.
@@ -49,6 +79,22 @@ var auditWhitelist = map[string]audit.EventType{
 	// Admin removes the custom pack and reverts all users to defaults.
 	"appconfig.DeleteLangPack": audit.EventDeleteLangPack,
 
+	// ── Grade coefficients ────────────────────────────────────────────────
+	// Admin changes how course averages are weighted for a category.
+	"usrcategories.SetCategoryCoefficients": audit.EventSetCoefficients,
+	// Admin reverts a category to the default coefficients.
+	"usrcategories.DeleteCategoryCoefficients": audit.EventDeleteCoefficients,
+
+	// ── Permissions ───────────────────────────────────────────────────────
+	// Admin changes which roles hold which permissions.
+	"appconfig.SetPermissions": audit.EventSetPermissions,
+	// Admin reverts role permissions to the defaults.
+	"appconfig.ResetPermissions": audit.EventResetPermissions,
+
+	// ── Moodle cache ──────────────────────────────────────────────────────
+	// Admin drops cached Moodle data so that changes show at once.
+	"appconfig.FlushMoodleCache": audit.EventCacheFlush,
+
 	// ── Audit log management ──────────────────────────────────────────────
 	// Admin manually triggers a purge of old audit entries.
 	"auditlog.PurgeAuditLogs": audit.EventAuditPurge,
@@ -72,6 +118,7 @@ func SetAuditLoggerProvider(fn func() *audit.Logger) {
 // Special case: any request that returns an unauthenticated / permission-denied
 // error on a *whitelisted* route is recorded as OutcomeDenied, which gives
 // security teams visibility into failed access attempts on sensitive operations.
+// Permission denials on other routes are recorded by AuthzMiddleware.
 //
 //encore:middleware global target=all
 func AuditMiddleware(req middleware.Request, next middleware.Next) middleware.Response {
@@ -101,6 +148,9 @@ func AuditMiddleware(req middleware.Request, next middleware.Next) middleware.Re
 		return next(req)
 	}
 
+	// Give the handler a place to attach structured details to the entry.
+	req = req.WithContext(audit.WithDetailsHolder(req.Context()))
+
 	// Execute the handler. We need the response before we can determine outcome.
 	resp := next(req)
 
@@ -131,7 +181,48 @@ func AuditMiddleware(req middleware.Request, next middleware.Next) middleware.Re
 	}
 
 	// Write the entry asynchronously — never blocks the request path.
-	al.Log(req.Context(), eventType, actorID, actorRole, outcome, endpoint, nil, errMsg)
+	details := audit.DetailsFromContext(req.Context())
+	eventType = audit.EventTypeFromContext(req.Context(), eventType)
+	al.Log(req.Context(), eventType, actorID, actorRole, outcome, endpoint, details, errMsg)
 
 	return resp
 }
+
+// logCallerRequest audits a request of a caller whose every request is
+// recorded, such as an impersonated session or an API key. Requests to
+// whitelisted routes are already recorded by AuditMiddleware and permission
+// denials by AuthzMiddleware, so only the others are written here.
+func logCallerRequest(
+	ctx context.Context,
+	routeKey string,
+	encoreReq *encore.Request,
+	payload *entities.TokenPayload,
+	eventType audit.EventType,
+	resp middleware.Response,
+) {
+	if _, audited := auditWhitelist[routeKey]; audited {
+		return
+	}
+	if errs.Code(resp.Err) == errs.PermissionDenied {
+		return
+	}
+	if auditLoggerProvider == nil {
+		return
+	}
+	al := auditLoggerProvider()
+	if al == nil {
+		return
+	}
+
+	outcome := audit.OutcomeSuccess
+	errMsg := ""
+	if resp.Err != nil {
+		errMsg = resp.Err.Error()
+		outcome = audit.OutcomeFailure
+		if errs.Code(resp.Err) == errs.Unauthenticated {
+			outcome = audit.OutcomeDenied
+		}
+	}
+	endpoint := encoreReq.Method + " " + encoreReq.Path
+	al.Log(ctx, eventType, payload.UserID, payload.Role, outcome, endpoint, nil, errMsg)
+}
@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"encore.app/audit"
+	"encore.app/internal/authz"
+	"encore.app/internal/entities"
+	"encore.app/internal/logger"
+	"encore.dev"
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+	"encore.dev/middleware"
+)
+
+// permissionRules is the single source of truth for who may call what.
+//
+// Key format: "ServiceName.EndpointName", as in auditWhitelist. Routes that
+// are not listed only require authentication (or none, for public routes).
+// Which roles hold a permission is decided by the authz policy, which admins
+// can change at runtime.
+var permissionRules = map[string]authz.Permission{
+	// ── Sessions ──────────────────────────────────────────────────────────
+	"authn.ListUserSessions": authz.PermSessionsManage,
+	"authn.RevokeSession":    authz.PermSessionsManage,
+	"authn.Impersonate":      authz.PermUsersImpersonate,
+
+	// ── API keys ──────────────────────────────────────────────────────────
+	"authn.ListAPIKeys":  authz.PermAPIKeysManage,
+	"authn.CreateAPIKey": authz.PermAPIKeysManage,
+	"authn.RotateAPIKey": authz.PermAPIKeysManage,
+	"authn.RevokeAPIKey": authz.PermAPIKeysManage,
+
+	// ── Grades ────────────────────────────────────────────────────────────
+	"usrcourses.UpdateCourseGrades":      authz.PermGradesUpdate,
+	"usrcourses.BatchUpdateCourseGrades": authz.PermGradesUpdate,
+	"usrcourses.LockCourseGrades":        authz.PermGradesLock,
+	"usrcourses.UnlockCourseGrades":      authz.PermGradesUnlock,
+	"usrcourses.GetCourseGradeLocks":     authz.PermGradeLocksRead,
+	"usrgrades.GetStudentTranscript":     authz.PermTranscriptReadAny,
+
+	// ── Reports and export jobs ───────────────────────────────────────────
+	"usrcategories.GetCategoryReport":    authz.PermReportsRead,
+	"usrcategories.ExportCategoryReport": authz.PermReportsRead,
+	"usrexport.CreateExportJob":          authz.PermExportJobsRun,
+	"usrexport.GetExportJob":             authz.PermExportJobsRun,
+	"usrexport.DownloadExportJob":        authz.PermExportJobsRun,
+
+	// ── Export templates ──────────────────────────────────────────────────
+	"usrexport.GetAllExportTemplates": authz.PermExportTemplateManage,
+	"usrexport.UploadExportTemplate":  authz.PermExportTemplateManage,
+	"usrexport.PreviewExportTemplate": authz.PermExportTemplateManage,
+	"usrexport.DeleteExportTemplate":  authz.PermExportTemplateManage,
+
+	// ── Configuration ─────────────────────────────────────────────────────
+	"appconfig.SetLangPack":                    authz.PermLangPackManage,
+	"appconfig.DeleteLangPack":                 authz.PermLangPackManage,
+	"usrcategories.GetCategoryCoefficients":    authz.PermCoefficientsRead,
+	"usrcategories.SetCategoryCoefficients":    authz.PermCoefficientsManage,
+	"usrcategories.DeleteCategoryCoefficients": authz.PermCoefficientsManage,
+	"appconfig.GetPermissions":                 authz.PermPermissionsManage,
+	"appconfig.SetPermissions":                 authz.PermPermissionsManage,
+	"appconfig.ResetPermissions":               authz.PermPermissionsManage,
+	"appconfig.FlushMoodleCache":               authz.PermCacheManage,
+
+	// ── Audit log ─────────────────────────────────────────────────────────
+	"auditlog.ListAuditLogs":  authz.PermAuditRead,
+	"auditlog.GetAuditStats":  authz.PermAuditRead,
+	"auditlog.PurgeAuditLogs": authz.PermAuditPurge,
+}
+
+// apiKeyRules adds permissions to routes that users may call with
+// authentication alone. API keys may only call routes listed here or in
+// permissionRules: the others act on behalf of the calling user, and a
+// service account is none.
+var apiKeyRules = map[string]authz.Permission{
+	"usrcourses.GetCourseDetails":        authz.PermCoursesAccessAll,
+	"usrcourses.GetGradeHistory":         authz.PermCoursesAccessAll,
+	"usrexport.GetCourseExportTemplates": authz.PermCoursesAccessAll,
+	"usrexport.ExportCourseGrades":       authz.PermCoursesAccessAll,
+}
+
+// authorizerProvider is wired in by the authn service, which owns the
+// authorizer's dependencies.
+var authorizerProvider func() *authz.Authorizer
+
+// SetAuthorizerProvider is called once during authn service initialisation.
+func SetAuthorizerProvider(fn func() *authz.Authorizer) {
+	authorizerProvider = fn
+}
+
+// AuthzMiddleware rejects calls to routes in permissionRules unless the
+// caller's role, or for API keys their scopes, hold the route's permission.
+// API keys are also held to apiKeyRules. Every denial is audited, also
+// the ones handlers return themselves, e.g. for a course the caller does not
+// teach.
+//
+// It is defined after AuditMiddleware (files are ordered by name), so on
+// audited routes the denial is recorded in the entry AuditMiddleware writes;
+// on other routes it writes its own.
+//
+//encore:middleware global target=all
+func AuthzMiddleware(req middleware.Request, next middleware.Next) middleware.Response {
+	encoreReq := encore.CurrentRequest()
+	if encoreReq.Type != encore.APICall {
+		return next(req)
+	}
+
+	routeKey := encoreReq.Service + "." + encoreReq.Endpoint
+	// Let handlers attach details to a denial on routes AuditMiddleware does
+	// not record, too.
+	if _, audited := auditWhitelist[routeKey]; !audited {
+		req = req.WithContext(audit.WithDetailsHolder(req.Context()))
+	}
+	payload, _ := auth.Data().(*entities.TokenPayload)
+
+	perm, guarded := permissionRules[routeKey]
+	if !guarded && payload != nil && payload.Role == entities.RoleService {
+		perm, guarded = apiKeyRules[routeKey]
+		if !guarded {
+			err := &errs.Error{Code: errs.PermissionDenied, Message: "endpoint is not available to API keys"}
+			recordDenial(req.Context(), routeKey, encoreReq, payload, err)
+			return middleware.Response{Err: err}
+		}
+	}
+
+	if guarded {
+		if err := checkPermission(req.Context(), routeKey, payload, perm); err != nil {
+			if errs.Code(err) == errs.PermissionDenied {
+				audit.SetDetails(req.Context(), map[string]any{"permission": perm})
+				recordDenial(req.Context(), routeKey, encoreReq, payload, err)
+			}
+			return middleware.Response{Err: err}
+		}
+	}
+
+	resp := next(req)
+	if errs.Code(resp.Err) == errs.PermissionDenied {
+		recordDenial(req.Context(), routeKey, encoreReq, payload, resp.Err)
+	}
+	return resp
+}
+
+// checkPermission fails closed: without a caller or an authorizer no
+// protected route can be served.
+func checkPermission(
+	ctx context.Context,
+	routeKey string,
+	payload *entities.TokenPayload,
+	perm authz.Permission,
+) error {
+	if payload == nil {
+		return &errs.Error{Code: errs.Unauthenticated, Message: "not authenticated"}
+	}
+	if authorizerProvider == nil || authorizerProvider() == nil {
+		return &errs.Error{Code: errs.Unavailable, Message: "authorization is not ready"}
+	}
+
+	allowed, err := authorizerProvider().Permits(ctx, payload, perm)
+	if err != nil {
+		logger.ErrorContext(ctx, "AuthzMiddleware: policy lookup failed", "err", err, "route", routeKey)
+		return &errs.Error{Code: errs.Internal, Message: "failed to check permissions"}
+	}
+	if !allowed {
+		return &errs.Error{
+			Code:    errs.PermissionDenied,
+			Message: fmt.Sprintf("permission %s required", perm),
+		}
+	}
+	return nil
+}
+
+// recordDenial audits a permission denial as EventPermissionDenied.
+func recordDenial(
+	ctx context.Context,
+	routeKey string,
+	encoreReq *encore.Request,
+	payload *entities.TokenPayload,
+	denied error,
+) {
+	if _, audited := auditWhitelist[routeKey]; audited {
+		audit.SetEventType(ctx, audit.EventPermissionDenied)
+		return
+	}
+
+	if auditLoggerProvider == nil {
+		return
+	}
+	al := auditLoggerProvider()
+	if al == nil {
+		return
+	}
+
+	var actorID int64
+	var actorRole string
+	if payload != nil {
+		actorID = payload.UserID
+		actorRole = payload.Role
+	}
+	endpoint := encoreReq.Method + " " + encoreReq.Path
+	al.Log(ctx, audit.EventPermissionDenied, actorID, actorRole,
+		audit.OutcomeDenied, endpoint, audit.DetailsFromContext(ctx), denied.Error())
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+
+	"encore.app/audit"
+	"encore.app/internal/entities"
+	"encore.dev"
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+	"encore.dev/middleware"
+)
+
+// impersonationWritable lists the routes other than GET that an impersonation
+// token may call. Every other write is rejected.
+var impersonationWritable = map[string]bool{
+	// Ends the impersonated session.
+	"authn.Logout": true,
+}
+
+// ImpersonationMiddleware keeps impersonated sessions read-only and audits
+// every request made in them as EventImpersonatedRequest. The audit logger
+// adds the impersonator to the entry.
+//
+//encore:middleware global target=all
+func ImpersonationMiddleware(req middleware.Request, next middleware.Next) middleware.Response {
+	encoreReq := encore.CurrentRequest()
+	if encoreReq.Type != encore.APICall {
+		return next(req)
+	}
+	payload, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || payload == nil || payload.Impersonator == 0 {
+		return next(req)
+	}
+
+	routeKey := encoreReq.Service + "." + encoreReq.Endpoint
+	readOnly := encoreReq.Method == http.MethodGet || encoreReq.Method == http.MethodHead
+	if !readOnly && !impersonationWritable[routeKey] {
+		return middleware.Response{Err: &errs.Error{
+			Code:    errs.PermissionDenied,
+			Message: "impersonated sessions are read-only",
+		}}
+	}
+
+	resp := next(req)
+	logCallerRequest(req.Context(), routeKey, encoreReq, payload, audit.EventImpersonatedRequest, resp)
+	return resp
+}
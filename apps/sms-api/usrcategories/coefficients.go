@@ -0,0 +1,43 @@
+package usrcategories
+
+import (
+	"context"
+
+	"encore.app/audit"
+	"encore.app/authn"
+	"encore.app/internal/gradecalc"
+)
+
+// GetCategoryCoefficients returns the exam-type coefficients used to compute
+// course averages in a category. Categories without their own configuration
+// report the defaults.
+//
+//encore:api auth method=GET path=/categories/:categoryId/coefficients
+func GetCategoryCoefficients(
+	ctx context.Context,
+	categoryId int64,
+) (*gradecalc.Coefficients, error) {
+	return authn.GetContainer().GetGradeCalcController().GetCoefficients(ctx, categoryId)
+}
+
+// SetCategoryCoefficients replaces the coefficients of a category. Requires
+// config.coefficients.manage.
+//
+//encore:api auth method=PUT path=/categories/:categoryId/coefficients
+func SetCategoryCoefficients(
+	ctx context.Context,
+	categoryId int64,
+	req *gradecalc.Coefficients,
+) error {
+	audit.SetDetails(ctx, map[string]any{"categoryId": categoryId, "coefficients": req})
+	return authn.GetContainer().GetGradeCalcController().SetCoefficients(ctx, categoryId, req)
+}
+
+// DeleteCategoryCoefficients reverts a category to the default coefficients.
+// Requires config.coefficients.manage.
+//
+//encore:api auth method=DELETE path=/categories/:categoryId/coefficients
+func DeleteCategoryCoefficients(ctx context.Context, categoryId int64) error {
+	audit.SetDetails(ctx, map[string]any{"categoryId": categoryId})
+	return authn.GetContainer().GetGradeCalcController().DeleteCoefficients(ctx, categoryId)
+}
@@ -0,0 +1,63 @@
+package usrcategories
+
+import (
+	"context"
+
+	"encore.app/audit"
+	"encore.app/authn"
+	"encore.app/internal/entities"
+	"encore.app/internal/logger"
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+)
+
+// GetCategoryReport consolidates the averages of every student across the
+// courses of a category and its child categories.
+// Admin / manager → every course.
+// Teacher         → only courses where they are assigned as teacher.
+//
+//encore:api auth method=GET path=/categories/:categoryId/report
+func GetCategoryReport(ctx context.Context, categoryId int64) (*entities.CategoryReport, error) {
+	payload, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || payload == nil {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+
+	report, err := authn.GetContainer().GetCategoryReportController().GetReport(ctx, payload, categoryId)
+	if err != nil {
+		logger.ErrorContext(ctx, "GetCategoryReport error", "categoryId", categoryId, "err", err)
+		return nil, err
+	}
+	return report, nil
+}
+
+// ExportCategoryReportResponse holds the base64-encoded XLSX report.
+type ExportCategoryReportResponse struct {
+	Filename string `json:"filename"`
+	Mimetype string `json:"mimetype"`
+	// Content is the base64-encoded file. Decode with atob() in the browser.
+	Content string `json:"content"`
+}
+
+// ExportCategoryReport returns the category report as an XLSX workbook, with
+// the same course visibility as GetCategoryReport.
+//
+//encore:api auth method=GET path=/categories/:categoryId/report/export
+func ExportCategoryReport(ctx context.Context, categoryId int64) (*ExportCategoryReportResponse, error) {
+	payload, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || payload == nil {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+
+	audit.SetDetails(ctx, map[string]any{"categoryId": categoryId})
+	resp, err := authn.GetContainer().GetCategoryReportController().ExportReport(ctx, payload, categoryId)
+	if err != nil {
+		logger.ErrorContext(ctx, "ExportCategoryReport error", "categoryId", categoryId, "err", err)
+		return nil, err
+	}
+	return &ExportCategoryReportResponse{
+		Filename: resp.Filename,
+		Mimetype: resp.Mimetype,
+		Content:  resp.Filedata,
+	}, nil
+}
@@ -0,0 +1,102 @@
+package usrcourses
+
+import (
+	"context"
+
+	"encore.app/audit"
+	"encore.app/authn"
+	"encore.app/internal/entities"
+	"encore.app/internal/gradelocks"
+	"encore.app/internal/logger"
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+)
+
+// LockCourseGradesRequest selects what to freeze.
+type LockCourseGradesRequest struct {
+	// ExamType is one of: 15P | 1T | Thi. Omit it to lock the whole course.
+	ExamType string `json:"examType"`
+	// Reason is an optional note, e.g. the exam board decision number.
+	Reason string `json:"reason"`
+}
+
+// LockCourseGrades freezes a course's grades, or one exam type in it.
+// Requires grades.lock.
+//
+//encore:api auth method=POST path=/courses/:id/locks
+func LockCourseGrades(
+	ctx context.Context,
+	id int64,
+	req *LockCourseGradesRequest,
+) (*gradelocks.Lock, error) {
+	actor, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || actor == nil {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+	lock, err := authn.GetContainer().
+		GetGradeLockController().
+		LockGrades(ctx, actor, id, req.ExamType, req.Reason)
+	if err != nil {
+		logger.ErrorContext(ctx, "LockCourseGrades error", "err", err, "courseId", id)
+		return nil, err
+	}
+
+	audit.SetDetails(ctx, map[string]any{
+		"course_id": id,
+		"lock_id":   lock.ID,
+		"exam_type": lock.ExamType,
+		"reason":    lock.Reason,
+	})
+	return lock, nil
+}
+
+// UnlockCourseGradesRequest carries the mandatory unlock reason.
+type UnlockCourseGradesRequest struct {
+	Reason string `json:"reason"`
+}
+
+// UnlockCourseGrades lifts a grade lock. Requires grades.unlock.
+//
+//encore:api auth method=POST path=/courses/:id/locks/:lockId/unlock
+func UnlockCourseGrades(
+	ctx context.Context,
+	id int64,
+	lockId int64,
+	req *UnlockCourseGradesRequest,
+) (*gradelocks.Lock, error) {
+	actor, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || actor == nil {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+	lock, err := authn.GetContainer().
+		GetGradeLockController().
+		UnlockGrades(ctx, actor, id, lockId, req.Reason)
+	if err != nil {
+		logger.ErrorContext(ctx, "UnlockCourseGrades error", "err", err, "lockId", lockId)
+		return nil, err
+	}
+
+	audit.SetDetails(ctx, map[string]any{
+		"course_id": id,
+		"lock_id":   lock.ID,
+		"exam_type": lock.ExamType,
+		"reason":    req.Reason,
+	})
+	return lock, nil
+}
+
+// GetCourseGradeLocks lists a course's grade locks, active and lifted.
+// Teachers may only list the locks of courses they teach.
+//
+//encore:api auth method=GET path=/courses/:id/locks
+func GetCourseGradeLocks(ctx context.Context, id int64) (*gradelocks.ListResponse, error) {
+	if _, err := requireCourseAccess(ctx, id); err != nil {
+		return nil, err
+	}
+	resp, err := authn.GetContainer().GetGradeLockController().GetLocks(ctx, id)
+	if err != nil {
+		logger.ErrorContext(ctx, "GetCourseGradeLocks error", "err", err, "courseId", id)
+		return nil, &errs.Error{Code: errs.Internal, Message: "failed to list grade locks"}
+	}
+	return resp, nil
+}
@@ -2,10 +2,13 @@ package usrcourses
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 
+	"encore.app/audit"
 	"encore.app/authn"
 	"encore.app/internal/entities"
+	"encore.app/internal/gradehistory"
 	"encore.app/internal/logger"
 	"encore.app/internal/mdlapi"
 	"encore.dev/beta/auth"
@@ -37,21 +40,20 @@ func GetCourses(
 		GetUserCourses(ctx, controllerReq)
 }
 
-// Get course details endpoint
+// Get course details endpoint. Only callers with access to the course may
+// read its grades.
 //
 //encore:api auth method=GET path=/courses/:id
 func GetCourseDetails(
 	ctx context.Context,
 	id int64,
 ) (*mdlapi.GetCourseGradesResponse, error) {
-	uid, ok := auth.UserID()
-	if !ok {
-		logger.ErrorContext(ctx, "Failed to get UserID from mdw")
-		return nil, &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	actor, err := requireCourseAccess(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
-	userId, _ := strconv.ParseInt(string(uid), 10, 64)
-	req := &entities.FindOneCourseParams{Id: id, UserId: userId}
+	req := &entities.FindOneCourseParams{Id: id, UserId: actor.UserID}
 	return authn.GetContainer().GetCourseController().GetCourseDetails(ctx, req)
 }
 
@@ -64,10 +66,119 @@ func UpdateCourseGrades(
 ) (*entities.UpdateCourseGradesResponse, error) {
 	logger.InfoContext(ctx, "Proccessing UpdateCourseGrades", "request", req)
 
-	if _, err := authn.GetContainer().GetCourseController().UpdateCourseGrades(ctx, req); err != nil {
+	actor, err := requireCourseAccess(ctx, int64(req.CourseID))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := authn.GetContainer().GetCourseController().UpdateCourseGrades(ctx, actor, req); err != nil {
 		logger.ErrorContext(ctx, "UpdateCourseGrades error", "err", err, "req", req)
 		return nil, err
 	}
 
 	return &entities.UpdateCourseGradesResponse{Data: "Ok"}, nil
 }
+
+// maxBatchActivities caps how many Moodle calls one batch request can make.
+const maxBatchActivities = 100
+
+// BatchUpdateCourseGradesRequest carries grades for many activities at once.
+// The course ID comes from the path; any courseid inside an activity is ignored.
+type BatchUpdateCourseGradesRequest struct {
+	Activities []mdlapi.UpdateGradesRequest `json:"activities"`
+}
+
+// Batch update course grades endpoint
+//
+//encore:api auth method=PUT path=/courses/:id/grades
+func BatchUpdateCourseGrades(
+	ctx context.Context,
+	id int64,
+	req *BatchUpdateCourseGradesRequest,
+) (*entities.BatchUpdateGradesResponse, error) {
+	if len(req.Activities) == 0 {
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: "activities must not be empty"}
+	}
+	if len(req.Activities) > maxBatchActivities {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: fmt.Sprintf("at most %d activities per batch", maxBatchActivities),
+		}
+	}
+
+	actor, err := requireCourseAccess(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := authn.GetContainer().
+		GetCourseController().
+		BatchUpdateCourseGrades(ctx, actor, int(id), req.Activities)
+	if err != nil {
+		logger.ErrorContext(ctx, "BatchUpdateCourseGrades error", "err", err, "courseId", id)
+		return nil, err
+	}
+
+	audit.SetDetails(ctx, batchAuditDetails(resp))
+	return resp, nil
+}
+
+// Get course grade history endpoint
+//
+//encore:api auth method=GET path=/courses/:id/grades/history
+func GetGradeHistory(
+	ctx context.Context,
+	id int64,
+	req *gradehistory.ListRequest,
+) (*gradehistory.ListResponse, error) {
+	if _, err := requireCourseAccess(ctx, id); err != nil {
+		return nil, err
+	}
+	resp, err := authn.GetContainer().GetCourseController().GetGradeHistory(ctx, id, req)
+	if err != nil {
+		logger.ErrorContext(ctx, "GetGradeHistory error", "err", err, "courseId", id)
+		return nil, &errs.Error{Code: errs.Internal, Message: "failed to list grade history"}
+	}
+	return resp, nil
+}
+
+// requireCourseAccess returns the caller if they may read and grade the
+// course. Denials are audited by the authz middleware.
+func requireCourseAccess(ctx context.Context, courseID int64) (*entities.TokenPayload, error) {
+	actor, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || actor == nil {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+	if err := authn.GetContainer().GetCourseController().CheckCourseAccess(ctx, actor, courseID); err != nil {
+		audit.SetDetails(ctx, map[string]any{"course_id": courseID})
+		return nil, err
+	}
+	return actor, nil
+}
+
+type auditGradeChange struct {
+	ActivityID int                  `json:"activityid"`
+	ItemNumber int                  `json:"itemnumber"`
+	Grades     []mdlapi.UpdateGrade `json:"grades"`
+}
+
+// batchAuditDetails lists only the grades that were actually written.
+func batchAuditDetails(resp *entities.BatchUpdateGradesResponse) map[string]any {
+	changes := make([]auditGradeChange, 0, len(resp.Activities))
+	for _, a := range resp.Activities {
+		change := auditGradeChange{ActivityID: a.ActivityID, ItemNumber: a.ItemNumber}
+		for _, s := range a.Students {
+			if s.Success {
+				change.Grades = append(change.Grades,
+					mdlapi.UpdateGrade{StudentID: s.StudentID, Grade: s.Grade})
+			}
+		}
+		if len(change.Grades) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	return map[string]any{
+		"course_id": resp.CourseID,
+		"succeeded": resp.Succeeded,
+		"failed":    resp.Failed,
+		"changes":   changes,
+	}
+}
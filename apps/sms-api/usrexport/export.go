@@ -3,6 +3,7 @@ package usrexport
 import (
 	"context"
 
+	"encore.app/audit"
 	"encore.app/authn"
 	"encore.app/internal/entities"
 	"encore.app/internal/logger"
@@ -62,6 +63,7 @@ type ExportCourseResponse struct {
 }
 
 // ExportCourseGrades generates a grade export and returns the file as base64.
+// Only callers with access to the course may export it.
 //
 //encore:api auth method=GET path=/courses/:id/export
 func ExportCourseGrades(
@@ -69,6 +71,12 @@ func ExportCourseGrades(
 	id int64,
 	req *ExportCourseRequest,
 ) (*ExportCourseResponse, error) {
+	actor, _ := auth.Data().(*entities.TokenPayload)
+	if err := authn.GetContainer().GetCourseController().CheckCourseAccess(ctx, actor, id); err != nil {
+		audit.SetDetails(ctx, map[string]any{"course_id": id})
+		return nil, err
+	}
+
 	resp, err := authn.GetContainer().GetExportController().ExportCourseGrades(
 		ctx, int(id), req.TemplateID,
 	)
@@ -91,16 +99,14 @@ type GetAllTemplatesRequest struct {
 	Type string `json:"type" query:"type"`
 }
 
-// GetAllExportTemplates lists all templates for the given type (admin only).
+// GetAllExportTemplates lists all templates for the given type.
+// Requires export.template.manage.
 //
 //encore:api auth method=GET path=/admin/export/templates
 func GetAllExportTemplates(
 	ctx context.Context,
 	req *GetAllTemplatesRequest,
 ) (*GetTemplatesResponse, error) {
-	if err := requireAdminOrManager(ctx); err != nil {
-		return nil, err
-	}
 	resp, err := authn.GetContainer().GetExportController().GetAllTemplates(ctx, req.Type)
 	if err != nil {
 		logger.ErrorContext(ctx, "GetAllExportTemplates error", "type", req.Type, "err", err)
@@ -121,16 +127,14 @@ type UploadExportTemplateRequest struct {
 	Filedata string `json:"filedata"`
 }
 
-// UploadExportTemplate saves a new template (admin only).
+// UploadExportTemplate saves a new template.
+// Requires export.template.manage.
 //
 //encore:api auth method=POST path=/admin/export/templates
 func UploadExportTemplate(
 	ctx context.Context,
 	req *UploadExportTemplateRequest,
 ) (*ExportTemplate, error) {
-	if err := requireAdminOrManager(ctx); err != nil {
-		return nil, err
-	}
 
 	mdlReq := &mdlapi.UploadTemplateRequest{
 		Type:     req.Type,
@@ -159,7 +163,60 @@ func UploadExportTemplate(
 	return &ExportTemplate{ID: uploadResp.ID}, nil
 }
 
-// DeleteExportTemplate removes a template by type and ID (admin only).
+// PreviewExportTemplateRequest selects the template to preview: either a
+// stored TemplateID, or an unsaved file given by Filename and Filedata.
+type PreviewExportTemplateRequest struct {
+	// CourseID is the course whose grades fill the template.
+	CourseID int64 `json:"courseId"`
+	// TemplateID is the ID of a stored course template.
+	TemplateID string `json:"templateId"`
+	// Filename is the original filename (extension determines format).
+	Filename string `json:"filename"`
+	// Filedata is the base64-encoded file content.
+	Filedata string `json:"filedata"`
+}
+
+// PreviewExportTemplate renders a template against a course and returns the
+// file without saving the template.
+// Requires export.template.manage and access to the course.
+//
+//encore:api auth method=POST path=/admin/export/templates/preview
+func PreviewExportTemplate(
+	ctx context.Context,
+	req *PreviewExportTemplateRequest,
+) (*ExportCourseResponse, error) {
+	if req.CourseID <= 0 {
+		return nil, &errs.Error{Code: errs.InvalidArgument, Message: "courseId is required"}
+	}
+	if (req.TemplateID == "") == (req.Filedata == "") {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "exactly one of templateId or filedata is required",
+		}
+	}
+	// The preview holds the course's whole gradebook.
+	actor, _ := auth.Data().(*entities.TokenPayload)
+	if err := authn.GetContainer().GetCourseController().CheckCourseAccess(ctx, actor, req.CourseID); err != nil {
+		audit.SetDetails(ctx, map[string]any{"course_id": req.CourseID})
+		return nil, err
+	}
+
+	resp, err := authn.GetContainer().GetExportController().PreviewTemplate(
+		ctx, int(req.CourseID), req.TemplateID, req.Filename, req.Filedata,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "PreviewExportTemplate error", "courseID", req.CourseID, "err", err)
+		return nil, err
+	}
+	return &ExportCourseResponse{
+		Filename: resp.Filename,
+		Mimetype: resp.Mimetype,
+		Content:  resp.Filedata,
+	}, nil
+}
+
+// DeleteExportTemplate removes a template by type and ID.
+// Requires export.template.manage.
 //
 //encore:api auth method=DELETE path=/admin/export/templates/:templateType/:templateId
 func DeleteExportTemplate(
@@ -167,9 +224,6 @@ func DeleteExportTemplate(
 	templateType string,
 	templateId string,
 ) error {
-	if err := requireAdminOrManager(ctx); err != nil {
-		return err
-	}
 	_, err := authn.GetContainer().GetExportController().DeleteTemplate(ctx, templateType, templateId)
 	if err != nil {
 		logger.ErrorContext(ctx, "DeleteExportTemplate error",
@@ -178,19 +232,6 @@ func DeleteExportTemplate(
 	return err
 }
 
-// ── auth helpers ───────────────────────────────────────────────────────────
-
-func requireAdminOrManager(ctx context.Context) error {
-	payload, ok := auth.Data().(*entities.TokenPayload)
-	if !ok || payload == nil {
-		return &errs.Error{Code: errs.Unauthenticated, Message: "not authenticated"}
-	}
-	if payload.Role != entities.RoleAdmin && payload.Role != entities.RoleManager {
-		return &errs.Error{Code: errs.PermissionDenied, Message: "admin or manager role required"}
-	}
-	return nil
-}
-
 // ── conversion helpers ─────────────────────────────────────────────────────
 
 func toTemplate(t mdlapi.ExportTemplate) *ExportTemplate {
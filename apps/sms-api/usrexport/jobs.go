@@ -0,0 +1,104 @@
+package usrexport
+
+import (
+	"context"
+
+	"encore.app/audit"
+	"encore.app/authn"
+	"encore.app/internal/entities"
+	"encore.app/internal/exportjobs"
+	"encore.app/internal/logger"
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+)
+
+// CreateExportJobRequest selects what to export: one course, or every course
+// of a category the caller can see (packed into one ZIP).
+type CreateExportJobRequest struct {
+	CourseID   int64 `json:"courseId"`
+	CategoryID int64 `json:"categoryId"`
+	// TemplateID is the template used for every course; empty for the
+	// default DOCX export.
+	TemplateID string `json:"templateId"`
+}
+
+// CreateExportJob starts an export in the background and returns the job to
+// poll with GetExportJob. Only callers with access to a course may export it.
+//
+//encore:api auth method=POST path=/exports/jobs
+func CreateExportJob(ctx context.Context, req *CreateExportJobRequest) (*exportjobs.Job, error) {
+	actor, err := currentUser()
+	if err != nil {
+		return nil, err
+	}
+	if (req.CourseID > 0) == (req.CategoryID > 0) {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "exactly one of courseId or categoryId is required",
+		}
+	}
+
+	// Category jobs only export the courses the caller can see; a single
+	// course is checked here, before anything is queued.
+	if req.CourseID > 0 {
+		if err := authn.GetContainer().GetCourseController().CheckCourseAccess(ctx, actor, req.CourseID); err != nil {
+			audit.SetDetails(ctx, map[string]any{"courseId": req.CourseID})
+			return nil, err
+		}
+	}
+
+	job, err := authn.GetContainer().GetExportJobController().CreateJob(
+		ctx, actor, req.CourseID, req.CategoryID, req.TemplateID,
+	)
+	if err != nil {
+		logger.ErrorContext(ctx, "CreateExportJob error", "request", req, "err", err)
+		return nil, err
+	}
+
+	audit.SetDetails(ctx, map[string]any{
+		"jobId":      job.ID,
+		"courseId":   req.CourseID,
+		"categoryId": req.CategoryID,
+		"templateId": req.TemplateID,
+	})
+	return job, nil
+}
+
+// GetExportJob returns the status and progress of an export job.
+//
+//encore:api auth method=GET path=/exports/jobs/:id
+func GetExportJob(ctx context.Context, id string) (*exportjobs.Job, error) {
+	actor, err := currentUser()
+	if err != nil {
+		return nil, err
+	}
+	return authn.GetContainer().GetExportJobController().GetJob(ctx, actor, id)
+}
+
+// DownloadExportJob returns the file of a finished export job as base64.
+//
+//encore:api auth method=GET path=/exports/jobs/:id/download
+func DownloadExportJob(ctx context.Context, id string) (*ExportCourseResponse, error) {
+	actor, err := currentUser()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := authn.GetContainer().GetExportJobController().DownloadJob(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportCourseResponse{
+		Filename: resp.Filename,
+		Mimetype: resp.Mimetype,
+		Content:  resp.Filedata,
+	}, nil
+}
+
+func currentUser() (*entities.TokenPayload, error) {
+	payload, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || payload == nil {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: "not authenticated"}
+	}
+	return payload, nil
+}
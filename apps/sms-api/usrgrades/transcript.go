@@ -0,0 +1,60 @@
+package usrgrades
+
+import (
+	"context"
+
+	"encore.app/audit"
+	"encore.app/authn"
+	"encore.app/internal/entities"
+	"encore.app/internal/logger"
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+)
+
+// TranscriptRequest selects the document format.
+type TranscriptRequest struct {
+	// Format is "pdf" (default) or "docx".
+	Format string `json:"format" query:"format"`
+}
+
+// TranscriptResponse holds the base64-encoded transcript.
+type TranscriptResponse struct {
+	Filename string `json:"filename"`
+	Mimetype string `json:"mimetype"`
+	// Content is the base64-encoded file. Decode with atob() in the browser.
+	Content string `json:"content"`
+}
+
+// GetUserTranscript returns the caller's grades in all of their courses as a
+// printable transcript.
+//
+//encore:api auth method=GET path=/users/grades/transcript
+func GetUserTranscript(ctx context.Context, req *TranscriptRequest) (*TranscriptResponse, error) {
+	payload, ok := auth.Data().(*entities.TokenPayload)
+	if !ok || payload == nil {
+		return nil, &errs.Error{Code: errs.Unauthenticated, Message: errs.Unauthenticated.String()}
+	}
+	return transcript(ctx, payload.UserID, req)
+}
+
+// GetStudentTranscript returns the transcript of any user. Requires
+// transcript.read_any.
+//
+//encore:api auth method=GET path=/admin/users/:id/grades/transcript
+func GetStudentTranscript(ctx context.Context, id int64, req *TranscriptRequest) (*TranscriptResponse, error) {
+	return transcript(ctx, id, req)
+}
+
+func transcript(ctx context.Context, userID int64, req *TranscriptRequest) (*TranscriptResponse, error) {
+	audit.SetDetails(ctx, map[string]any{"userId": userID, "format": req.Format})
+	resp, err := authn.GetContainer().GetUserController().GetTranscript(ctx, userID, req.Format)
+	if err != nil {
+		logger.ErrorContext(ctx, "GetTranscript error", "userId", userID, "err", err)
+		return nil, err
+	}
+	return &TranscriptResponse{
+		Filename: resp.Filename,
+		Mimetype: resp.Mimetype,
+		Content:  resp.Filedata,
+	}, nil
+}
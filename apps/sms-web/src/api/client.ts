@@ -66,7 +66,7 @@ export default class Client {
 		}
 
 		if (typeof options === 'string') {
-			options = { auth: options }
+			options = { auth: { Authorization: 'Bearer ' + options } }
 		}
 
 		this.target = target
@@ -113,16 +113,30 @@ export interface ClientOptions {
 	}
 
 	/**
-	 * Allows you to set the auth token to be used for each request
-	 * either by passing in a static token string or by passing in a function
-	 * which returns the auth token.
-	 *
-	 * These tokens will be sent as bearer tokens in the Authorization header.
+	 * Allows you to set the authentication data to be used for each
+	 * request either by passing in a static object or by passing in
+	 * a function which returns a new object for each request.
 	 */
-	auth?: string | AuthDataGenerator
+	auth?: authn.AuthParams | AuthDataGenerator
 }
 
 export namespace appconfig {
+	/**
+	 * FlushMoodleCacheRequest is the body for POST /admin/cache/flush. At least
+	 * one of the IDs is required.
+	 */
+	export interface FlushMoodleCacheRequest {
+		courseId?: number
+		categoryId?: number
+	}
+
+	export interface FlushMoodleCacheResponse {
+		/**
+		 * Flushed is the number of cached responses dropped.
+		 */
+		flushed: number
+	}
+
 	/**
 	 * LangPackResponse is the shape returned by GET /config/langpack.
 	 * Pack is raw JSON so we can support arbitrary nested structures.
@@ -131,6 +145,21 @@ export namespace appconfig {
 		pack: JSONValue
 	}
 
+	/**
+	 * PermissionsResponse is the shape returned by GET /admin/permissions.
+	 */
+	export interface PermissionsResponse {
+		/**
+		 * Permissions lists every permission that can be granted.
+		 */
+		permissions: authz.PermissionInfo[]
+		roles: string[]
+		/**
+		 * Policy maps each role to the permissions it holds.
+		 */
+		policy: authz.Policy
+	}
+
 	/**
 	 * SetLangPackRequest is the body for PUT /config/langpack.
 	 */
@@ -138,14 +167,25 @@ export namespace appconfig {
 		pack: JSONValue
 	}
 
+	/**
+	 * SetPermissionsRequest is the body for PUT /admin/permissions.
+	 */
+	export interface SetPermissionsRequest {
+		policy: authz.Policy
+	}
+
 	export class ServiceClient {
 		private baseClient: BaseClient
 
 		constructor(baseClient: BaseClient) {
 			this.baseClient = baseClient
 			this.DeleteLangPack = this.DeleteLangPack.bind(this)
+			this.FlushMoodleCache = this.FlushMoodleCache.bind(this)
 			this.GetLangPack = this.GetLangPack.bind(this)
+			this.GetPermissions = this.GetPermissions.bind(this)
+			this.ResetPermissions = this.ResetPermissions.bind(this)
 			this.SetLangPack = this.SetLangPack.bind(this)
+			this.SetPermissions = this.SetPermissions.bind(this)
 		}
 
 		/**
@@ -155,6 +195,22 @@ export namespace appconfig {
 			await this.baseClient.callTypedAPI('DELETE', `/config/langpack`)
 		}
 
+		/**
+		 * FlushMoodleCache drops the cached Moodle data of a course or category, so
+		 * that changes made in Moodle show at once. Requires cache.manage.
+		 */
+		public async FlushMoodleCache(
+			params: FlushMoodleCacheRequest
+		): Promise<FlushMoodleCacheResponse> {
+			// Now make the actual call to the API
+			const resp = await this.baseClient.callTypedAPI(
+				'POST',
+				`/admin/cache/flush`,
+				JSON.stringify(params)
+			)
+			return (await resp.json()) as FlushMoodleCacheResponse
+		}
+
 		/**
 		 * GetLangPack returns the current application-level language pack.
 		 * Public so login page can use it.
@@ -169,7 +225,27 @@ export namespace appconfig {
 		}
 
 		/**
-		 * SetLangPack saves a new application-level language pack. Admin only.
+		 * GetPermissions returns the permissions each role holds.
+		 */
+		public async GetPermissions(): Promise<PermissionsResponse> {
+			// Now make the actual call to the API
+			const resp = await this.baseClient.callTypedAPI(
+				'GET',
+				`/admin/permissions`
+			)
+			return (await resp.json()) as PermissionsResponse
+		}
+
+		/**
+		 * ResetPermissions reverts every role to its default permissions.
+		 */
+		public async ResetPermissions(): Promise<void> {
+			await this.baseClient.callTypedAPI('DELETE', `/admin/permissions`)
+		}
+
+		/**
+		 * SetLangPack saves a new application-level language pack. Requires
+		 * config.langpack.manage.
 		 */
 		public async SetLangPack(params: SetLangPackRequest): Promise<void> {
 			await this.baseClient.callTypedAPI(
@@ -178,6 +254,18 @@ export namespace appconfig {
 				JSON.stringify(params)
 			)
 		}
+
+		/**
+		 * SetPermissions replaces the permissions of every role. Roles left out of
+		 * the policy lose all permissions.
+		 */
+		public async SetPermissions(params: SetPermissionsRequest): Promise<void> {
+			await this.baseClient.callTypedAPI(
+				'PUT',
+				`/admin/permissions`,
+				JSON.stringify(params)
+			)
+		}
 	}
 }
 
@@ -278,6 +366,55 @@ export namespace auditlog {
 }
 
 export namespace authn {
+	/**
+	 * APIKeySecretResponse is the only response that contains a key's secret.
+	 */
+	export interface APIKeySecretResponse {
+		key: apikeys.Key
+		/**
+		 * Secret is sent as the X-API-Key header. It cannot be shown again.
+		 */
+		secret: string
+	}
+
+	/**
+	 * AuthParams carries the credentials of a request: the JWT of a user, or the
+	 * API key of a service account.
+	 */
+	export interface AuthParams {
+		Authorization?: string
+		APIKey?: string
+	}
+
+	export interface CreateAPIKeyRequest {
+		name: string
+		/**
+		 * Scopes are the permissions of the key, e.g. "courses.access_all".
+		 */
+		scopes: string[]
+		/**
+		 * ExpiresAt is optional; keys without it never expire.
+		 */
+		expires_at?: string
+	}
+
+	export interface ExchangeRequest {
+		code: string
+	}
+
+	export interface ImpersonateResponse {
+		/**
+		 * AccessToken acts as the user until ExpiresAt. There is no refresh token.
+		 */
+		accessToken: string
+		expiresAt: string
+		user: entities.UserInfo
+	}
+
+	export interface ListAPIKeysResponse {
+		data: apikeys.Key[]
+	}
+
 	export interface OAuth2CallbackRequest {
 		state: string
 		code: string
@@ -292,9 +429,71 @@ export namespace authn {
 
 		constructor(baseClient: BaseClient) {
 			this.baseClient = baseClient
+			this.CreateAPIKey = this.CreateAPIKey.bind(this)
+			this.Exchange = this.Exchange.bind(this)
+			this.Impersonate = this.Impersonate.bind(this)
+			this.ListAPIKeys = this.ListAPIKeys.bind(this)
 			this.Me = this.Me.bind(this)
 			this.OAuth2Callback = this.OAuth2Callback.bind(this)
 			this.RefreshToken = this.RefreshToken.bind(this)
+			this.RevokeAPIKey = this.RevokeAPIKey.bind(this)
+			this.RotateAPIKey = this.RotateAPIKey.bind(this)
+		}
+
+		/**
+		 * CreateAPIKey creates an API key for a service account. Requires
+		 * apikeys.manage.
+		 */
+		public async CreateAPIKey(
+			params: CreateAPIKeyRequest
+		): Promise<APIKeySecretResponse> {
+			// Now make the actual call to the API
+			const resp = await this.baseClient.callTypedAPI(
+				'POST',
+				`/admin/api-keys`,
+				JSON.stringify(params)
+			)
+			return (await resp.json()) as APIKeySecretResponse
+		}
+
+		/**
+		 * Exchange redeems the one-time code of the OAuth2 callback redirect for the
+		 * token pair. Codes expire after a few seconds and work only once.
+		 */
+		public async Exchange(
+			params: ExchangeRequest
+		): Promise<entities.CallbackResponse> {
+			// Now make the actual call to the API
+			const resp = await this.baseClient.callTypedAPI(
+				'POST',
+				`/authn/exchange`,
+				JSON.stringify(params)
+			)
+			return (await resp.json()) as entities.CallbackResponse
+		}
+
+		/**
+		 * Impersonate issues a short-lived, read-only token to view the application
+		 * as another user. Every request made with it is audited with both user IDs.
+		 * Requires users.impersonate.
+		 */
+		public async Impersonate(userId: number): Promise<ImpersonateResponse> {
+			// Now make the actual call to the API
+			const resp = await this.baseClient.callTypedAPI(
+				'POST',
+				`/admin/impersonate/${encodeURIComponent(userId)}`
+			)
+			return (await resp.json()) as ImpersonateResponse
+		}
+
+		/**
+		 * ListAPIKeys returns every API key, revoked ones included. Secrets are never
+		 * returned. Requires apikeys.manage.
+		 */
+		public async ListAPIKeys(): Promise<ListAPIKeysResponse> {
+			// Now make the actual call to the API
+			const resp = await this.baseClient.callTypedAPI('GET', `/admin/api-keys`)
+			return (await resp.json()) as ListAPIKeysResponse
 		}
 
 		/**
@@ -349,6 +548,29 @@ export namespace authn {
 			)
 			return (await resp.json()) as entities.CallbackResponse
 		}
+
+		/**
+		 * RevokeAPIKey disables an API key for good. Requires apikeys.manage.
+		 */
+		public async RevokeAPIKey(id: number): Promise<void> {
+			await this.baseClient.callTypedAPI(
+				'DELETE',
+				`/admin/api-keys/${encodeURIComponent(id)}`
+			)
+		}
+
+		/**
+		 * RotateAPIKey replaces the secret of an API key. The old secret stops
+		 * working at once. Requires apikeys.manage.
+		 */
+		public async RotateAPIKey(id: number): Promise<APIKeySecretResponse> {
+			// Now make the actual call to the API
+			const resp = await this.baseClient.callTypedAPI(
+				'POST',
+				`/admin/api-keys/${encodeURIComponent(id)}/rotate`
+			)
+			return (await resp.json()) as APIKeySecretResponse
+		}
 	}
 }
 
@@ -689,6 +911,33 @@ export namespace usrgrades {
 	}
 }
 
+export namespace apikeys {
+	/**
+	 * Key is the stored part of an API key of a service account. Only the hash
+	 * of the secret is kept; the secret is shown once, when it is created or
+	 * rotated.
+	 */
+	export interface Key {
+		id: number
+		name: string
+		/**
+		 * Prefix identifies the key in lists and logs. It is not secret.
+		 */
+		prefix: string
+		/**
+		 * Scopes are the permissions the key holds.
+		 */
+		scopes: string[]
+		created_by: number
+		created_at: string
+		rotated_at: string | null
+		last_used_at: string | null
+		expires_at: string | null
+		revoked_by: number | null
+		revoked_at: string | null
+	}
+}
+
 export namespace audit {
 	export interface ActorActivity {
 		actor_id: number
@@ -697,7 +946,9 @@ export namespace audit {
 	}
 
 	/**
-	 * Entry is a single immutable audit log record.
+	 * Entry is a single immutable audit log record. ImpersonatorID is the admin
+	 * acting as ActorID, and APIKeyID the API key the actor authenticated with;
+	 * both are 0 for ordinary requests.
 	 */
 	export interface Entry {
 		id: string
@@ -705,6 +956,8 @@ export namespace audit {
 		event_type: EventType
 		actor_id: number
 		actor_role: string
+		impersonator_id: number
+		api_key_id: number
 		outcome: Outcome
 		service: string
 		endpoint: string
@@ -776,6 +1029,26 @@ export namespace audit {
 	}
 }
 
+export namespace authz {
+	/**
+	 * Permission names an action that a role can be granted.
+	 */
+	export type Permission = string
+
+	/**
+	 * PermissionInfo describes a permission for the admin UI.
+	 */
+	export interface PermissionInfo {
+		name: Permission
+		description: string
+	}
+
+	/**
+	 * Policy grants permissions to roles. Roles that are not listed have none.
+	 */
+	export type Policy = { [key: string]: Permission[] }
+}
+
 export namespace entities {
 	export interface CallbackResponse {
 		accessToken: string
@@ -807,12 +1080,22 @@ export namespace entities {
 
 	export interface GetUsersCategoriesResponse {
 		data: Category[]
+		/**
+		 * Source is where Moodle's data was read from: "webservice" or
+		 * "database".
+		 */
+		source?: string
 	}
 
 	export interface GetUsersCoursesRequest {}
 
 	export interface GetUsersCoursesResponse {
 		data: Course[]
+		/**
+		 * Source is where Moodle's data was read from: "webservice" or
+		 * "database".
+		 */
+		source?: string
 	}
 
 	export interface HttpCallbackResponse {
@@ -835,6 +1118,11 @@ export namespace entities {
 		phone1: string
 		username: string
 		role: UserRole
+		/**
+		 * Suspended and Deleted users can no longer refresh their tokens.
+		 */
+		suspended: boolean
+		deleted: boolean
 	}
 
 	/**
@@ -1195,8 +1483,8 @@ type CallParameters = Omit<RequestInit, 'method' | 'body' | 'headers'> & {
 
 // AuthDataGenerator is a function that returns a new instance of the authentication data required by this API
 export type AuthDataGenerator = () =>
-	| string
-	| Promise<string | undefined>
+	| authn.AuthParams
+	| Promise<authn.AuthParams | undefined>
 	| undefined
 
 // A fetcher is the prototype for the inbuilt Fetch function
@@ -1245,7 +1533,7 @@ class BaseClient {
 	}
 
 	async getAuthData(): Promise<CallParameters | undefined> {
-		let authData: string | undefined
+		let authData: authn.AuthParams | undefined
 
 		// If authorization data generator is present, call it and add the returned data to the request
 		if (this.authGenerator) {
@@ -1260,8 +1548,10 @@ class BaseClient {
 		if (authData) {
 			const data: CallParameters = {}
 
-			data.headers = {}
-			data.headers['Authorization'] = 'Bearer ' + authData
+			data.headers = makeRecord<string, string>({
+				authorization: authData.Authorization,
+				'x-api-key': authData.APIKey
+			})
 
 			return data
 		}
@@ -65,6 +65,10 @@ class authnApi {
 	async GetUserInfo() {
 		return client.authn.Me()
 	}
+
+	async Exchange(code: string) {
+		return tempClient.authn.Exchange({ code })
+	}
 }
 export const AuthApi = new authnApi()
 
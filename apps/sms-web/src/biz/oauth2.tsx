@@ -1,20 +1,7 @@
-import { env } from '@/env'
+import { ApiUrl } from '@/const'
 
-// Generate the OAuth2 authorization URL
-export const generateOAuth2Url = () => {
-	const params = new URLSearchParams({
-		client_id: env.VITE_CLIENT_ID,
-		redirect_uri: env.VITE_REDIRECT_URI,
-		scope: 'user_info',
-		response_type: 'code',
-		state: 'randomstate'
-	})
-
-	return `${env.VITE_OAUTH2_URL}/${env.VITE_TOKEN_URI}?${params.toString()}`
-}
-
-// Use it in your component
-export const oauth2Uri = generateOAuth2Url()
+// The API generates the state and PKCE challenge, then redirects to Moodle.
+export const oauth2Uri = `${ApiUrl}/oauth2/login`
 
 // Or create a login function
 export const initiateOAuth2Login = () => {
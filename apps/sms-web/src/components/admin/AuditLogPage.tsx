@@ -56,6 +56,8 @@ interface AuditEntry {
 	event_type: string
 	actor_id: number
 	actor_role: string
+	impersonator_id: number
+	api_key_id: number
 	outcome: 'success' | 'failure' | 'denied'
 	service: string
 	endpoint: string
@@ -226,6 +228,32 @@ const EVENT_TYPE_OPTIONS = [
 		labelKey: 'audit.eventTypes.auth.token_refresh'
 	},
 	{ value: 'auth.denied', labelKey: 'audit.eventTypes.auth.denied' },
+	{ value: 'auth.logout', labelKey: 'audit.eventTypes.auth.logout' },
+	{ value: 'auth.logout_all', labelKey: 'audit.eventTypes.auth.logout_all' },
+	{
+		value: 'auth.session_revoke',
+		labelKey: 'audit.eventTypes.auth.session_revoke'
+	},
+	{
+		value: 'auth.role_change',
+		labelKey: 'audit.eventTypes.auth.role_change'
+	},
+	{
+		value: 'auth.permission_denied',
+		labelKey: 'audit.eventTypes.auth.permission_denied'
+	},
+	{
+		value: 'auth.impersonate',
+		labelKey: 'audit.eventTypes.auth.impersonate'
+	},
+	{
+		value: 'auth.impersonated_request',
+		labelKey: 'audit.eventTypes.auth.impersonated_request'
+	},
+	{ value: 'apikey.create', labelKey: 'audit.eventTypes.apikey.create' },
+	{ value: 'apikey.rotate', labelKey: 'audit.eventTypes.apikey.rotate' },
+	{ value: 'apikey.revoke', labelKey: 'audit.eventTypes.apikey.revoke' },
+	{ value: 'apikey.request', labelKey: 'audit.eventTypes.apikey.request' },
 	{ value: 'grade.update', labelKey: 'audit.eventTypes.grade.update' },
 	{ value: 'export.grades', labelKey: 'audit.eventTypes.export.grades' },
 	{ value: 'template.upload', labelKey: 'audit.eventTypes.template.upload' },
@@ -238,6 +266,18 @@ const EVENT_TYPE_OPTIONS = [
 		value: 'config.langpack_delete',
 		labelKey: 'audit.eventTypes.config.langpack_delete'
 	},
+	{
+		value: 'config.permissions_set',
+		labelKey: 'audit.eventTypes.config.permissions_set'
+	},
+	{
+		value: 'config.permissions_reset',
+		labelKey: 'audit.eventTypes.config.permissions_reset'
+	},
+	{
+		value: 'config.cache_flush',
+		labelKey: 'audit.eventTypes.config.cache_flush'
+	},
 	{ value: 'audit.purge', labelKey: 'audit.eventTypes.audit.purge' }
 ] as const
 
@@ -896,7 +936,15 @@ function AuditRow({ entry }: { entry: AuditEntry }) {
 					</code>
 				</TableCell>
 				<TableCell className='text-xs tabular-nums'>
-					{entry.actor_id || '—'}
+					{entry.api_key_id
+						? t('audit.apiKey', { id: entry.api_key_id })
+						: entry.actor_id || '—'}
+					{entry.impersonator_id ? (
+						<span className='text-muted-foreground'>
+							{' '}
+							{t('audit.impersonatedBy', { id: entry.impersonator_id })}
+						</span>
+					) : null}
 				</TableCell>
 				<TableCell>
 					{entry.actor_role ? (
@@ -32,7 +32,7 @@ import useAuth from '@/hooks/useAuth'
 
 type TemplateType = 'course' | 'quiz' | 'assign'
 
-const ALLOWED_EXTS = ['.docx', '.xlsx', '.xls']
+const ALLOWED_EXTS = ['.docx', '.xlsx']
 const MAX_MB = 10
 
 function fileToBase64(file: File): Promise<string> {
@@ -183,7 +183,7 @@ function TemplatePanel({ type }: { type: TemplateType }) {
 					<input
 						ref={inputRef}
 						type='file'
-						accept='.docx,.xlsx,.xls'
+						accept='.docx,.xlsx'
 						className='hidden'
 						onChange={handleFileChange}
 						disabled={uploadMutation.isPending}
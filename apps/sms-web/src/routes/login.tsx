@@ -1,3 +1,4 @@
+import { AuthApi } from '@/api'
 import { AuthController } from '@/biz'
 import { initiateOAuth2Login } from '@/biz/oauth2'
 import useAuth, { AUTH_QUERY_KEY } from '@/hooks/useAuth'
@@ -33,12 +34,19 @@ export default function Login() {
 		initiateOAuth2Login()
 	}
 
-	const handleEventListener = (event: MessageEvent<TokenEvent>) => {
-		if (event.data?.token) {
-			const { accessToken, refreshToken } = event.data.token
+	const handleEventListener = async (event: MessageEvent<TokenEvent>) => {
+		if (event.origin !== window.location.origin || !event.data?.code) {
+			return
+		}
+		try {
+			const { accessToken, refreshToken } = await AuthApi.Exchange(
+				event.data.code
+			)
 			AuthController.setTokens({ accessToken, refreshToken })
 			toast.success(t('auth.loginSuccess'))
 			queryClient.invalidateQueries({ queryKey: AUTH_QUERY_KEY })
+		} catch (err) {
+			console.error('Login code exchange failed:', err)
 		}
 	}
 
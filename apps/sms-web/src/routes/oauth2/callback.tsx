@@ -2,21 +2,15 @@ import { createFileRoute } from '@tanstack/react-router'
 import z from 'zod'
 
 const Oauth2CallbackSearchSchema = z.object({
-	accessToken: z.string().jwt().nonempty(),
-	refreshToken: z.string().jwt().nonempty()
+	code: z.string().nonempty()
 })
 
 export const Route = createFileRoute('/oauth2/callback')({
 	component: Callback,
 	validateSearch: Oauth2CallbackSearchSchema,
-	loaderDeps: ({ search: { accessToken, refreshToken } }) => ({
-		accessToken,
-		refreshToken
-	}),
-	loader: ({ deps: { accessToken, refreshToken } }) => {
-		const isTokensValid =
-			accessToken.length !== 0 && refreshToken.length !== 0
-		if (!isTokensValid) {
+	loaderDeps: ({ search: { code } }) => ({ code }),
+	loader: ({ deps: { code } }) => {
+		if (code.length === 0) {
 			// Impl error handling here
 			return
 		}
@@ -26,7 +20,9 @@ export const Route = createFileRoute('/oauth2/callback')({
 			return
 		}
 
-		window.opener.postMessage({ token: { accessToken, refreshToken } }, '*')
+		// Only the one-time code leaves this window; the opener exchanges it
+		// for the tokens.
+		window.opener.postMessage({ code }, window.location.origin)
 		window.close()
 	}
 })
@@ -8,8 +8,9 @@ export type AppToken = {
 	refreshToken: string
 }
 
+// Posted by the OAuth2 callback popup; the code is exchanged for an AppToken.
 export type TokenEvent = {
-	token: AppToken
+	code: string
 }
 
 export class CourseCategory {
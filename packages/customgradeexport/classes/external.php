@@ -14,7 +14,7 @@ defined('MOODLE_INTERNAL') || die();
 /**
  * External API class.
  *
- * Uses the Moodle 4.2+ \core_external namespace. All five functions are
+ * Uses the Moodle 4.2+ \core_external namespace. All six functions are
  * stateless and use capability checks rather than session cookies so they
  * are safe for webservice/restful access.
  */
@@ -233,6 +233,54 @@ class external extends \core_external\external_api {
         ]);
     }
 
+    // ── get_template_content ────────────────────────────────────────────────
+
+    public static function get_template_content_parameters(): \core_external\external_function_parameters {
+        return new \core_external\external_function_parameters([
+            'type'       => new \core_external\external_value(PARAM_ALPHA,       'Template type'),
+            'templateid' => new \core_external\external_value(PARAM_ALPHANUMEXT, 'Template ID'),
+        ]);
+    }
+
+    /**
+     * Return the raw template file so the SMS can render it itself.
+     *
+     * @param  string $type        'course', 'quiz', or 'assign'
+     * @param  string $templateid  Template identifier
+     * @return array               {name, format, filedata}
+     */
+    public static function get_template_content(string $type, string $templateid): array {
+        $params = self::validate_parameters(
+            self::get_template_content_parameters(),
+            ['type' => $type, 'templateid' => $templateid]
+        );
+
+        $context = \context_system::instance();
+        self::validate_context($context);
+        if (!has_capability('local/customgradeexport:uploadtemplate', $context)) {
+            require_capability('local/customgradeexport:export', $context);
+        }
+
+        $content = template_manager::get_template_content($params['type'], $params['templateid']);
+        if ($content === false || $content === '') {
+            throw new \moodle_exception('templatenotfound', 'local_customgradeexport');
+        }
+
+        return [
+            'name'     => template_manager::get_template_name($params['type'], $params['templateid']),
+            'format'   => template_manager::get_template_format($params['type'], $params['templateid']),
+            'filedata' => base64_encode($content),
+        ];
+    }
+
+    public static function get_template_content_returns(): \core_external\external_single_structure {
+        return new \core_external\external_single_structure([
+            'name'     => new \core_external\external_value(PARAM_TEXT,  'Display name'),
+            'format'   => new \core_external\external_value(PARAM_ALPHA, 'File format: docx | xlsx | xls'),
+            'filedata' => new \core_external\external_value(PARAM_RAW,   'Base64-encoded file content'),
+        ]);
+    }
+
     // ── delete_template (admin) ─────────────────────────────────────────────
 
     public static function delete_template_parameters(): \core_external\external_function_parameters {
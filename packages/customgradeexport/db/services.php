@@ -51,6 +51,15 @@ $functions = [
         'ajax'        => false,
     ],
 
+    'local_customgradeexport_get_template_content' => [
+        'classname'   => 'local_customgradeexport\external',
+        'methodname'  => 'get_template_content',
+        'description' => 'Return a template file as base64 so it can be rendered outside Moodle.',
+        'type'        => 'read',
+        'capabilities' => 'local/customgradeexport:export',
+        'ajax'        => false,
+    ],
+
     'local_customgradeexport_delete_template' => [
         'classname'   => 'local_customgradeexport\external',
         'methodname'  => 'delete_template',
@@ -136,6 +136,7 @@ $string['replacefile']             = 'Replace file (optional)';
 $string['replacefilehelp']         = 'Leave empty to keep the current file and only update the name.';
 $string['download']                = 'Download';
 $string['templatenotavailable']    = 'This template is currently being migrated and is not available for download. Please try again in a moment.';
+$string['templatenotfound']         = 'The template file could not be found.';
 
 // Task
 $string['task_migrate_templates']  = 'Migrate templates to S3';
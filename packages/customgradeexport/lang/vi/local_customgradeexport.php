@@ -131,6 +131,7 @@ $string['replacefile']             = 'Thay thế tệp (tuỳ chọn)';
 $string['replacefilehelp']         = 'Để trống nếu chỉ muốn cập nhật tên.';
 $string['download']                = 'Tải xuống';
 $string['templatenotavailable']    = 'Mẫu đang được di chuyển, vui lòng thử lại sau.';
+$string['templatenotfound']         = 'Không tìm thấy tệp mẫu.';
 
 $string['task_migrate_templates']  = 'Di chuyển mẫu lên S3';
 $string['type']                    = 'Loại';
@@ -11,7 +11,7 @@
 defined('MOODLE_INTERNAL') || die();
 
 $plugin->component = 'local_customgradeexport';
-$plugin->version   = 2026040202;   // S3 migration via scheduled task + status field
+$plugin->version   = 2026040203;   // get_template_content webservice
 $plugin->requires  = 2024042200;   // Moodle 5.0+
 $plugin->maturity  = MATURITY_STABLE;
-$plugin->release   = '1.2.0';
+$plugin->release   = '1.3.0';
@@ -0,0 +1,105 @@
+<?php
+namespace local_smswebhook;
+
+defined('MOODLE_INTERNAL') || die();
+
+use core\event\base;
+use core\task\manager;
+use local_smswebhook\task\send_event;
+
+/**
+ * Queues the events the SMS follows for delivery. Sending happens in an ad
+ * hoc task, so that a slow or unreachable SMS never holds up Moodle, and
+ * failed deliveries are retried.
+ */
+class observer {
+
+    public static function queue(base $event) {
+        if (empty(get_config('local_smswebhook', 'url'))) {
+            return;
+        }
+
+        $task = new send_event();
+        $task->set_custom_data(self::payload($event));
+        manager::queue_adhoc_task($task);
+    }
+
+    /**
+     * The event as the SMS reads it: see the Event type in internal/mdlevents.
+     */
+    private static function payload(base $event) {
+        global $DB;
+
+        $data = $event->get_data();
+        $courseid = (int)($data['courseid'] ?? 0);
+
+        $payload = [
+            'id'                => \core\uuid::generate(),
+            'eventname'         => $data['eventname'],
+            'courseid'          => $courseid,
+            'categoryid'        => 0,
+            'contextlevel'      => (int)$data['contextlevel'],
+            'contextinstanceid' => (int)$data['contextinstanceid'],
+            'objectid'          => (int)($data['objectid'] ?? 0),
+            'userid'            => (int)($data['userid'] ?? 0),
+            'relateduserid'     => (int)($data['relateduserid'] ?? 0),
+            'origin'            => self::origin(),
+            'timecreated'       => (int)$data['timecreated'],
+        ];
+
+        if ($courseid > 1) {
+            $payload['categoryid'] = (int)$DB->get_field('course', 'category', ['id' => $courseid]);
+        }
+        if ($event instanceof \core\event\user_graded) {
+            $payload['grade'] = self::grade($data);
+        }
+        return $payload;
+    }
+
+    /**
+     * How the event was caused, the way the standard log records it.
+     */
+    private static function origin() {
+        if (defined('WS_SERVER') && WS_SERVER) {
+            return 'ws';
+        }
+        if (CLI_SCRIPT) {
+            return 'cli';
+        }
+        return 'web';
+    }
+
+    /**
+     * The activity and values of a grade. The previous value is read from
+     * the grade history, where Moodle has just added the new one.
+     */
+    private static function grade(array $data) {
+        global $DB;
+
+        $itemid = (int)$data['other']['itemid'];
+        $finalgrade = $data['other']['finalgrade'] ?? null;
+
+        $grade = [
+            'itemid'     => $itemid,
+            'cmid'       => 0,
+            'itemnumber' => 0,
+            'finalgrade' => $finalgrade === null ? null : (float)$finalgrade,
+            'oldgrade'   => null,
+        ];
+
+        $item = $DB->get_record('grade_items', ['id' => $itemid],
+            'id, courseid, itemtype, itemmodule, iteminstance, itemnumber', IGNORE_MISSING);
+        if ($item && $item->itemtype === 'mod') {
+            $cm = get_coursemodule_from_instance($item->itemmodule, $item->iteminstance, $item->courseid, false, IGNORE_MISSING);
+            $grade['cmid'] = $cm ? (int)$cm->id : 0;
+            $grade['itemnumber'] = (int)$item->itemnumber;
+        }
+
+        $history = array_values($DB->get_records('grade_grades_history', ['oldid' => $data['objectid']],
+            'timemodified DESC, id DESC', 'id, finalgrade', 0, 2));
+        if (count($history) === 2 && $history[1]->finalgrade !== null) {
+            $grade['oldgrade'] = (float)$history[1]->finalgrade;
+        }
+        return $grade;
+    }
+}
@@ -0,0 +1,21 @@
+<?php
+namespace local_smswebhook\privacy;
+
+defined('MOODLE_INTERNAL') || die();
+
+use core_privacy\local\metadata\collection;
+
+/**
+ * The plugin stores nothing itself; it sends events to the SMS.
+ */
+class provider implements \core_privacy\local\metadata\provider {
+
+    public static function get_metadata(collection $collection): collection {
+        $collection->add_external_location_link('sms', [
+            'userid'        => 'privacy:metadata:sms:userid',
+            'relateduserid' => 'privacy:metadata:sms:relateduserid',
+            'finalgrade'    => 'privacy:metadata:sms:finalgrade',
+        ], 'privacy:metadata:sms');
+        return $collection;
+    }
+}
@@ -0,0 +1,34 @@
+<?php
+// This file is part of Moodle - http://moodle.org/
+//
+// Moodle is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+/**
+ * Events reported to the SMS. Keep in step with internal/mdlevents in the
+ * SMS API, which refuses any other event.
+ *
+ * @package    local_smswebhook
+ * @copyright  2026 CDHC2
+ * @license    http://www.gnu.org/copyleft/gpl.html GNU GPL v3 or later
+ */
+
+defined('MOODLE_INTERNAL') || die();
+
+$observers = [];
+foreach ([
+    '\core\event\user_graded',
+    '\core\event\user_enrolment_created',
+    '\core\event\user_enrolment_deleted',
+    '\core\event\course_updated',
+    '\core\event\role_assigned',
+    '\core\event\role_unassigned',
+] as $eventname) {
+    $observers[] = [
+        'eventname' => $eventname,
+        'callback'  => '\local_smswebhook\observer::queue',
+        'internal'  => false, // Only once the transaction is committed.
+    ];
+}
@@ -0,0 +1,35 @@
+<?php
+// This file is part of Moodle - http://moodle.org/
+//
+// Moodle is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+/**
+ * Language strings for local_smswebhook
+ *
+ * @package    local_smswebhook
+ * @copyright  2026 CDHC2
+ * @license    http://www.gnu.org/copyleft/gpl.html GNU GPL v3 or later
+ */
+
+defined('MOODLE_INTERNAL') || die();
+
+$string['pluginname'] = 'SMS webhook';
+
+// Settings
+$string['url'] = 'Webhook URL';
+$string['url_desc'] = 'Where events are posted, e.g. https://sms.example.com/webhooks/moodle. Leave empty to send nothing.';
+$string['secret'] = 'Signing secret';
+$string['secret_desc'] = 'Shared with the SMS as MOODLE_WEBHOOK_SECRET. Every event is signed with it.';
+
+// Task and errors
+$string['task_send_event'] = 'Send an event to the SMS';
+$string['deliveryfailed'] = 'The SMS refused or did not answer the event (HTTP {$a})';
+
+// Privacy
+$string['privacy:metadata:sms'] = 'Grade, enrolment, role and course events are sent to the school management system (SMS), which keeps a history of grades.';
+$string['privacy:metadata:sms:userid'] = 'The ID of the user who caused the event.';
+$string['privacy:metadata:sms:relateduserid'] = 'The ID of the user the event is about, such as the student graded.';
+$string['privacy:metadata:sms:finalgrade'] = 'The new and previous grade, for grade events.';
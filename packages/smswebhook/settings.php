@@ -0,0 +1,39 @@
+<?php
+
+/**
+ * Admin settings for local_smswebhook.
+ *
+ * @package    local_smswebhook
+ * @copyright  2026 CDHC2
+ * @license    http://www.gnu.org/copyleft/gpl.html GNU GPL v3 or later
+ */
+
+defined('MOODLE_INTERNAL') || die();
+
+if ($hassiteconfig) {
+
+    $settings = new admin_settingpage(
+        'local_smswebhook',
+        get_string('pluginname', 'local_smswebhook')
+    );
+
+    $ADMIN->add('localplugins', $settings);
+
+    if ($ADMIN->fulltree) {
+
+        $settings->add(new admin_setting_configtext(
+            'local_smswebhook/url',
+            get_string('url', 'local_smswebhook'),
+            get_string('url_desc', 'local_smswebhook'),
+            '',
+            PARAM_URL
+        ));
+
+        $settings->add(new admin_setting_configpasswordunmask(
+            'local_smswebhook/secret',
+            get_string('secret', 'local_smswebhook'),
+            get_string('secret_desc', 'local_smswebhook'),
+            ''
+        ));
+    }
+}
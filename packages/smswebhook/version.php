@@ -0,0 +1,23 @@
+<?php
+// This file is part of Moodle - http://moodle.org/
+//
+// Moodle is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+/**
+ * Version information for local_smswebhook
+ *
+ * @package    local_smswebhook
+ * @copyright  2026 CDHC2
+ * @license    http://www.gnu.org/copyleft/gpl.html GNU GPL v3 or later
+ */
+
+defined('MOODLE_INTERNAL') || die();
+
+$plugin->component = 'local_smswebhook';
+$plugin->version   = 2026101800;  // YYYYMMDDXX
+$plugin->requires  = 2022041900;  // Moodle 4.0+
+$plugin->maturity  = MATURITY_STABLE;
+$plugin->release   = 'v1.0.0';